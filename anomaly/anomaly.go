@@ -0,0 +1,392 @@
+// Package anomaly detects the containers of pods that crash loop, are
+// OOMKilled, fail to pull their image or restart in bursts, from the
+// container statuses of the pod events, and emits them as anomaly events.
+package anomaly
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// Anomaly types, the event types of the anomaly events.
+const (
+	TypeCrashLoop    = "CrashLoopBackOff"
+	TypeOOMKilled    = "OOMKilled"
+	TypeImagePull    = "ImagePullFailure"
+	TypeRestartStorm = "RestartStorm"
+)
+
+// Types lists the anomaly types.
+var Types = []string{TypeCrashLoop, TypeOOMKilled, TypeImagePull, TypeRestartStorm}
+
+// pruneInterval is how often the pods that no longer exist are forgotten.
+const pruneInterval = time.Minute
+
+// Default thresholds.
+const (
+	DefaultCrashLoopRestarts    = 3
+	DefaultRestartStormRestarts = 5
+	DefaultRestartStormWindow   = 10 * time.Minute
+)
+
+// imagePullReasons are the waiting reasons of containers whose image cannot
+// be pulled.
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// Anomaly is an anomaly of a container of a pod. Its metadata is the one of
+// the pod, so that sinks filter it and find its deployment like for the pod,
+// except for the UID, which identifies the anomaly type of the container.
+type Anomaly struct {
+	metav1.ObjectMeta `json:"metadata"`
+
+	Type      string `json:"type"`
+	Container string `json:"container"`
+	Node      string `json:"node,omitempty"`
+
+	// Reason is the reason the container waits, or the reason of its last
+	// termination for crash loops, restart storms and OOMKills.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	ExitCode     int32 `json:"exitCode"`
+	RestartCount int32 `json:"restartCount"`
+
+	// Restarts is the number of restarts within the restart storm window,
+	// for restart storms.
+	Restarts int32 `json:"restarts,omitempty"`
+}
+
+// newAnomaly returns the anomaly of type t of the container of pod with
+// status s.
+func newAnomaly(t string, pod *v1.Pod, s v1.ContainerStatus) *Anomaly {
+	return &Anomaly{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             types.UID(strings.Join([]string{string(pod.UID), s.Name, t}, "/")),
+			ResourceVersion: pod.ResourceVersion,
+			Labels:          pod.Labels,
+			OwnerReferences: pod.OwnerReferences,
+		},
+		Type:         t,
+		Container:    s.Name,
+		Node:         pod.Spec.NodeName,
+		RestartCount: s.RestartCount,
+	}
+}
+
+// terminated sets the reason and exit code of a to the ones of the
+// termination term.
+func (a *Anomaly) terminated(term *v1.ContainerStateTerminated) {
+	if term == nil {
+		return
+	}
+	a.Reason = term.Reason
+	a.ExitCode = term.ExitCode
+	if a.Message == "" {
+		a.Message = term.Message
+	}
+}
+
+// Thresholds decide when the containers of a deployment are anomalous.
+type Thresholds struct {
+	// CrashLoopRestarts is the restart count from which a container in
+	// CrashLoopBackOff is reported.
+	CrashLoopRestarts int32
+
+	// RestartStormRestarts restarts of a container within
+	// RestartStormWindow are a restart storm.
+	RestartStormRestarts int32
+	RestartStormWindow   time.Duration
+
+	// Ignore are the anomaly types not reported.
+	Ignore []string
+}
+
+// with returns t with the fields set in override.
+func (t Thresholds) with(override Thresholds) Thresholds {
+	if override.CrashLoopRestarts != 0 {
+		t.CrashLoopRestarts = override.CrashLoopRestarts
+	}
+	if override.RestartStormRestarts != 0 {
+		t.RestartStormRestarts = override.RestartStormRestarts
+	}
+	if override.RestartStormWindow != 0 {
+		t.RestartStormWindow = override.RestartStormWindow
+	}
+	if override.Ignore != nil {
+		t.Ignore = override.Ignore
+	}
+	return t
+}
+
+func (t Thresholds) ignores(anomalyType string) bool {
+	for _, ignored := range t.Ignore {
+		if strings.EqualFold(ignored, anomalyType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Options describes a Detector.
+type Options struct {
+	// Thresholds apply to every pod. Zero fields use the defaults.
+	Thresholds
+
+	// Deployments override the set fields of the thresholds for the pods
+	// of deployments, keyed by namespace/name, or by name for the
+	// deployments of that name in every namespace.
+	Deployments map[string]Thresholds
+
+	// Exists reports whether the pod namespace/name with uid still exists.
+	// The containers of the pods that no longer exist are forgotten, even if
+	// their deletion was not seen. Nil only forgets the deleted pods.
+	Exists func(namespace, name string, uid types.UID) bool
+}
+
+// thresholds returns the thresholds of the pods of deployment in namespace.
+func (o Options) thresholds(namespace, deployment string) Thresholds {
+	t := o.Thresholds
+	if deployment == "" {
+		return t
+	}
+	if override, ok := o.Deployments[namespace+"/"+deployment]; ok {
+		return t.with(override)
+	}
+	if override, ok := o.Deployments[deployment]; ok {
+		return t.with(override)
+	}
+	return t
+}
+
+// podState is what the Detector remembers of a pod between its events.
+type podState struct {
+	namespace  string
+	name       string
+	containers map[string]*container
+}
+
+// container is what the Detector remembers of a container between the
+// events of its pod.
+type container struct {
+	restartCount int32
+
+	// lastFinished is when the last termination seen finished
+	lastFinished time.Time
+
+	// crashLoop and imagePull are set once reported, until the container
+	// is ready or running again
+	crashLoop bool
+	imagePull bool
+
+	// restarts are the restarts seen within the storm window, the oldest
+	// first
+	restarts     []restarts
+	stormEmitted time.Time
+}
+
+// restarts are restarts of a container seen in the same event.
+type restarts struct {
+	at    time.Time
+	count int32
+}
+
+// Detector is an EventSink detecting the anomalies of the containers of the
+// pods of the events it is sent, and sending them to its sinks as events of
+// common.ResourceAnomaly, with the anomaly type as the event type.
+type Detector struct {
+	opts  Options
+	sinks watcher.Sinks
+
+	lock      sync.Mutex
+	pods      map[types.UID]*podState
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+// NewDetector returns a Detector sending the anomalies to sinks, which the
+// caller closes.
+func NewDetector(opts Options, sinks watcher.Sinks) *Detector {
+	if opts.CrashLoopRestarts == 0 {
+		opts.CrashLoopRestarts = DefaultCrashLoopRestarts
+	}
+	if opts.RestartStormRestarts == 0 {
+		opts.RestartStormRestarts = DefaultRestartStormRestarts
+	}
+	if opts.RestartStormWindow == 0 {
+		opts.RestartStormWindow = DefaultRestartStormWindow
+	}
+	return &Detector{opts: opts, sinks: sinks, pods: map[types.UID]*podState{}, now: time.Now}
+}
+
+// Name implements watcher.EventSink.
+func (d *Detector) Name() string { return "anomaly" }
+
+// Close implements watcher.EventSink, the sinks are closed by the caller.
+func (d *Detector) Close() error { return nil }
+
+// Send detects the anomalies of the containers of the pod of e and sends
+// them to the sinks.
+func (d *Detector) Send(e common.Event) error {
+	pod, ok := e.Object.(*v1.Pod)
+	if !ok || e.ResourceType != common.ResourcePod {
+		return nil
+	}
+
+	if e.EventType == common.EventDeleted {
+		d.lock.Lock()
+		delete(d.pods, pod.UID)
+		d.lock.Unlock()
+		return nil
+	}
+
+	for _, a := range d.Detect(pod) {
+		metrics.Anomalies.WithLabelValues(a.Type).Inc()
+		klog.V(2).InfoS("Detected container anomaly", "key", e.Key, "anomaly", a.Type, "container", a.Container,
+			"reason", a.Reason, "exitCode", a.ExitCode, "restartCount", a.RestartCount)
+
+		anomaly := common.Event{
+			Key:          e.Key,
+			EventType:    a.Type,
+			ResourceType: common.ResourceAnomaly,
+			Object:       a,
+			Time:         e.Time,
+			Context:      e.Context,
+		}
+		if err := d.sinks.Send(anomaly); err != nil {
+			klog.ErrorS(err, "Failed to send anomaly", anomaly.LogValues()...)
+		}
+	}
+	return nil
+}
+
+// Detect returns the anomalies of the containers of pod since its last
+// observation. The first observation of a container only reports the
+// states it is in, crash loops and image pull failures, not its past
+// terminations and restarts.
+func (d *Detector) Detect(pod *v1.Pod) []*Anomaly {
+	t := d.opts.thresholds(pod.Namespace, common.DeploymentName(pod))
+	now := d.now()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var anomalies []*Anomaly
+	report := func(a *Anomaly) {
+		if !t.ignores(a.Type) {
+			anomalies = append(anomalies, a)
+		}
+	}
+
+	if now.Sub(d.lastPrune) >= pruneInterval {
+		d.prune(now)
+	}
+
+	state, ok := d.pods[pod.UID]
+	if !ok {
+		state = &podState{namespace: pod.Namespace, name: pod.Name, containers: map[string]*container{}}
+		d.pods[pod.UID] = state
+	}
+	containers := state.containers
+
+	statuses := append(append([]v1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		c, seen := containers[s.Name]
+		if !seen {
+			c = &container{restartCount: s.RestartCount}
+			containers[s.Name] = c
+		}
+
+		waiting := s.State.Waiting
+		switch {
+		case waiting != nil && waiting.Reason == "CrashLoopBackOff":
+			if !c.crashLoop && s.RestartCount >= t.CrashLoopRestarts {
+				c.crashLoop = true
+				a := newAnomaly(TypeCrashLoop, pod, s)
+				a.Message = waiting.Message
+				a.terminated(s.LastTerminationState.Terminated)
+				report(a)
+			}
+		case waiting != nil && imagePullReasons[waiting.Reason]:
+			if !c.imagePull {
+				c.imagePull = true
+				a := newAnomaly(TypeImagePull, pod, s)
+				a.Reason = waiting.Reason
+				a.Message = waiting.Message
+				report(a)
+			}
+		}
+		if s.Ready {
+			c.crashLoop = false
+		}
+		if s.State.Running != nil {
+			c.imagePull = false
+		}
+
+		// the current termination of containers that are not restarted,
+		// or the last one
+		term := s.State.Terminated
+		if term == nil {
+			term = s.LastTerminationState.Terminated
+		}
+		if term != nil && term.FinishedAt.Time.After(c.lastFinished) {
+			if seen && term.Reason == "OOMKilled" {
+				a := newAnomaly(TypeOOMKilled, pod, s)
+				a.terminated(term)
+				report(a)
+			}
+			c.lastFinished = term.FinishedAt.Time
+		}
+
+		if seen && s.RestartCount > c.restartCount {
+			c.restarts = append(c.restarts, restarts{at: now, count: s.RestartCount - c.restartCount})
+		}
+		c.restartCount = s.RestartCount
+
+		cutoff := now.Add(-t.RestartStormWindow)
+		for len(c.restarts) > 0 && c.restarts[0].at.Before(cutoff) {
+			c.restarts = c.restarts[1:]
+		}
+		var recent int32
+		for _, r := range c.restarts {
+			recent += r.count
+		}
+		if recent >= t.RestartStormRestarts && c.stormEmitted.Before(cutoff) {
+			c.stormEmitted = now
+			a := newAnomaly(TypeRestartStorm, pod, s)
+			a.Restarts = recent
+			a.terminated(s.LastTerminationState.Terminated)
+			report(a)
+		}
+	}
+	return anomalies
+}
+
+// prune forgets the pods that no longer exist. The caller holds the lock.
+func (d *Detector) prune(now time.Time) {
+	d.lastPrune = now
+	if d.opts.Exists == nil {
+		return
+	}
+	for uid, state := range d.pods {
+		if !d.opts.Exists(state.namespace, state.name, uid) {
+			delete(d.pods, uid)
+		}
+	}
+}
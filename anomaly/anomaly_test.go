@@ -0,0 +1,199 @@
+package anomaly
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var start = time.Date(2022, 3, 1, 2, 0, 0, 0, time.UTC)
+
+// recorder is a sink recording the events it is sent.
+type recorder struct {
+	events []common.Event
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Send(e common.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
+
+// anomalies returns the anomalies recorded since the last call, described
+// as type:container:reason:exitCode:restartCount:restarts.
+func (r *recorder) anomalies() []string {
+	var anomalies []string
+	for _, e := range r.events {
+		a := e.Object.(*Anomaly)
+		if e.ResourceType != common.ResourceAnomaly || e.EventType != a.Type {
+			panic(fmt.Sprintf("unexpected event %+v", e))
+		}
+		anomalies = append(anomalies, fmt.Sprintf("%s:%s:%s:%d:%d:%d", a.Type, a.Container, a.Reason, a.ExitCode, a.RestartCount, a.Restarts))
+	}
+	r.events = nil
+	return anomalies
+}
+
+var controller = true
+
+// web is a pod of the web deployment. The tests send copies of it with the
+// container statuses of their cases.
+var web = &v1.Pod{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:            "web-1",
+		Namespace:       "default",
+		UID:             "uid-web-1",
+		ResourceVersion: "1",
+		Labels:          map[string]string{appv1.DefaultDeploymentUniqueLabelKey: "5d4f8c"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f8c", Controller: &controller}},
+	},
+}
+
+func running(restarts int32, ready bool, last *v1.ContainerStateTerminated) v1.ContainerStatus {
+	return v1.ContainerStatus{
+		Name:                 "app",
+		Ready:                ready,
+		RestartCount:         restarts,
+		State:                v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+		LastTerminationState: v1.ContainerState{Terminated: last},
+	}
+}
+
+func waiting(reason string, restarts int32, last *v1.ContainerStateTerminated) v1.ContainerStatus {
+	return v1.ContainerStatus{
+		Name:                 "app",
+		RestartCount:         restarts,
+		State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason}},
+		LastTerminationState: v1.ContainerState{Terminated: last},
+	}
+}
+
+func terminated(reason string, exitCode int32, minute int) *v1.ContainerStateTerminated {
+	return &v1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode, FinishedAt: metav1.NewTime(start.Add(time.Duration(minute) * time.Minute))}
+}
+
+func TestDetector(t *testing.T) {
+	sink := &recorder{}
+	d := NewDetector(Options{Thresholds: Thresholds{CrashLoopRestarts: 2, RestartStormRestarts: 3}}, watcher.Sinks{sink})
+	now := start
+	d.now = func() time.Time { return now }
+
+	for i, test := range []struct {
+		minutes   int
+		eventType string
+		status    v1.ContainerStatus
+		expected  []string
+	}{
+		{0, common.EventAdded, running(0, true, nil), nil},
+		{1, common.EventModified, running(1, true, terminated("OOMKilled", 137, 1)), []string{"OOMKilled:app:OOMKilled:137:1:0"}},
+		// a resync reports nothing new
+		{1, common.EventModified, running(1, true, terminated("OOMKilled", 137, 1)), nil},
+		{2, common.EventModified, waiting("CrashLoopBackOff", 2, terminated("Error", 1, 2)), []string{"CrashLoopBackOff:app:Error:1:2:0"}},
+		{3, common.EventModified, running(3, false, terminated("Error", 1, 3)), []string{"RestartStorm:app:Error:1:3:3"}},
+		// reported until ready again, or the storm window has passed
+		{4, common.EventModified, waiting("CrashLoopBackOff", 4, terminated("Error", 1, 4)), nil},
+		{15, common.EventModified, running(9, true, terminated("Error", 1, 15)), []string{"RestartStorm:app:Error:1:9:5"}},
+		{16, common.EventModified, waiting("CrashLoopBackOff", 10, terminated("OOMKilled", 137, 16)), []string{"CrashLoopBackOff:app:OOMKilled:137:10:0", "OOMKilled:app:OOMKilled:137:10:0"}},
+	} {
+		now = start.Add(time.Duration(test.minutes) * time.Minute)
+		p := web.DeepCopy()
+		p.Status.ContainerStatuses = []v1.ContainerStatus{test.status}
+		if err := d.Send(common.Event{Key: "default/web-1", EventType: test.eventType, ResourceType: common.ResourcePod, Object: p}); err != nil {
+			t.Fatal(err)
+		}
+		if anomalies := sink.anomalies(); !reflect.DeepEqual(anomalies, test.expected) {
+			t.Errorf("%d: expected anomalies %v, got %v", i, test.expected, anomalies)
+		}
+	}
+
+	if err := d.Send(common.Event{Key: "default/web-1", EventType: common.EventDeleted, ResourceType: common.ResourcePod, Object: web}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.pods) != 0 {
+		t.Errorf("expected the containers of the deleted pod to be forgotten, got %v", d.pods)
+	}
+}
+
+func TestDetectorFirstObservation(t *testing.T) {
+	sink := &recorder{}
+	d := NewDetector(Options{}, watcher.Sinks{sink})
+
+	// past terminations and restarts are not reported, current states are
+	for i, test := range []struct {
+		eventType string
+		name      string
+		status    v1.ContainerStatus
+		expected  []string
+	}{
+		{common.EventAdded, "web-1", waiting("CrashLoopBackOff", 12, terminated("OOMKilled", 137, 0)), []string{"CrashLoopBackOff:app:OOMKilled:137:12:0"}},
+		{common.EventAdded, "web-2", waiting("ErrImagePull", 0, nil), []string{"ImagePullFailure:app:ErrImagePull:0:0:0"}},
+		{common.EventModified, "web-2", waiting("ImagePullBackOff", 0, nil), nil},
+	} {
+		p := web.DeepCopy()
+		p.Name, p.UID = test.name, types.UID("uid-"+test.name)
+		p.Status.ContainerStatuses = []v1.ContainerStatus{test.status}
+		d.Send(common.Event{Key: "default/" + test.name, EventType: test.eventType, ResourceType: common.ResourcePod, Object: p})
+		if anomalies := sink.anomalies(); !reflect.DeepEqual(anomalies, test.expected) {
+			t.Errorf("%d: expected anomalies %v, got %v", i, test.expected, anomalies)
+		}
+	}
+}
+
+func TestDeploymentThresholds(t *testing.T) {
+	sink := &recorder{}
+	d := NewDetector(Options{Deployments: map[string]Thresholds{
+		"production/web": {CrashLoopRestarts: 1},
+		"web":            {Ignore: []string{"crashloopbackoff"}},
+	}}, watcher.Sinks{sink})
+
+	for _, namespace := range []string{"default", "production"} {
+		p := web.DeepCopy()
+		p.Namespace, p.UID = namespace, types.UID("uid-"+namespace)
+		p.Status.ContainerStatuses = []v1.ContainerStatus{waiting("CrashLoopBackOff", 1, terminated("Error", 2, 0))}
+		d.Send(common.Event{Key: namespace + "/web-1", EventType: common.EventAdded, ResourceType: common.ResourcePod, Object: p})
+	}
+	if anomalies, expected := sink.anomalies(), []string{"CrashLoopBackOff:app:Error:2:1:0"}; !reflect.DeepEqual(anomalies, expected) {
+		t.Errorf("expected anomalies %v, got %v", expected, anomalies)
+	}
+
+	if deployment := common.DeploymentName(&Anomaly{ObjectMeta: web.ObjectMeta}); deployment != "web" {
+		t.Errorf("expected anomalies to belong to the deployment of their pod, got %q", deployment)
+	}
+}
+
+func TestDetectorForgetsVanishedPods(t *testing.T) {
+	existing := map[types.UID]bool{"uid-web-1": true, "uid-web-2": true}
+	d := NewDetector(Options{Exists: func(namespace, name string, uid types.UID) bool { return existing[uid] }}, watcher.Sinks{&recorder{}})
+	now := start
+	d.now = func() time.Time { return now }
+
+	for _, name := range []string{"web-1", "web-2"} {
+		p := web.DeepCopy()
+		p.Name, p.UID = name, types.UID("uid-"+name)
+		p.Status.ContainerStatuses = []v1.ContainerStatus{running(0, true, nil)}
+		d.Send(common.Event{Key: "default/" + name, EventType: common.EventAdded, ResourceType: common.ResourcePod, Object: p})
+	}
+
+	// web-1 is gone without its deletion being seen
+	delete(existing, "uid-web-1")
+	now = now.Add(pruneInterval)
+	p := web.DeepCopy()
+	p.Name, p.UID = "web-2", "uid-web-2"
+	p.Status.ContainerStatuses = []v1.ContainerStatus{running(0, true, nil)}
+	d.Send(common.Event{Key: "default/web-2", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: p})
+
+	if _, ok := d.pods["uid-web-1"]; ok || len(d.pods) != 1 {
+		t.Errorf("expected only web-2 to be remembered, got %v", d.pods)
+	}
+}
@@ -0,0 +1,169 @@
+// Package cloudevents encodes the events of dwserver as CloudEvents 1.0, in
+// the JSON structured mode and the HTTP binary mode.
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SpecVersion is the version of the CloudEvents specification.
+	SpecVersion = "1.0"
+
+	// TypePrefix prefixes the types of the events, io.dw.pod.modified for
+	// example.
+	TypePrefix = "io.dw"
+
+	// ContentType is the media type of the structured mode.
+	ContentType = "application/cloudevents+json"
+
+	// DataContentType is the media type of the data of the events, the
+	// object they were observed with.
+	DataContentType = "application/json"
+
+	// headerPrefix prefixes the attributes sent as HTTP headers in the
+	// binary mode.
+	headerPrefix = "Ce-"
+)
+
+// Event is a CloudEvent. Its JSON encoding is the structured mode.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// Type returns the type of the events of resource of eventType, such as
+// io.dw.pod.modified. The words of event types are dot separated,
+// io.dw.deployment.rollout.started for example.
+func Type(resource, eventType string) string {
+	return TypePrefix + "." + strings.ToLower(resource) + "." + strings.ToLower(strings.ReplaceAll(eventType, "_", "."))
+}
+
+// Source returns the source of the events of resource in namespace, such as
+// /namespaces/default/pods. Cluster scoped resources have no namespace.
+func Source(namespace, resource string) string {
+	if namespace == "" {
+		return "/" + plural(resource)
+	}
+	return "/namespaces/" + namespace + "/" + plural(resource)
+}
+
+// plural returns the plural of resource, anomalies for anomaly.
+func plural(resource string) string {
+	resource = strings.ToLower(resource)
+	if strings.HasSuffix(resource, "y") {
+		return strings.TrimSuffix(resource, "y") + "ies"
+	}
+	return resource + "s"
+}
+
+// New returns the CloudEvent of e. Its source is derived from the namespace
+// and the resource of the object, its subject is the name of the object.
+// The ID is derived from the UID and the resourceVersion of the object and
+// the event type, so that redelivered events can be deduplicated.
+func New(e common.Event) (*Event, error) {
+	obj, err := meta.Accessor(e.Object)
+	if err != nil {
+		return nil, fmt.Errorf("event %s has no object: %v", e.Key, err)
+	}
+
+	data, err := json.Marshal(e.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	return &Event{
+		SpecVersion:     SpecVersion,
+		ID:              id(e, obj),
+		Source:          Source(obj.GetNamespace(), e.ResourceType),
+		Type:            Type(e.ResourceType, e.EventType),
+		Subject:         obj.GetName(),
+		Time:            t.UTC(),
+		DataContentType: DataContentType,
+		Data:            data,
+	}, nil
+}
+
+// ID returns the ID of the CloudEvent of e, see New. It is empty for events
+// without object.
+func ID(e common.Event) string {
+	obj, err := meta.Accessor(e.Object)
+	if err != nil {
+		return ""
+	}
+	return id(e, obj)
+}
+
+func id(e common.Event, obj metav1.Object) string {
+	uid := string(obj.GetUID())
+	if uid == "" {
+		uid = e.Key
+	}
+	return uid + "/" + obj.GetResourceVersion() + "/" + strings.ToLower(e.EventType)
+}
+
+// Structured returns the structured mode encoding of e.
+func Structured(e common.Event) ([]byte, error) {
+	event, err := New(e)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(event)
+}
+
+// Binary returns the body of the binary mode encoding of ev, the data, and
+// sets the attributes in header.
+func (ev *Event) Binary(header http.Header) []byte {
+	header.Set(headerPrefix+"Specversion", ev.SpecVersion)
+	header.Set(headerPrefix+"Id", ev.ID)
+	header.Set(headerPrefix+"Source", ev.Source)
+	header.Set(headerPrefix+"Type", ev.Type)
+	if ev.Subject != "" {
+		header.Set(headerPrefix+"Subject", ev.Subject)
+	}
+	header.Set(headerPrefix+"Time", ev.Time.Format(time.RFC3339Nano))
+	header.Set("Content-Type", ev.DataContentType)
+	return ev.Data
+}
+
+// FromBinary decodes the binary mode encoding of an event from the HTTP
+// header and body.
+func FromBinary(header http.Header, body []byte) (*Event, error) {
+	ev := &Event{
+		SpecVersion:     header.Get(headerPrefix + "Specversion"),
+		ID:              header.Get(headerPrefix + "Id"),
+		Source:          header.Get(headerPrefix + "Source"),
+		Type:            header.Get(headerPrefix + "Type"),
+		Subject:         header.Get(headerPrefix + "Subject"),
+		DataContentType: header.Get("Content-Type"),
+		Data:            body,
+	}
+	if ev.SpecVersion != SpecVersion {
+		return nil, fmt.Errorf("unsupported specversion %q", ev.SpecVersion)
+	}
+
+	var err error
+	if ev.Time, err = time.Parse(time.RFC3339Nano, header.Get(headerPrefix+"Time")); err != nil {
+		return nil, fmt.Errorf("invalid time: %v", err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,194 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podEvent() common.Event {
+	return common.Event{
+		Key:          "default/web-1",
+		EventType:    common.EventModified,
+		ResourceType: common.ResourcePod,
+		Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-1", Namespace: "default", UID: "6f1c", ResourceVersion: "42",
+		}},
+		Time: time.Date(2022, 3, 1, 2, 0, 0, 0, time.FixedZone("CET", 3600)),
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		e    common.Event
+		want Event
+	}{
+		{
+			name: "pod",
+			e:    podEvent(),
+			want: Event{
+				SpecVersion:     SpecVersion,
+				ID:              "6f1c/42/modified",
+				Source:          "/namespaces/default/pods",
+				Type:            "io.dw.pod.modified",
+				Subject:         "web-1",
+				Time:            time.Date(2022, 3, 1, 1, 0, 0, 0, time.UTC),
+				DataContentType: DataContentType,
+			},
+		},
+		{
+			name: "deployment without uid",
+			e: common.Event{
+				Key:          "prod/api",
+				EventType:    common.EventDeleted,
+				ResourceType: common.ResourceDeployment,
+				Object:       &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod", ResourceVersion: "7"}},
+				Time:         time.Date(2022, 3, 1, 1, 0, 0, 0, time.UTC),
+			},
+			want: Event{
+				SpecVersion:     SpecVersion,
+				ID:              "prod/api/7/deleted",
+				Source:          "/namespaces/prod/deployments",
+				Type:            "io.dw.deployment.deleted",
+				Subject:         "api",
+				Time:            time.Date(2022, 3, 1, 1, 0, 0, 0, time.UTC),
+				DataContentType: DataContentType,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := New(test.e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := got.Data
+			got.Data = nil
+			if !got.Time.Equal(test.want.Time) || got.Time.Location() != time.UTC {
+				t.Errorf("time %v, expected %v", got.Time, test.want.Time)
+			}
+			got.Time = test.want.Time
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("New() = %+v, expected %+v", *got, test.want)
+			}
+
+			var obj metav1.PartialObjectMetadata
+			if err := json.Unmarshal(data, &obj); err != nil || obj.Name != test.want.Subject {
+				t.Errorf("unexpected data %s: %v", data, err)
+			}
+		})
+	}
+}
+
+func TestRolloutEvents(t *testing.T) {
+	for eventType, expected := range map[string]string{
+		common.EventRolloutStarted:   "io.dw.deployment.rollout.started",
+		common.EventRolloutCompleted: "io.dw.deployment.rollout.completed",
+		common.EventRolloutFailed:    "io.dw.deployment.rollout.failed",
+	} {
+		e := common.Event{
+			Key:          "prod/api",
+			EventType:    eventType,
+			ResourceType: common.ResourceDeployment,
+			Object:       &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod", UID: "9a2e", ResourceVersion: "7"}},
+			Time:         time.Date(2022, 3, 1, 1, 0, 0, 0, time.UTC),
+		}
+
+		body, err := Structured(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var attributes map[string]interface{}
+		if err := json.Unmarshal(body, &attributes); err != nil {
+			t.Fatal(err)
+		}
+		if attributes["type"] != expected || attributes["subject"] != "api" || attributes["source"] != "/namespaces/prod/deployments" {
+			t.Errorf("%s: unexpected structured attributes %v", eventType, attributes)
+		}
+
+		event, err := New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := http.Header{}
+		event.Binary(header)
+		if header.Get("ce-type") != expected || header.Get("ce-subject") != "api" || header.Get("ce-source") != "/namespaces/prod/deployments" {
+			t.Errorf("%s: unexpected binary header %v", eventType, header)
+		}
+
+		// the rollout and the update of the same version are distinct events
+		if modified := ID(common.Event{Key: e.Key, EventType: common.EventModified, Object: e.Object}); event.ID == modified {
+			t.Errorf("%s: expected an ID distinct from the update, got %s", eventType, event.ID)
+		}
+	}
+}
+
+func TestNewWithoutObject(t *testing.T) {
+	if _, err := New(common.Event{Key: "default/web-1", EventType: common.EventAdded, ResourceType: common.ResourcePod}); err == nil {
+		t.Error("expected an error for an event without object")
+	}
+}
+
+func TestStructured(t *testing.T) {
+	body, err := Structured(podEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(body, &attributes); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"specversion":     "1.0",
+		"id":              "6f1c/42/modified",
+		"source":          "/namespaces/default/pods",
+		"type":            "io.dw.pod.modified",
+		"subject":         "web-1",
+		"time":            "2022-03-01T01:00:00Z",
+		"datacontenttype": "application/json",
+	} {
+		if attributes[name] != want {
+			t.Errorf("%s = %v, expected %q", name, attributes[name], want)
+		}
+	}
+	if data, ok := attributes["data"].(map[string]interface{}); !ok || data["metadata"] == nil {
+		t.Errorf("expected the pod as data, got %v", attributes["data"])
+	}
+}
+
+func TestBinary(t *testing.T) {
+	event, err := New(podEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{}
+	body := event.Binary(header)
+	if header.Get("ce-type") != "io.dw.pod.modified" || header.Get("Content-Type") != DataContentType {
+		t.Errorf("unexpected header %v", header)
+	}
+
+	got, err := FromBinary(header, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != event.ID || got.Source != event.Source || got.Type != event.Type || got.Subject != event.Subject ||
+		!got.Time.Equal(event.Time) || string(got.Data) != string(event.Data) {
+		t.Errorf("FromBinary() = %+v, expected %+v", got, event)
+	}
+
+	header.Del("ce-specversion")
+	if _, err := FromBinary(header, body); err == nil {
+		t.Error("expected an error without specversion")
+	}
+}
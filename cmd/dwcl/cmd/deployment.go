@@ -6,7 +6,7 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 var deploymentCmd = &cobra.Command{
@@ -28,7 +28,7 @@ var deploymentWatchCmd = &cobra.Command{
 			err        error
 		)
 
-		if kubeConfig, err = common.ClientConfig(kubeConfigPath); err != nil {
+		if kubeConfig, err = common.ClientConfig(clientOpts); err != nil {
 			panic(err.Error())
 		}
 
@@ -37,7 +37,19 @@ var deploymentWatchCmd = &cobra.Command{
 			panic(err.Error())
 		}
 
-		dw := watcher.NewDeploymentWatcher(clientset, namespace)
+		opts, err := watcherOptions()
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		sinks, err := watchSinks()
+		if err != nil {
+			klog.Fatal(err)
+		}
+		defer sinks.Close()
+
+		manager := watcher.NewManager(clientset, opts)
+		dw := watcher.NewDeploymentWatcher(manager, namespace, sinks...)
 
 		stop := make(chan struct{})
 		defer close(stop)
@@ -45,11 +57,12 @@ var deploymentWatchCmd = &cobra.Command{
 		if err != nil {
 			klog.Fatal(err)
 		}
-		select {}
+		waitForSignal()
 	},
 }
 
 func init() {
 	deploymentCmd.AddCommand(deploymentWatchCmd)
+	addWatchFlags(deploymentWatchCmd)
 	deploymentWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "deployment namespace")
 }
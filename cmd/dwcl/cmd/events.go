@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events [Remote GRPC Server addr]",
+	Short: "query the event history of dwserver",
+	Long: `query the event history of dwserver
+
+--since and --until take a time as RFC3339, a clock time of today such as
+02:15, or a duration before now such as 15m. --replay reads the on-disk
+event log instead of the in-memory history, which outlives restarts of
+dwserver.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		since, err := parseEventTime(eventsSince, now)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %v", err)
+		}
+		until, err := parseEventTime(eventsUntil, now)
+		if err != nil {
+			return fmt.Errorf("invalid --until: %v", err)
+		}
+		if err := checkQueryOutput(); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		defer cancel()
+
+		conn, err := dialServer(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		client := pb.NewPodStatIntfClient(conn)
+		request := &pb.QueryEventsRequest{
+			Since:      since,
+			Until:      until,
+			Namespace:  namespace,
+			Deployment: eventsDeployment,
+			Pod:        eventsPod,
+			Types:      eventsTypes,
+			Resource:   eventsResource,
+			Limit:      int32(eventsLimit),
+		}
+
+		var (
+			events    []*pb.Event
+			truncated bool
+		)
+		if eventsReplay {
+			if events, err = replayEvents(ctx, client, request); err != nil {
+				return err
+			}
+		} else {
+			reply, err := client.QueryEvents(ctx, request)
+			if err != nil {
+				return err
+			}
+			events, truncated = reply.GetEvents(), reply.GetTruncated()
+		}
+
+		if queryOutput == "json" {
+			for _, e := range events {
+				data, err := protojson.Marshal(e)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			}
+		} else {
+			printEvents(events)
+		}
+
+		if truncated {
+			fmt.Fprintf(os.Stderr, "more than %d events matched, narrow the time range or raise --limit\n", eventsLimit)
+		}
+		return nil
+	},
+}
+
+// replayEvents returns the events of the event log selected by request.
+func replayEvents(ctx context.Context, client pb.PodStatIntfClient, request *pb.QueryEventsRequest) ([]*pb.Event, error) {
+	stream, err := client.ReplayEvents(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*pb.Event
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+}
+
+// parseEventTime parses a time of the events command, nil if value is
+// empty.
+func parseEventTime(value string, now time.Time) (*timestamppb.Timestamp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return timestamppb.New(now.Add(-d)), nil
+	}
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			y, m, d := now.Date()
+			return timestamppb.New(time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location())), nil
+		}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a duration, a clock time or an RFC3339 time", value)
+	}
+	return timestamppb.New(t), nil
+}
+
+// printEvents prints events as a table.
+func printEvents(events []*pb.Event) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "TIME\tTYPE\tRESOURCE\tNAMESPACE\tNAME\tDEPLOYMENT\tPHASE\tNODE\tDETAIL")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.GetTime().AsTime().Local().Format(time.RFC3339),
+			e.GetType(),
+			e.GetResource(),
+			e.GetNamespace(),
+			e.GetName(),
+			orNone(e.GetDeployment()),
+			orNone(e.GetPodstat().GetPodstate()),
+			orNone(e.GetPodstat().GetNodename()),
+			orNone(eventDetail(e)))
+	}
+}
+
+// eventDetail describes the anomaly of an anomaly event, or why the pod of
+// a pod event is not running as expected.
+func eventDetail(e *pb.Event) string {
+	a := e.GetAnomaly()
+	if a == nil {
+		return e.GetPodstat().GetReason()
+	}
+
+	detail := "container " + a.GetContainer()
+	if a.GetReason() != "" {
+		detail += ": " + a.GetReason()
+	}
+	if a.GetExitCode() != 0 {
+		detail += fmt.Sprintf(", exit code %d", a.GetExitCode())
+	}
+	detail += fmt.Sprintf(", %d restarts", a.GetRestartCount())
+	if a.GetRestarts() > 0 {
+		detail += fmt.Sprintf(" (%d recent)", a.GetRestarts())
+	}
+	return detail
+}
+
+func orNone(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func init() {
+	eventsCmd.Flags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "namespace of the events, empty for all namespaces")
+	eventsCmd.Flags().StringVar(&eventsSince, "since", eventsSince, "only events at or after this time")
+	eventsCmd.Flags().StringVar(&eventsUntil, "until", eventsUntil, "only events at or before this time")
+	eventsCmd.Flags().StringVar(&eventsDeployment, "deployment", eventsDeployment, "only events of this deployment and its pods")
+	eventsCmd.Flags().StringVar(&eventsPod, "pod", eventsPod, "only events of this pod")
+	eventsCmd.Flags().StringVar(&eventsResource, "resource", eventsResource, "only events of pods, deployments or anomalies: pod, deployment or anomaly")
+	eventsCmd.Flags().StringSliceVar(&eventsTypes, "type", eventsTypes, "only events of these types: added, modified, deleted, rollout_started, rollout_completed, rollout_failed, or anomaly types such as OOMKilled")
+	eventsCmd.Flags().IntVar(&eventsLimit, "limit", eventsLimit, "maximum number of events, the oldest first, 0 for no limit")
+	eventsCmd.Flags().BoolVar(&eventsReplay, "replay", eventsReplay, "replay the on-disk event log of dwserver instead of querying its in-memory history")
+	addQueryFlags(eventsCmd)
+}
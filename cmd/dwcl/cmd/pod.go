@@ -7,8 +7,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 var podCmd = &cobra.Command{
@@ -30,7 +29,7 @@ var podWatchCmd = &cobra.Command{
 			err        error
 		)
 
-		if kubeConfig, err = common.ClientConfig(kubeConfigPath); err != nil {
+		if kubeConfig, err = common.ClientConfig(clientOpts); err != nil {
 			panic(err.Error())
 		}
 
@@ -39,8 +38,19 @@ var podWatchCmd = &cobra.Command{
 			panic(err.Error())
 		}
 
-		q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), common.PodQueue)
-		pw := watcher.NewPodWatcher(clientset, namespace, q)
+		opts, err := watcherOptions()
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		sinks, err := watchSinks()
+		if err != nil {
+			klog.Fatal(err)
+		}
+		defer sinks.Close()
+
+		manager := watcher.NewManager(clientset, opts)
+		pw := watcher.NewPodWatcher(manager, namespace, sinks...)
 
 		stop := make(chan struct{})
 		defer close(stop)
@@ -48,11 +58,12 @@ var podWatchCmd = &cobra.Command{
 		if err != nil {
 			klog.Fatal(err)
 		}
-		select {}
+		waitForSignal()
 	},
 }
 
 func init() {
 	podCmd.AddCommand(podWatchCmd)
+	addWatchFlags(podWatchCmd)
 	podWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace")
 }
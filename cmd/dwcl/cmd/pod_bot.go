@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"strconv"
@@ -12,6 +11,8 @@ import (
 
 	podbot "github.com/bobbybho/k8s-deployment-watcher/testbots/podbots"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
 )
 
 var podBotCmd = &cobra.Command{
@@ -27,7 +28,6 @@ var podBotRunCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		var podBotCnt int64
 		var err error
-		//var opt grpc.DialOption
 		var remoteAddr string
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -51,17 +51,30 @@ var podBotRunCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		dialOpt, err := tlsOpts.DialOption()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+		tokenDialOpts, err := tokenOpts.DialOptions()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		dialOpts := append([]grpc.DialOption{dialOpt}, tokenDialOpts...)
+
 		podBotList := make([]podbot.PodBot, podBotCnt)
 
 		for i := 0; i < int(podBotCnt); i++ {
 			podBotName := fmt.Sprintf("podbot-%d", i)
-			podBotList = append(podBotList, podbot.PodBot{Name: podBotName})
+			podBotList = append(podBotList, podbot.PodBot{Name: podBotName, Namespace: namespace, IncludeChanges: includeChanges, CloudEvents: cloudEvents})
 		}
 
 		for _, pBot := range podBotList {
 			pB := pBot
 			go func() {
-				pB.Run(ctx, remoteAddr)
+				pB.Run(ctx, remoteAddr, dialOpts...)
 			}()
 		}
 
@@ -69,7 +82,7 @@ var podBotRunCmd = &cobra.Command{
 		for {
 			select {
 			case sig := <-sigs:
-				log.Printf("\nReceived a signal %v\n", sig)
+				klog.InfoS("Received signal, stopping pod bots", "signal", sig)
 				cancel()
 				break waitLoop
 			default:
@@ -82,5 +95,9 @@ var podBotRunCmd = &cobra.Command{
 
 func init() {
 	podBotCmd.AddCommand(podBotRunCmd)
-	podBotRunCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace")
+	tlsOpts.AddFlags(podBotCmd.PersistentFlags())
+	tokenOpts.AddFlags(podBotCmd.PersistentFlags())
+	podBotRunCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "pod namespace, empty for all namespaces")
+	podBotRunCmd.Flags().BoolVar(&includeChanges, "include-changes", false, "ask for the changed fields of updated pods")
+	podBotRunCmd.Flags().BoolVar(&cloudEvents, "cloudevents", false, "print the events as CloudEvents to stdout, one per line")
 }
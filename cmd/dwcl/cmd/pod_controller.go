@@ -1,21 +1,18 @@
 package cmd
 
 import (
-	"log"
-	"net"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/bobbybho/k8s-deployment-watcher/common"
-	"github.com/bobbybho/k8s-deployment-watcher/controller"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/server"
+	watcher "github.com/bobbybho/k8s-deployment-watcher/watcher"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-
-	podserver "github.com/bobbybho/k8s-deployment-watcher/grpc/server/pod"
-	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+	"k8s.io/klog/v2"
 )
 
 var podControllerCmd = &cobra.Command{
@@ -35,10 +32,10 @@ var podControllerWatchCmd = &cobra.Command{
 		var (
 			kubeConfig *rest.Config
 			err        error
-			errc       chan error
+			errc       = make(chan error, 1)
 		)
 
-		if kubeConfig, err = common.ClientConfig(kubeConfigPath); err != nil {
+		if kubeConfig, err = common.ClientConfig(clientOpts); err != nil {
 			panic(err.Error())
 		}
 
@@ -50,48 +47,72 @@ var podControllerWatchCmd = &cobra.Command{
 		// register for signals
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGQUIT)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGHUP)
-		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
-
-		pc := controller.NewPodController(clientset, namespace)
 
-		addr := "0.0.0.0:8088"
-
-		// TODO: the listening addr should be read from a config
-		lis, err := net.Listen("tcp", addr)
+		cfg, err := podControllerConfig()
+		if err != nil {
+			klog.Exitf("Invalid configuration: %v", err)
+		}
+		watcherOpts, err := cfg.WatcherOptions()
 		if err != nil {
-			log.Fatalf("failed to listen: %v", err)
+			klog.Exitf("Invalid configuration: %v", err)
 		}
 
-		s := grpc.NewServer()
-		pb.RegisterPodStatIntfServer(s, &podserver.PodServer{})
+		srv, err := server.New(cfg, watcher.NewManager(clientset, watcherOpts), clientset)
+		if err != nil {
+			klog.Exitf("Failed to create server: %v", err)
+		}
 
+		stop := make(chan struct{})
 		go func() {
-			log.Printf("GRPC server is listening on %v", addr)
-			errc <- s.Serve(lis)
+			errc <- srv.Run(stop)
 		}()
 
-		stop := make(chan struct{})
-		defer close(stop)
-		pc.Run(stop)
-
-	waitloop:
-		for {
-			select {
-			case sig := <-sigs:
-				log.Printf("Received a signal: %v\n", sig)
-				break waitloop
-			case err := <-errc:
-				log.Printf("Received error from gRPC server: %v\n", err.Error())
-				break waitloop
-			}
+		select {
+		case sig := <-sigs:
+			klog.InfoS("Received signal, shutting down", "signal", sig)
+		case err := <-errc:
+			klog.ErrorS(err, "Server failed")
+			close(stop)
+			return
+		}
+
+		// wait for the streams to drain and the informers to stop
+		close(stop)
+		if err := <-errc; err != nil {
+			klog.ErrorS(err, "Failed to shut down cleanly")
 		}
-		log.Println("Bye")
+		klog.Info("Bye")
+		klog.Flush()
 	},
 }
 
+// podControllerConfig returns the configuration of the server of the
+// pod-controller command: the gRPC server only, watching the pods of the
+// namespace flag with the sinks of the sinks flag.
+func podControllerConfig() (*config.Config, error) {
+	cfg := config.Default()
+	cfg.Namespaces = nil
+	if namespace != "" {
+		cfg.Namespaces = []string{namespace}
+	}
+	cfg.Server.ListenAddress = listenAddress
+	cfg.Server.HTTPAddress = ""
+
+	cfg.Sinks.Pods = nil
+	for _, spec := range sinkSpecs {
+		sink, err := config.ParseSink(spec)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Sinks.Pods = append(cfg.Sinks.Pods, sink)
+	}
+
+	return cfg, cfg.Validate()
+}
+
 func init() {
 	podControllerCmd.AddCommand(podControllerWatchCmd)
-	podControllerWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace")
+	podControllerWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace, empty for all namespaces")
+	podControllerWatchCmd.Flags().StringSliceVar(&sinkSpecs, "sinks", sinkSpecs, "sinks of the pod events besides the gRPC clients: log, stdout[=cloudevents], file=PATH, webhook=URL, nats=URL/SUBJECT or kafka=BROKER/TOPIC")
+	podControllerWatchCmd.PersistentFlags().StringVar(&listenAddress, "listen-address", config.DefaultListenAddress, "gRPC listen address")
 }
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var podTimelineCmd = &cobra.Command{
+	Use:   "timeline [Remote GRPC Server addr] [pod]",
+	Short: "show the lifecycle timelines and startup latencies of pods",
+	Long: `show the lifecycle timelines and startup latencies of pods
+
+With a pod, prints the time of each step of its lifecycle: Created,
+Scheduled, Initialized, Started, ContainersReady and Ready. Without, prints
+the latencies of every pod of the namespace, or of --deployment, and their
+percentiles per deployment and per zone.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkQueryOutput(); err != nil {
+			return err
+		}
+
+		request := &pb.PodTimelineRequest{Namespace: namespace, Deployment: timelineDeployment}
+		if len(args) == 2 {
+			request.Pod = args[1]
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		defer cancel()
+
+		conn, err := dialServer(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		reply, err := pb.NewPodStatIntfClient(conn).GetPodTimeline(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case queryOutput == "json":
+			data, err := protojson.Marshal(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		case request.Pod != "":
+			for _, t := range reply.GetTimelines() {
+				printTimeline(t)
+			}
+		default:
+			printTimelines(reply)
+		}
+		return nil
+	},
+}
+
+// printTimeline prints the steps of t.
+func printTimeline(t *pb.PodTimeline) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Pod:\t%s/%s\n", t.GetNamespace(), t.GetName())
+	fmt.Fprintf(w, "Deployment:\t%s\n", orNone(t.GetDeployment()))
+	fmt.Fprintf(w, "Node:\t%s\n", orNone(t.GetNode()))
+	fmt.Fprintf(w, "Zone:\t%s\n", orNone(t.GetZone()))
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "STEP\tTIME\tSINCE CREATED")
+	var created time.Time
+	for _, step := range t.GetSteps() {
+		if step.GetTime() == nil {
+			fmt.Fprintf(w, "%s\t<pending>\t\n", step.GetName())
+			continue
+		}
+		at := step.GetTime().AsTime()
+		if created.IsZero() {
+			created = at
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", step.GetName(), at.Local().Format(time.RFC3339), at.Sub(created))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Scheduling latency:\t%s\n", latency(t.GetSchedulingLatency()))
+	fmt.Fprintf(w, "Startup latency:\t%s\n", latency(t.GetStartupLatency()))
+	fmt.Fprintf(w, "Time to ready:\t%s\n", latency(t.GetTimeToReady()))
+}
+
+// printTimelines prints the latencies of the timelines of reply and their
+// percentiles.
+func printTimelines(reply *pb.PodTimelineReply) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tDEPLOYMENT\tZONE\tSCHEDULING\tSTARTUP\tREADY")
+	for _, t := range reply.GetTimelines() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.GetNamespace(),
+			t.GetName(),
+			orNone(t.GetDeployment()),
+			orNone(t.GetZone()),
+			latency(t.GetSchedulingLatency()),
+			latency(t.GetStartupLatency()),
+			latency(t.GetTimeToReady()))
+	}
+
+	summaries := append(append([]*pb.LatencySummary(nil), reply.GetDeployments()...), reply.GetZones()...)
+	if len(summaries) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "GROUP\tLATENCY\tCOUNT\tP50\tP90\tP99\tMAX")
+	for _, s := range summaries {
+		group := "zone " + s.GetZone()
+		if s.GetDeployment() != "" {
+			group = "deployment " + s.GetNamespace() + "/" + s.GetDeployment()
+		}
+		for _, l := range []struct {
+			name  string
+			stats *pb.LatencyStats
+		}{
+			{"scheduling", s.GetScheduling()},
+			{"startup", s.GetStartup()},
+			{"ready", s.GetTimeToReady()},
+		} {
+			if l.stats.GetCount() == 0 {
+				fmt.Fprintf(w, "%s\t%s\t0\t<none>\t<none>\t<none>\t<none>\n", group, l.name)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", group, l.name, l.stats.GetCount(),
+				l.stats.GetP50().AsDuration(), l.stats.GetP90().AsDuration(), l.stats.GetP99().AsDuration(), l.stats.GetMax().AsDuration())
+		}
+	}
+}
+
+// latency formats d, <none> if it is not known.
+func latency(d *durationpb.Duration) string {
+	if d.AsDuration() == 0 {
+		return "<none>"
+	}
+	return d.AsDuration().String()
+}
+
+func init() {
+	podCmd.AddCommand(podTimelineCmd)
+	addQueryFlags(podTimelineCmd)
+	podTimelineCmd.Flags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace, empty for all namespaces")
+	podTimelineCmd.Flags().StringVar(&timelineDeployment, "deployment", timelineDeployment, "only the pods of this deployment")
+}
@@ -1,10 +1,20 @@
 package cmd
 
 import (
-	"path/filepath"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	homedir "github.com/mitchellh/go-homedir"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/diff"
+	"github.com/bobbybho/k8s-deployment-watcher/logging"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -13,16 +23,53 @@ var (
 		Args:  cobra.NoArgs,
 		Short: "dwsh commands",
 		Long:  `dwsh commands`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return logging.Configure(logLevel, logFormat)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
 	}
 
-	kubeConfigPathDefault = ""
-	kubeConfigPath        = ""
+	clientOpts common.ClientOptions
+
+	tlsOpts   common.TLSClientOptions
+	tokenOpts common.TokenOptions
 
 	nameSpaceDefault = "default"
 	namespace        = ""
+
+	listenAddress = ""
+
+	logLevel  = 0
+	logFormat = logging.FormatText
+
+	// output is the format the watch commands print the changes of updates
+	// in, empty to only log them.
+	output          = ""
+	diffIgnorePaths = diff.DefaultIgnorePaths
+
+	includeChanges = false
+	cloudEvents    = false
+
+	sinkSpecs = []string{watcher.SinkLog}
+
+	// the selection and the output of the events command
+	eventsSince      = ""
+	eventsUntil      = ""
+	eventsDeployment = ""
+	eventsPod        = ""
+	eventsResource   = ""
+	eventsTypes      []string
+	eventsLimit      = 0
+	eventsReplay     = false
+
+	// the deployment the timelines are selected by
+	timelineDeployment = ""
+
+	// the output format and the timeout of the commands querying dwserver
+	queryOutput  = "table"
+	queryTimeout = 10 * time.Second
 )
 
 // Execute executes the root command.
@@ -30,21 +77,98 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
-func getKubeConfigPath() string {
-	home, err := homedir.Dir()
+// watcherOptions returns the options of the watch commands.
+func watcherOptions() (watcher.Options, error) {
+	opts := watcher.DefaultOptions()
+
+	differ, err := diff.New(diff.Options{IgnorePaths: diffIgnorePaths})
+	if err != nil {
+		return opts, err
+	}
+	opts.Differ = differ
+
+	if output != "" {
+		if opts.OnUpdate, err = watcher.PrintUpdates(os.Stdout, output); err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+// watchSinks builds the sinks of the watch commands.
+func watchSinks() (watcher.Sinks, error) {
+	opts := make([]watcher.SinkOptions, 0, len(sinkSpecs))
+	for _, spec := range sinkSpecs {
+		o, err := watcher.ParseSink(spec)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, o)
+	}
+	return watcher.NewSinks(opts)
+}
+
+// dialServer connects to the dwserver at address with the TLS and token
+// flags.
+func dialServer(ctx context.Context, address string) (*grpc.ClientConn, error) {
+	dialOpt, err := tlsOpts.DialOption()
+	if err != nil {
+		return nil, err
+	}
+	tokenDialOpts, err := tokenOpts.DialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	dialOpts := append([]grpc.DialOption{dialOpt, grpc.WithBlock()}, tokenDialOpts...)
+	conn, err := grpc.DialContext(ctx, address, dialOpts...)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
 	}
+	return conn, nil
+}
+
+// checkQueryOutput checks the output format of the query commands.
+func checkQueryOutput() error {
+	if queryOutput != "table" && queryOutput != "json" {
+		return fmt.Errorf("unsupported output %q, expected table or json", queryOutput)
+	}
+	return nil
+}
+
+// addQueryFlags adds the flags of dialServer and of the output of the
+// query commands to cmd.
+func addQueryFlags(cmd *cobra.Command) {
+	tlsOpts.AddFlags(cmd.Flags())
+	tokenOpts.AddFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&queryOutput, "output", "o", queryOutput, "output format, table or json")
+	cmd.Flags().DurationVar(&queryTimeout, "timeout", queryTimeout, "timeout of the query")
+}
+
+// waitForSignal blocks until dwcl is told to terminate.
+func waitForSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	klog.InfoS("Received signal, shutting down", "signal", sig)
+}
 
-	return filepath.Join(home, ".kube", "config")
+// addWatchFlags adds the flags of watcherOptions and watchSinks to cmd.
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&sinkSpecs, "sinks", sinkSpecs, "sinks of the events: log, stdout[=cloudevents], file=PATH, webhook=URL, nats=URL/SUBJECT or kafka=BROKER/TOPIC")
+	cmd.Flags().StringVarP(&output, "output", "o", output, "print the changes of updates as text, json or json-patch")
+	cmd.Flags().StringSliceVar(&diffIgnorePaths, "diff-ignore-paths", diffIgnorePaths, "field paths left out of the changes of updates, * matches any key or index")
 }
 
 func init() {
-	kubeConfigPathDefault = getKubeConfigPath()
-	kubeConfigPath = kubeConfigPathDefault
+	clientOpts.AddFlags(rootCmd.PersistentFlags())
+	rootCmd.PersistentFlags().IntVar(&logLevel, "log-level", logLevel, "log verbosity")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logFormat, "log format, text or json")
 
 	rootCmd.AddCommand(deploymentCmd)
 	rootCmd.AddCommand(podCmd)
 	rootCmd.AddCommand(podControllerCmd)
 	rootCmd.AddCommand(podBotCmd)
+	rootCmd.AddCommand(eventsCmd)
 }
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Args:  cobra.NoArgs,
+	Short: "configuration commands",
+	Long:  `configuration commands`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Args:  cobra.NoArgs,
+	Short: "validate the configuration",
+	Long:  `validate the configuration resolved from the config file, the environment and the flags`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := loadConfig(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("configuration is valid")
+	},
+}
+
+var configPrintDefaultsCmd = &cobra.Command{
+	Use:   "print-defaults",
+	Args:  cobra.NoArgs,
+	Short: "print the default configuration",
+	Long:  `print the default configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out, err := config.Default().Marshal()
+		if err != nil {
+			panic(err.Error())
+		}
+		fmt.Print(string(out))
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPrintDefaultsCmd)
+}
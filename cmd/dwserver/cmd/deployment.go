@@ -6,7 +6,7 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 var deploymentCmd = &cobra.Command{
@@ -28,7 +28,12 @@ var deploymentWatchCmd = &cobra.Command{
 			err        error
 		)
 
-		if kubeConfig, err = common.ClientConfig(kubeConfigPath); err != nil {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		if kubeConfig, err = common.ClientConfig(clientOpts); err != nil {
 			panic(err.Error())
 		}
 
@@ -37,19 +42,35 @@ var deploymentWatchCmd = &cobra.Command{
 			panic(err.Error())
 		}
 
-		dw := watcher.NewDeploymentWatcher(clientset, namespace)
+		sinks, err := watchSinks(cfg, common.ResourceDeployment)
+		if err != nil {
+			klog.Fatal(err)
+		}
+		defer sinks.Close()
 
-		stop := make(chan struct{})
-		defer close(stop)
-		err = dw.Run(stop)
+		onUpdate, err := printUpdates()
 		if err != nil {
 			klog.Fatal(err)
 		}
-		select {}
+
+		manager, err := newManager(kubeConfig, clientset, cfg, onUpdate)
+		if err != nil {
+			panic(err.Error())
+		}
+
+		stop := make(chan struct{})
+		defer close(stop)
+		for _, namespace := range watchedNamespaces(cfg) {
+			dw := watcher.NewDeploymentWatcher(manager, namespace, sinks...)
+			if err = dw.Run(stop); err != nil {
+				klog.Fatal(err)
+			}
+		}
+		waitForSignal()
 	},
 }
 
 func init() {
 	deploymentCmd.AddCommand(deploymentWatchCmd)
-	deploymentWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "deployment namespace")
+	deploymentWatchCmd.Flags().StringVarP(&output, "output", "o", output, "print the changes of updates as text, json or json-patch")
 }
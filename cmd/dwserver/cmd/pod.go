@@ -7,8 +7,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 var podCmd = &cobra.Command{
@@ -30,7 +29,12 @@ var podWatchCmd = &cobra.Command{
 			err        error
 		)
 
-		if kubeConfig, err = common.ClientConfig(kubeConfigPath); err != nil {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		if kubeConfig, err = common.ClientConfig(clientOpts); err != nil {
 			panic(err.Error())
 		}
 
@@ -39,20 +43,35 @@ var podWatchCmd = &cobra.Command{
 			panic(err.Error())
 		}
 
-		q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), common.PodQueue)
-		pw := watcher.NewPodWatcher(clientset, namespace, q)
+		sinks, err := watchSinks(cfg, common.ResourcePod)
+		if err != nil {
+			klog.Fatal(err)
+		}
+		defer sinks.Close()
 
-		stop := make(chan struct{})
-		defer close(stop)
-		err = pw.Run(stop)
+		onUpdate, err := printUpdates()
 		if err != nil {
 			klog.Fatal(err)
 		}
-		select {}
+
+		manager, err := newManager(kubeConfig, clientset, cfg, onUpdate)
+		if err != nil {
+			panic(err.Error())
+		}
+
+		stop := make(chan struct{})
+		defer close(stop)
+		for _, namespace := range watchedNamespaces(cfg) {
+			pw := watcher.NewPodWatcher(manager, namespace, sinks...)
+			if err = pw.Run(stop); err != nil {
+				klog.Fatal(err)
+			}
+		}
+		waitForSignal()
 	},
 }
 
 func init() {
 	podCmd.AddCommand(podWatchCmd)
-	podWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace")
+	podWatchCmd.Flags().StringVarP(&output, "output", "o", output, "print the changes of updates as text, json or json-patch")
 }
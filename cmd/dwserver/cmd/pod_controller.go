@@ -1,22 +1,25 @@
 package cmd
 
 import (
-	"log"
-	"net"
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/bobbybho/k8s-deployment-watcher/controller"
-	podserver "github.com/bobbybho/k8s-deployment-watcher/grpc/server/pod"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/server"
+	"github.com/bobbybho/k8s-deployment-watcher/tracing"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-
-	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+	"k8s.io/klog/v2"
 )
 
+// tracingFlushTimeout bounds the export of the remaining spans on exit.
+const tracingFlushTimeout = 5 * time.Second
+
 var podControllerCmd = &cobra.Command{
 	Use:   "pod-controller",
 	Args:  cobra.NoArgs,
@@ -34,10 +37,28 @@ var podControllerWatchCmd = &cobra.Command{
 		var (
 			kubeConfig *rest.Config
 			err        error
-			errc       chan error
+			errc       = make(chan error, 1)
 		)
 
-		if kubeConfig, err = rest.InClusterConfig(); err != nil {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			klog.Exitf("Failed to load config: %v", err)
+		}
+
+		shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingOptions())
+		if err != nil {
+			klog.Exitf("Failed to set up tracing: %v", err)
+		}
+		defer func() {
+			// flush the spans of the last events
+			ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				klog.ErrorS(err, "Failed to flush traces")
+			}
+		}()
+
+		if kubeConfig, err = common.ClientConfig(clientOpts); err != nil {
 			panic(err.Error())
 		}
 
@@ -48,49 +69,67 @@ var podControllerWatchCmd = &cobra.Command{
 
 		// register for signals
 		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGQUIT)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGHUP)
-		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
-
-		pc := controller.NewPodController(clientset, namespace)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-		addr := "0.0.0.0:8088"
+		manager, err := newManager(kubeConfig, clientset, cfg, nil)
+		if err != nil {
+			panic(err.Error())
+		}
 
-		// TODO: the listening addr should be read from a config
-		lis, err := net.Listen("tcp", addr)
+		srv, err := server.New(cfg, manager, clientset)
 		if err != nil {
-			log.Fatalf("failed to listen: %v", err)
+			klog.Exitf("Failed to create server: %v", err)
 		}
 
-		s := grpc.NewServer()
-		pb.RegisterPodStatIntfServer(s, &podserver.PodServer{PodController: pc})
+		stop := make(chan struct{})
+
+		var configChanges <-chan struct{}
+		if configPath != "" {
+			if configChanges, err = server.WatchConfigFile(configPath, stop); err != nil {
+				klog.ErrorS(err, "Failed to watch config file, reload with SIGHUP", "path", configPath)
+			}
+		}
 
 		go func() {
-			log.Printf("GRPC server is listening on %v", addr)
-			errc <- s.Serve(lis)
+			errc <- srv.Run(stop)
 		}()
 
-		stop := make(chan struct{})
-		defer close(stop)
-		pc.Run(stop)
+		// the server applies the logging settings once the reload succeeds
+		reload := func() (*config.Config, error) {
+			return resolveConfig(cmd)
+		}
 
 	waitloop:
 		for {
 			select {
 			case sig := <-sigs:
-				log.Printf("Received a signal: %v\n", sig)
+				if sig == syscall.SIGHUP {
+					klog.InfoS("Received signal, reloading configuration", "signal", sig)
+					srv.Reload(reload)
+					continue
+				}
+				klog.InfoS("Received signal, shutting down", "signal", sig)
 				break waitloop
+			case <-configChanges:
+				klog.InfoS("Config file changed, reloading configuration", "path", configPath)
+				srv.Reload(reload)
 			case err := <-errc:
-				log.Printf("Received error from gRPC server: %v\n", err.Error())
-				break waitloop
+				klog.ErrorS(err, "Server failed")
+				close(stop)
+				return
 			}
 		}
-		log.Println("Bye")
+
+		// wait for the streams to drain and the informers to stop
+		close(stop)
+		if err := <-errc; err != nil {
+			klog.ErrorS(err, "Failed to shut down cleanly")
+		}
+		klog.Info("Bye")
+		klog.Flush()
 	},
 }
 
 func init() {
 	podControllerCmd.AddCommand(podControllerWatchCmd)
-	podControllerWatchCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", nameSpaceDefault, "pod namespace")
 }
@@ -1,10 +1,19 @@
 package cmd
 
 import (
-	"path/filepath"
+	"os"
+	"os/signal"
+	"syscall"
 
-	homedir "github.com/mitchellh/go-homedir"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/logging"
+	watcher "github.com/bobbybho/k8s-deployment-watcher/watcher"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -18,11 +27,13 @@ var (
 		},
 	}
 
-	kubeConfigPathDefault = ""
-	kubeConfigPath        = ""
+	clientOpts common.ClientOptions
 
-	nameSpaceDefault = "default"
-	namespace        = ""
+	configPath = ""
+
+	// output is the format the watch commands print the changes of updates
+	// in, empty to only log them.
+	output = ""
 )
 
 // Execute executes the root command.
@@ -30,20 +41,112 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
-func getKubeConfigPath() string {
-	home, err := homedir.Dir()
+// loadConfig resolves and validates the configuration, and applies its
+// logging settings.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := resolveConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := logging.Configure(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// resolveConfig resolves the configuration from the config file, the
+// environment and the flags of cmd, and validates it.
+func resolveConfig(cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := config.Resolve(configPath, cmd.Flags())
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	return filepath.Join(home, ".kube", "config")
+	return cfg, nil
+}
+
+// watchedNamespaces returns the namespaces to watch, NamespaceAll if none
+// are configured.
+func watchedNamespaces(cfg *config.Config) []string {
+	if len(cfg.Namespaces) == 0 {
+		return []string{""}
+	}
+	return cfg.Namespaces
+}
+
+// watchSinks builds the sinks and the webhooks of the events of resource.
+func watchSinks(cfg *config.Config, resource string) (watcher.Sinks, error) {
+	sinks, err := resourceSinks(cfg, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := cfg.WebhookSinks(resource)
+	if err != nil {
+		sinks.Close()
+		return nil, err
+	}
+
+	return append(sinks, webhooks...), nil
+}
+
+// resourceSinks builds the configured sinks of the events of resource only,
+// the sinks of the other resource would be opened and never closed.
+func resourceSinks(cfg *config.Config, resource string) (watcher.Sinks, error) {
+	if resource == common.ResourceDeployment {
+		return cfg.DeploymentSinks()
+	}
+	return cfg.PodSinks()
+}
+
+// waitForSignal blocks until dwserver is told to terminate.
+func waitForSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	klog.InfoS("Received signal, shutting down", "signal", sig)
+}
+
+// printUpdates returns the handler printing the changes of updates to
+// stdout in the output format, nil if no format is set.
+func printUpdates() (func(watcher.Update), error) {
+	if output == "" {
+		return nil, nil
+	}
+	return watcher.PrintUpdates(os.Stdout, output)
+}
+
+func newManager(kubeConfig *rest.Config, clientset kubernetes.Interface, cfg *config.Config, onUpdate func(watcher.Update)) (*watcher.Manager, error) {
+	opts, err := cfg.WatcherOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts.OnUpdate = onUpdate
+
+	if opts.MetadataOnlyDeployments {
+		metadataClient, err := metadata.NewForConfig(kubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		opts.MetadataClient = metadataClient
+	}
+
+	return watcher.NewManager(clientset, opts), nil
 }
 
 func init() {
-	kubeConfigPathDefault = getKubeConfigPath()
-	kubeConfigPath = kubeConfigPathDefault
+	clientOpts.AddFlags(rootCmd.PersistentFlags())
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "path to the YAML or JSON configuration file")
+	config.AddFlags(rootCmd.PersistentFlags())
 
 	rootCmd.AddCommand(deploymentCmd)
 	rootCmd.AddCommand(podCmd)
 	rootCmd.AddCommand(podControllerCmd)
-}
\ No newline at end of file
+	rootCmd.AddCommand(configCmd)
+}
@@ -1,11 +1,97 @@
 package common
 
 import (
+	"os"
+
+	"github.com/spf13/pflag"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
 )
 
-// ClientConfig loads kubectl configuration
-func ClientConfig(configPath string) (*rest.Config, error) {
-	return clientcmd.BuildConfigFromFlags("", configPath)
+// ClientOptions selects and tunes the Kubernetes client configuration.
+type ClientOptions struct {
+	// Kubeconfig is an explicit kubeconfig path. Setting it, Context or
+	// Cluster skips the in-cluster configuration.
+	Kubeconfig string
+	Context    string
+	Cluster    string
+
+	// As and AsGroups impersonate a user and its groups.
+	As       string
+	AsGroups []string
+
+	// QPS and Burst limit the requests to the API server. Zero keeps the
+	// client-go defaults.
+	QPS   float32
+	Burst int
+}
+
+// AddFlags registers the client flags on fs.
+func (o *ClientOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "path to the kubeconfig file, defaults to the in-cluster config, then $KUBECONFIG, then ~/.kube/config")
+	fs.StringVar(&o.Context, "context", o.Context, "kubeconfig context to use")
+	fs.StringVar(&o.Cluster, "cluster", o.Cluster, "kubeconfig cluster to use")
+	fs.StringVar(&o.As, "as", o.As, "user to impersonate")
+	fs.StringSliceVar(&o.AsGroups, "as-group", o.AsGroups, "group to impersonate, can be repeated")
+	fs.Float32Var(&o.QPS, "kube-api-qps", o.QPS, "queries per second to the API server, 0 for the client-go default")
+	fs.IntVar(&o.Burst, "kube-api-burst", o.Burst, "burst of queries to the API server, 0 for the client-go default")
+}
+
+// ClientConfig loads the client configuration. Unless a kubeconfig, context
+// or cluster is given explicitly, the in-cluster configuration is tried
+// first, then the files listed in $KUBECONFIG, merged, then ~/.kube/config.
+// An in-cluster configuration that fails for another reason than running
+// out of a cluster falls back to $KUBECONFIG if it is set.
+func ClientConfig(opts ClientOptions) (*rest.Config, error) {
+	config, err := loadClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.As != "" || len(opts.AsGroups) > 0 {
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: opts.As,
+			Groups:   opts.AsGroups,
+		}
+	}
+
+	if opts.QPS > 0 {
+		config.QPS = opts.QPS
+	}
+	if opts.Burst > 0 {
+		config.Burst = opts.Burst
+	}
+
+	return config, nil
+}
+
+// inClusterConfig is replaced by tests.
+var inClusterConfig = rest.InClusterConfig
+
+func loadClientConfig(opts ClientOptions) (*rest.Config, error) {
+	if opts.Kubeconfig == "" && opts.Context == "" && opts.Cluster == "" {
+		config, err := inClusterConfig()
+		if err == nil {
+			klog.Info("Using in-cluster client configuration")
+			return config, nil
+		}
+
+		// a broken in-cluster configuration, such as a missing token, is
+		// only an error without an explicit kubeconfig to fall back to
+		if err != rest.ErrNotInCluster {
+			if os.Getenv(clientcmd.RecommendedConfigPathEnvVar) == "" {
+				return nil, err
+			}
+			klog.InfoS("In-cluster client configuration failed, falling back to $KUBECONFIG", "err", err)
+		}
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.Context}
+	overrides.Context.Cluster = opts.Cluster
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
 }
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev}
+- name: prod
+  context: {cluster: prod, user: dev}
+current-context: dev
+users:
+- name: dev
+  user: {token: dev-token}
+`
+
+func TestClientConfigFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(f func() (*rest.Config, error)) { inClusterConfig = f }(inClusterConfig)
+	inCluster := &rest.Config{Host: "https://10.96.0.1:443"}
+	errNoToken := errors.New("open /var/run/secrets/kubernetes.io/serviceaccount/token: no such file or directory")
+
+	for _, test := range []struct {
+		name       string
+		opts       ClientOptions
+		kubeconfig string
+		inCluster  error
+		expected   string
+	}{
+		{name: "in cluster", kubeconfig: path, expected: inCluster.Host},
+		{name: "out of cluster", kubeconfig: path, inCluster: rest.ErrNotInCluster, expected: "https://dev.example.com:6443"},
+		{name: "broken in-cluster config with $KUBECONFIG", kubeconfig: path, inCluster: errNoToken, expected: "https://dev.example.com:6443"},
+		{name: "broken in-cluster config", inCluster: errNoToken},
+		{name: "explicit kubeconfig", opts: ClientOptions{Kubeconfig: path}, expected: "https://dev.example.com:6443"},
+		{name: "explicit context", opts: ClientOptions{Context: "prod"}, kubeconfig: path, expected: "https://prod.example.com:6443"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("KUBECONFIG", test.kubeconfig)
+			inClusterConfig = func() (*rest.Config, error) {
+				if test.inCluster != nil {
+					return nil, test.inCluster
+				}
+				return rest.CopyConfig(inCluster), nil
+			}
+
+			config, err := ClientConfig(test.opts)
+			if test.expected == "" {
+				if err != test.inCluster {
+					t.Errorf("expected the in-cluster error, got %v, %v", config, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Host != test.expected {
+				t.Errorf("expected host %s, got %s", test.expected, config.Host)
+			}
+		})
+	}
+}
@@ -1,12 +1,71 @@
 package common
 
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
 const (
 	PodQueue = "pod-queue"
 )
 
+// Event types, named after the Kubernetes watch event types.
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+)
+
+// Rollout event types, sent for the updates of deployments that start,
+// complete or fail a rollout, besides their EventModified event.
+const (
+	EventRolloutStarted   = "ROLLOUT_STARTED"
+	EventRolloutCompleted = "ROLLOUT_COMPLETED"
+	EventRolloutFailed    = "ROLLOUT_FAILED"
+)
+
+// Resource types
+const (
+	ResourcePod        = "pod"
+	ResourceDeployment = "deployment"
+
+	// ResourceAnomaly events report an anomaly of a container of a pod,
+	// their event type is the type of the anomaly.
+	ResourceAnomaly = "anomaly"
+)
+
 // Event ...
 type Event struct {
 	Key          string
 	EventType    string
 	ResourceType string
+
+	// Object is the object the event was observed with. For deletes it is
+	// the final state of the object, or its last known state when the
+	// delete was only seen as a tombstone.
+	Object interface{}
+
+	// Old is the previous state of the object of a modified event, nil
+	// for the other events.
+	Old interface{}
+
+	// Time is when the watcher observed the event.
+	Time time.Time
+
+	// Context carries the span tracing the event from its queueing to the
+	// subscribers, nil if the event is not traced. The spans of its
+	// processing and retries are started from it. It is a context rather
+	// than a span so that common does not depend on the tracing API.
+	Context context.Context
+}
+
+// LogValues returns the key/value pairs identifying e in structured logs.
+func (e Event) LogValues() []interface{} {
+	values := []interface{}{"key", e.Key, "event", e.EventType, "resource", e.ResourceType}
+	if obj, err := meta.Accessor(e.Object); err == nil {
+		values = append(values, "resourceVersion", obj.GetResourceVersion())
+	}
+	return values
 }
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"strings"
+
+	appv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeploymentName returns the name of the deployment obj belongs to: the
+// name of a deployment, or of the deployment owning the ReplicaSet that owns
+// a pod. It is empty for pods that are not part of a deployment.
+func DeploymentName(obj interface{}) string {
+	if d, ok := obj.(*appv1.Deployment); ok {
+		return d.Name
+	}
+
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+
+	// ReplicaSets of a deployment are named after it and the hash of the
+	// pod template, which their pods are labeled with
+	hash := m.GetLabels()[appv1.DefaultDeploymentUniqueLabelKey]
+	owner := metav1.GetControllerOf(m)
+	if hash == "" || owner == nil || owner.Kind != "ReplicaSet" {
+		return ""
+	}
+	return strings.TrimSuffix(owner.Name, "-"+hash)
+}
+
+// Deployment returns the name of the deployment the object of e belongs to,
+// see DeploymentName.
+func (e Event) Deployment() string {
+	if e.ResourceType == ResourceDeployment {
+		if m, err := meta.Accessor(e.Object); err == nil {
+			return m.GetName()
+		}
+	}
+	return DeploymentName(e.Object)
+}
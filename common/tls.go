@@ -0,0 +1,88 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TLSClientOptions configures the connection of gRPC clients to dwserver.
+type TLSClientOptions struct {
+	// CAFile verifies the server certificate. The system roots are used if
+	// it is empty.
+	CAFile string
+
+	// CertFile and KeyFile are the client certificate presented to servers
+	// that verify clients.
+	CertFile string
+	KeyFile  string
+
+	// Insecure connects without TLS.
+	Insecure bool
+}
+
+// AddFlags registers the TLS flags on fs.
+func (o *TLSClientOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.CAFile, "tls-ca", o.CAFile, "CA bundle used to verify the server certificate, defaults to the system roots")
+	fs.StringVar(&o.CertFile, "tls-cert", o.CertFile, "client certificate file")
+	fs.StringVar(&o.KeyFile, "tls-key", o.KeyFile, "client private key file")
+	fs.BoolVar(&o.Insecure, "insecure", o.Insecure, "connect without TLS")
+}
+
+// DialOption returns the transport credentials described by o.
+func (o TLSClientOptions) DialOption() (grpc.DialOption, error) {
+	if o.Insecure {
+		if o.CAFile != "" || o.CertFile != "" || o.KeyFile != "" {
+			return nil, fmt.Errorf("--insecure cannot be combined with --tls-ca, --tls-cert or --tls-key")
+		}
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.CAFile != "" {
+		pool, err := LoadCertPool(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+// LoadCertPool reads the PEM encoded certificates of path into a pool.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+
+	return NewCertPool(data)
+}
+
+// NewCertPool returns a pool of the PEM encoded certificates in data.
+func NewCertPool(data []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA bundle")
+	}
+	return pool, nil
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+)
+
+// TokenOptions selects the bearer token gRPC clients authenticate with.
+type TokenOptions struct {
+	Token string
+
+	// TokenFile is read on every call, so that rotated tokens such as
+	// projected service account tokens are picked up.
+	TokenFile string
+}
+
+// AddFlags registers the token flags on fs.
+func (o *TokenOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Token, "token", o.Token, "bearer token to authenticate with")
+	fs.StringVar(&o.TokenFile, "token-file", o.TokenFile, "file holding the bearer token to authenticate with")
+}
+
+// DialOptions returns the per call credentials described by o, none if no
+// token is set. Tokens are only sent over TLS.
+func (o TokenOptions) DialOptions() ([]grpc.DialOption, error) {
+	if o.Token != "" && o.TokenFile != "" {
+		return nil, fmt.Errorf("--token and --token-file cannot be combined")
+	}
+	if o.Token == "" && o.TokenFile == "" {
+		return nil, nil
+	}
+
+	return []grpc.DialOption{grpc.WithPerRPCCredentials(tokenCredentials(o))}, nil
+}
+
+type tokenCredentials TokenOptions
+
+// GetRequestMetadata ...
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token := t.Token
+	if t.TokenFile != "" {
+		data, err := ioutil.ReadFile(t.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity ...
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
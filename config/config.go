@@ -0,0 +1,720 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/controller"
+	"github.com/bobbybho/k8s-deployment-watcher/diff"
+	"github.com/bobbybho/k8s-deployment-watcher/eventlog"
+	"github.com/bobbybho/k8s-deployment-watcher/grpc/server/limit"
+	"github.com/bobbybho/k8s-deployment-watcher/history"
+	"github.com/bobbybho/k8s-deployment-watcher/logging"
+	"github.com/bobbybho/k8s-deployment-watcher/timeline"
+	"github.com/bobbybho/k8s-deployment-watcher/tracing"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultListenAddress is the default gRPC listen address.
+	DefaultListenAddress = "0.0.0.0:8088"
+
+	// DefaultHTTPAddress is the default listen address of the metrics,
+	// health and debug endpoints.
+	DefaultHTTPAddress = "0.0.0.0:9090"
+
+	// DefaultNamespace is the namespace watched when none is configured.
+	DefaultNamespace = "default"
+
+	// LogFormatText logs in the klog text format.
+	LogFormatText = logging.FormatText
+
+	// LogFormatJSON logs one JSON object per line.
+	LogFormatJSON = logging.FormatJSON
+
+	// AuthorizationAlwaysAllow lets every authenticated client read every
+	// namespace.
+	AuthorizationAlwaysAllow = "AlwaysAllow"
+
+	// AuthorizationSubjectAccessReview lets clients read the namespaces
+	// they can list pods in.
+	AuthorizationSubjectAccessReview = "SubjectAccessReview"
+
+	// DefaultUnaryQPS and DefaultUnaryBurst rate limit the unary calls of
+	// each client.
+	DefaultUnaryQPS   = 10
+	DefaultUnaryBurst = 20
+
+	// DefaultShutdownTimeout is how long open streams are given to finish
+	// on shutdown.
+	DefaultShutdownTimeout = 10 * time.Second
+
+	// DefaultSampleRatio traces every pod event.
+	DefaultSampleRatio = 1.0
+)
+
+// Config is the dwserver configuration. It is read from a YAML or JSON file
+// and can be overridden from the environment and the command line, in that
+// order of precedence.
+type Config struct {
+	Server     ServerConfig     `json:"server"`
+	Namespaces []string         `json:"namespaces"`
+	TLS        TLSConfig        `json:"tls"`
+	Auth       AuthConfig       `json:"auth"`
+	Informer   InformerConfig   `json:"informer"`
+	Controller ControllerConfig `json:"controller"`
+	Filters    FilterConfig     `json:"filters"`
+	Logging    LoggingConfig    `json:"logging"`
+	Tracing    TracingConfig    `json:"tracing"`
+	Diff       DiffConfig       `json:"diff"`
+	Sinks      SinksConfig      `json:"sinks"`
+	Webhooks   []WebhookConfig  `json:"webhooks,omitempty"`
+	History    HistoryConfig    `json:"history"`
+	EventLog   EventLogConfig   `json:"eventLog"`
+	Timelines  TimelinesConfig  `json:"timelines"`
+	Anomalies  AnomaliesConfig  `json:"anomalies"`
+}
+
+// ServerConfig ...
+type ServerConfig struct {
+	// ListenAddress is the address the gRPC server listens on.
+	ListenAddress string `json:"listenAddress"`
+
+	// HTTPAddress is the address /metrics, /healthz, /readyz and the debug
+	// endpoints are served on. Empty disables them.
+	HTTPAddress string `json:"httpAddress"`
+
+	// Profiling serves the pprof handlers under /debug/pprof/ and the open
+	// streams under /debug/subscribers.
+	Profiling bool `json:"profiling,omitempty"`
+
+	// MaxSubscribers limits the number of ListenPodStatus streams. Zero
+	// means no limit.
+	MaxSubscribers int `json:"maxSubscribers,omitempty"`
+
+	// MaxStreamsPerClient limits the concurrent streams of each client.
+	// Zero means no limit.
+	MaxStreamsPerClient int `json:"maxStreamsPerClient,omitempty"`
+
+	// UnaryQPS and UnaryBurst rate limit the unary calls of each client.
+	// Zero QPS means no limit.
+	UnaryQPS   float64 `json:"unaryQPS"`
+	UnaryBurst int     `json:"unaryBurst"`
+
+	// ShutdownTimeout bounds the graceful stop of the gRPC server. Streams
+	// still open when it expires are cancelled.
+	ShutdownTimeout metav1.Duration `json:"shutdownTimeout"`
+}
+
+// TLSConfig selects the server certificate, read either from files or from
+// a kubernetes.io/tls Secret. Both are reloaded when they change.
+type TLSConfig struct {
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+
+	// Secret is the namespace/name of a Secret holding the certificate in
+	// its tls.crt and tls.key keys.
+	Secret string `json:"secret,omitempty"`
+
+	// ClientCAFile enables verification of client certificates against the
+	// CA bundle it points to.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+}
+
+// Enabled reports whether the server should serve TLS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || t.Secret != ""
+}
+
+// SecretKey splits Secret into its namespace and name.
+func (t TLSConfig) SecretKey() (namespace, name string, err error) {
+	parts := strings.Split(t.Secret, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q is not of the form namespace/name", t.Secret)
+	}
+	return parts[0], parts[1], nil
+}
+
+// AuthConfig selects how gRPC clients are authenticated and authorized. If
+// no authentication method is enabled, clients are not authenticated.
+type AuthConfig struct {
+	// ClientCertificates identifies clients by their verified TLS client
+	// certificate.
+	ClientCertificates bool `json:"clientCertificates,omitempty"`
+
+	// TokenReview validates bearer tokens with the TokenReview API, for
+	// TokenAudiences if set.
+	TokenReview    bool     `json:"tokenReview,omitempty"`
+	TokenAudiences []string `json:"tokenAudiences,omitempty"`
+
+	// StaticTokenFile is a CSV file of bearer tokens in the API server
+	// token file format: token,user,uid,"group1,group2".
+	StaticTokenFile string `json:"staticTokenFile,omitempty"`
+
+	// Authorization is AlwaysAllow or SubjectAccessReview.
+	Authorization string `json:"authorization"`
+}
+
+// AuthenticationEnabled reports whether clients must authenticate.
+func (a AuthConfig) AuthenticationEnabled() bool {
+	return a.ClientCertificates || a.TokenReview || a.StaticTokenFile != ""
+}
+
+// InformerConfig ...
+type InformerConfig struct {
+	// Resync is the informer resync period. Zero disables resyncs.
+	Resync                  metav1.Duration `json:"resync"`
+	ListPageSize            int64           `json:"listPageSize,omitempty"`
+	TrimObjects             bool            `json:"trimObjects,omitempty"`
+	MetadataOnlyDeployments bool            `json:"metadataOnlyDeployments,omitempty"`
+}
+
+// ControllerConfig ...
+type ControllerConfig struct {
+	Workers        int             `json:"workers"`
+	MaxRetries     int             `json:"maxRetries"`
+	QueueBaseDelay metav1.Duration `json:"queueBaseDelay"`
+	QueueMaxDelay  metav1.Duration `json:"queueMaxDelay"`
+	QueueQPS       float64         `json:"queueQPS"`
+	QueueBurst     int             `json:"queueBurst"`
+}
+
+// FilterConfig selects the pods reported to clients.
+type FilterConfig struct {
+	LabelSelector string `json:"labelSelector,omitempty"`
+}
+
+// LoggingConfig ...
+type LoggingConfig struct {
+	// Level is the klog verbosity. Pod diffs are logged from level 5.
+	Level int `json:"level"`
+
+	// Format is LogFormatText or LogFormatJSON.
+	Format string `json:"format"`
+}
+
+// TracingConfig configures the OpenTelemetry traces of pod events and gRPC
+// calls.
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP gRPC receiver spans are
+	// exported to. Empty disables tracing.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Insecure exports spans without TLS.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// SampleRatio is the fraction of pod events traced, between 0 and 1.
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// DiffConfig configures the changes reported for updated pods and
+// deployments.
+type DiffConfig struct {
+	// IgnorePaths are the field paths left out of the changes, such as
+	// metadata.managedFields or metadata.labels.*.
+	IgnorePaths []string `json:"ignorePaths"`
+}
+
+// HistoryConfig sets the retention of the event history served by
+// QueryEvents.
+type HistoryConfig struct {
+	// MaxEvents is the number of events kept per pod and per deployment.
+	// Zero disables the history.
+	MaxEvents int `json:"maxEvents"`
+
+	// MaxAge is how long events are kept.
+	MaxAge metav1.Duration `json:"maxAge"`
+}
+
+// Enabled reports whether the event history is kept.
+func (h HistoryConfig) Enabled() bool {
+	return h.MaxEvents > 0
+}
+
+// EventLogConfig enables the on-disk event log served by ReplayEvents.
+type EventLogConfig struct {
+	// Dir is the directory of the log. Empty disables it.
+	Dir string `json:"dir,omitempty"`
+
+	// SegmentSizeMB is the size segments are sealed at, the default if
+	// zero.
+	SegmentSizeMB int `json:"segmentSizeMB,omitempty"`
+
+	// MaxSizeMB bounds the size of the log. Zero means no limit.
+	MaxSizeMB int `json:"maxSizeMB,omitempty"`
+
+	// MaxAge is how long events are kept.
+	MaxAge metav1.Duration `json:"maxAge"`
+
+	// CompactInterval is how often expired and duplicate events are
+	// dropped.
+	CompactInterval metav1.Duration `json:"compactInterval"`
+}
+
+// Enabled reports whether the event log is kept.
+func (e EventLogConfig) Enabled() bool {
+	return e.Dir != ""
+}
+
+// TimelinesConfig configures the pod lifecycle timelines served by
+// GetPodTimeline. They are off by default: their zone summaries watch the
+// nodes of the cluster, which needs list and watch on nodes.
+type TimelinesConfig struct {
+	// MaxAge is how long the timelines of deleted pods are kept. Zero
+	// disables the timelines.
+	MaxAge metav1.Duration `json:"maxAge"`
+}
+
+// Enabled reports whether the timelines are kept.
+func (t TimelinesConfig) Enabled() bool {
+	return t.MaxAge.Duration > 0
+}
+
+// AnomaliesConfig configures the detection of the crash loops, OOMKills,
+// image pull failures and restart storms of containers, off by default like
+// the other optional sinks.
+type AnomaliesConfig struct {
+	// Detect turns the detection on.
+	Detect bool `json:"detect,omitempty"`
+
+	// AnomalyThresholds apply to every pod.
+	AnomalyThresholds `json:",inline"`
+
+	// Deployments override the thresholds of the pods of deployments.
+	Deployments []DeploymentAnomaliesConfig `json:"deployments,omitempty"`
+}
+
+// Enabled reports whether anomalies are detected.
+func (a AnomaliesConfig) Enabled() bool {
+	return a.Detect
+}
+
+// AnomalyThresholds decide when a container is anomalous. Zero fields of
+// the thresholds of a deployment keep the ones of every pod.
+type AnomalyThresholds struct {
+	// CrashLoopRestarts is the restart count from which a container in
+	// CrashLoopBackOff is reported.
+	CrashLoopRestarts int `json:"crashLoopRestarts,omitempty"`
+
+	// RestartStormRestarts restarts of a container within
+	// RestartStormWindow are reported as a restart storm.
+	RestartStormRestarts int             `json:"restartStormRestarts,omitempty"`
+	RestartStormWindow   metav1.Duration `json:"restartStormWindow"`
+
+	// Ignore are the anomaly types not reported.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+func (t AnomalyThresholds) thresholds() anomaly.Thresholds {
+	return anomaly.Thresholds{
+		CrashLoopRestarts:    int32(t.CrashLoopRestarts),
+		RestartStormRestarts: int32(t.RestartStormRestarts),
+		RestartStormWindow:   t.RestartStormWindow.Duration,
+		Ignore:               t.Ignore,
+	}
+}
+
+// DeploymentAnomaliesConfig sets the anomaly thresholds of the pods of a
+// deployment.
+type DeploymentAnomaliesConfig struct {
+	// Namespace is the namespace of the deployment, empty for the
+	// deployments of that name in every namespace.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	AnomalyThresholds `json:",inline"`
+}
+
+func (d DeploymentAnomaliesConfig) key() string {
+	if d.Namespace == "" {
+		return d.Name
+	}
+	return d.Namespace + "/" + d.Name
+}
+
+// SinksConfig selects where the events of the pod and deployment watchers
+// are sent. Pod events are always broadcast to the gRPC subscribers too.
+type SinksConfig struct {
+	Pods        []SinkConfig `json:"pods"`
+	Deployments []SinkConfig `json:"deployments"`
+}
+
+// SinkConfig ...
+type SinkConfig struct {
+	// Type is log, stdout, file, webhook, nats or kafka.
+	Type string `json:"type"`
+
+	// URL is the endpoint webhook sinks POST the events to, the comma
+	// separated servers of nats sinks or brokers of kafka sinks.
+	URL string `json:"url,omitempty"`
+
+	// Topic is the subject prefix of nats sinks, which a JetStream stream
+	// must capture, or the topic of kafka sinks.
+	Topic string `json:"topic,omitempty"`
+
+	// Timeout bounds each webhook request or publish.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Path is the file of file sinks.
+	Path string `json:"path,omitempty"`
+
+	// MaxSizeMB is the size in megabytes a file sink rotates at.
+	MaxSizeMB int64 `json:"maxSizeMB,omitempty"`
+
+	// MaxBackups is the number of rotated files kept.
+	MaxBackups int `json:"maxBackups,omitempty"`
+
+	// Format is json or cloudevents, the encoding of the events written by
+	// stdout, file, webhook, nats and kafka sinks.
+	Format string `json:"format,omitempty"`
+
+	// BatchSize is the number of events nats and kafka sinks publish at
+	// once.
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// FlushInterval is the longest nats and kafka sinks hold an event to
+	// fill a batch.
+	FlushInterval *metav1.Duration `json:"flushInterval,omitempty"`
+
+	// DeadLetterFile is the file nats and kafka sinks append the events
+	// they could not publish to.
+	DeadLetterFile string `json:"deadLetterFile,omitempty"`
+}
+
+// ParseSink parses the short form of a sink of watcher.ParseSink. The other
+// settings of the sink keep their defaults.
+func ParseSink(spec string) (SinkConfig, error) {
+	opts, err := watcher.ParseSink(spec)
+	if err != nil {
+		return SinkConfig{}, err
+	}
+	return SinkConfig{Type: opts.Type, URL: opts.URL, Topic: opts.Topic, Path: opts.Path, Format: opts.Format}, nil
+}
+
+// Options ...
+func (s SinkConfig) Options() watcher.SinkOptions {
+	opts := watcher.SinkOptions{
+		Type:           s.Type,
+		URL:            s.URL,
+		Path:           s.Path,
+		MaxSize:        s.MaxSizeMB << 20,
+		MaxBackups:     s.MaxBackups,
+		Format:         s.Format,
+		Topic:          s.Topic,
+		BatchSize:      s.BatchSize,
+		DeadLetterPath: s.DeadLetterFile,
+	}
+	if s.Timeout != nil {
+		opts.Timeout = s.Timeout.Duration
+	}
+	if s.FlushInterval != nil {
+		opts.FlushInterval = s.FlushInterval.Duration
+	}
+	return opts
+}
+
+// WebhookConfig describes an HTTP endpoint the pod and deployment events are
+// POSTed to.
+type WebhookConfig struct {
+	URL string `json:"url"`
+
+	// Format is json, slack, cloudevents or cloudevents-binary.
+	Format string `json:"format,omitempty"`
+
+	// Template is a Go template rendering the body from the event record,
+	// in place of Format.
+	Template string `json:"template,omitempty"`
+
+	// ContentType of templated bodies.
+	ContentType string `json:"contentType,omitempty"`
+
+	// SecretFile holds the key the requests are signed with, with
+	// HMAC-SHA256 in the X-Dw-Signature-256 header.
+	SecretFile string `json:"secretFile,omitempty"`
+
+	// Resources, Namespaces, Deployments and EventTypes filter the events
+	// sent. Empty lists match every event.
+	Resources   []string `json:"resources,omitempty"`
+	Namespaces  []string `json:"namespaces,omitempty"`
+	Deployments []string `json:"deployments,omitempty"`
+	EventTypes  []string `json:"eventTypes,omitempty"`
+
+	// Timeout bounds each request.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// MaxRetries is the number of times a failed request is retried, 3 if
+	// unset.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+
+	// Backoff is the delay before the first retry, doubled up to MaxBackoff.
+	Backoff    *metav1.Duration `json:"backoff,omitempty"`
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// DeadLetterFile is the file undeliverable events are appended to.
+	DeadLetterFile string `json:"deadLetterFile,omitempty"`
+}
+
+// Options returns the options of the webhook. The secret is read from
+// SecretFile.
+func (w WebhookConfig) Options() (watcher.WebhookOptions, error) {
+	opts := watcher.WebhookOptions{
+		URL:         w.URL,
+		Format:      w.Format,
+		Template:    w.Template,
+		ContentType: w.ContentType,
+		Filter: watcher.EventFilter{
+			Resources:   w.Resources,
+			Namespaces:  w.Namespaces,
+			Deployments: w.Deployments,
+			EventTypes:  w.EventTypes,
+		},
+		MaxRetries:     watcher.DefaultWebhookRetries,
+		DeadLetterPath: w.DeadLetterFile,
+	}
+	if w.Timeout != nil {
+		opts.Timeout = w.Timeout.Duration
+	}
+	if w.MaxRetries != nil {
+		opts.MaxRetries = *w.MaxRetries
+	}
+	if w.Backoff != nil {
+		opts.Backoff = w.Backoff.Duration
+	}
+	if w.MaxBackoff != nil {
+		opts.MaxBackoff = w.MaxBackoff.Duration
+	}
+
+	if w.SecretFile != "" {
+		secret, err := ioutil.ReadFile(w.SecretFile)
+		if err != nil {
+			return opts, fmt.Errorf("failed to read webhook secret: %v", err)
+		}
+		opts.Secret = []byte(strings.TrimSpace(string(secret)))
+	}
+
+	return opts, nil
+}
+
+// Default returns the configuration dwserver runs with when nothing is
+// configured.
+func Default() *Config {
+	informerOpts := watcher.DefaultOptions()
+	controllerOpts := controller.DefaultOptions()
+
+	return &Config{
+		Server: ServerConfig{
+			ListenAddress:   DefaultListenAddress,
+			HTTPAddress:     DefaultHTTPAddress,
+			UnaryQPS:        DefaultUnaryQPS,
+			UnaryBurst:      DefaultUnaryBurst,
+			ShutdownTimeout: metav1.Duration{Duration: DefaultShutdownTimeout},
+		},
+		Namespaces: []string{DefaultNamespace},
+		Auth: AuthConfig{
+			Authorization: AuthorizationAlwaysAllow,
+		},
+		Informer: InformerConfig{
+			Resync: metav1.Duration{Duration: informerOpts.Resync},
+		},
+		Controller: ControllerConfig{
+			Workers:        controllerOpts.Workers,
+			MaxRetries:     controllerOpts.MaxRetries,
+			QueueBaseDelay: metav1.Duration{Duration: controllerOpts.RateLimiter.BaseDelay},
+			QueueMaxDelay:  metav1.Duration{Duration: controllerOpts.RateLimiter.MaxDelay},
+			QueueQPS:       controllerOpts.RateLimiter.QPS,
+			QueueBurst:     controllerOpts.RateLimiter.Burst,
+		},
+		Logging: LoggingConfig{
+			Format: LogFormatText,
+		},
+		Tracing: TracingConfig{
+			SampleRatio: DefaultSampleRatio,
+		},
+		Diff: DiffConfig{
+			IgnorePaths: append([]string(nil), diff.DefaultIgnorePaths...),
+		},
+		Sinks: SinksConfig{
+			Pods:        []SinkConfig{{Type: watcher.SinkLog}},
+			Deployments: []SinkConfig{{Type: watcher.SinkLog}},
+		},
+		History: HistoryConfig{
+			MaxEvents: history.DefaultMaxEvents,
+			MaxAge:    metav1.Duration{Duration: history.DefaultMaxAge},
+		},
+		EventLog: EventLogConfig{
+			MaxAge:          metav1.Duration{Duration: eventlog.DefaultMaxAge},
+			CompactInterval: metav1.Duration{Duration: eventlog.DefaultCompactInterval},
+		},
+		Anomalies: AnomaliesConfig{
+			AnomalyThresholds: AnomalyThresholds{
+				CrashLoopRestarts:    anomaly.DefaultCrashLoopRestarts,
+				RestartStormRestarts: anomaly.DefaultRestartStormRestarts,
+				RestartStormWindow:   metav1.Duration{Duration: anomaly.DefaultRestartStormWindow},
+			},
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults. An empty
+// path returns the defaults. Unknown fields are rejected.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Marshal returns the YAML representation of c.
+func (c *Config) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// WatcherOptions ...
+func (c *Config) WatcherOptions() (watcher.Options, error) {
+	differ, err := diff.New(diff.Options{IgnorePaths: c.Diff.IgnorePaths})
+	if err != nil {
+		return watcher.Options{}, err
+	}
+
+	return watcher.Options{
+		Resync:                  c.Informer.Resync.Duration,
+		ListPageSize:            c.Informer.ListPageSize,
+		TrimObjects:             c.Informer.TrimObjects,
+		MetadataOnlyDeployments: c.Informer.MetadataOnlyDeployments,
+		Differ:                  differ,
+	}, nil
+}
+
+// PodSinks builds the sinks of the pod events. The caller closes them.
+func (c *Config) PodSinks() (watcher.Sinks, error) {
+	return newSinks(c.Sinks.Pods)
+}
+
+// DeploymentSinks builds the sinks of the deployment events. The caller
+// closes them.
+func (c *Config) DeploymentSinks() (watcher.Sinks, error) {
+	return newSinks(c.Sinks.Deployments)
+}
+
+// WebhookSinks builds the webhook sinks that take events of resource, every
+// webhook sink if resource is empty. The caller closes them.
+func (c *Config) WebhookSinks(resource string) (watcher.Sinks, error) {
+	sinks := make(watcher.Sinks, 0, len(c.Webhooks))
+	for _, w := range c.Webhooks {
+		opts, err := w.Options()
+		if resource != "" && !opts.Filter.MatchesResource(resource) {
+			continue
+		}
+		if err == nil {
+			var sink watcher.EventSink
+			if sink, err = watcher.NewWebhookSink(opts); err == nil {
+				sinks = append(sinks, sink)
+				continue
+			}
+		}
+		sinks.Close()
+		return nil, fmt.Errorf("webhook %s: %v", w.URL, err)
+	}
+	return sinks, nil
+}
+
+func newSinks(configs []SinkConfig) (watcher.Sinks, error) {
+	opts := make([]watcher.SinkOptions, 0, len(configs))
+	for _, s := range configs {
+		opts = append(opts, s.Options())
+	}
+	return watcher.NewSinks(opts)
+}
+
+// LimitOptions ...
+func (c *Config) LimitOptions() limit.Options {
+	return limit.Options{
+		MaxStreamsPerClient: c.Server.MaxStreamsPerClient,
+		UnaryQPS:            c.Server.UnaryQPS,
+		UnaryBurst:          c.Server.UnaryBurst,
+	}
+}
+
+// TracingOptions ...
+func (c *Config) TracingOptions() tracing.Options {
+	return tracing.Options{
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		SampleRatio: c.Tracing.SampleRatio,
+		ServiceName: "dwserver",
+	}
+}
+
+// HistoryOptions ...
+func (c *Config) HistoryOptions() history.Options {
+	return history.Options{
+		MaxEvents: c.History.MaxEvents,
+		MaxAge:    c.History.MaxAge.Duration,
+	}
+}
+
+// EventLogOptions ...
+func (c *Config) EventLogOptions() eventlog.Options {
+	return eventlog.Options{
+		Dir:             c.EventLog.Dir,
+		SegmentSize:     int64(c.EventLog.SegmentSizeMB) << 20,
+		MaxSize:         int64(c.EventLog.MaxSizeMB) << 20,
+		MaxAge:          c.EventLog.MaxAge.Duration,
+		CompactInterval: c.EventLog.CompactInterval.Duration,
+	}
+}
+
+// TimelineOptions ...
+func (c *Config) TimelineOptions() timeline.Options {
+	return timeline.Options{MaxAge: c.Timelines.MaxAge.Duration}
+}
+
+// AnomalyOptions ...
+func (c *Config) AnomalyOptions() anomaly.Options {
+	opts := anomaly.Options{
+		Thresholds:  c.Anomalies.thresholds(),
+		Deployments: make(map[string]anomaly.Thresholds, len(c.Anomalies.Deployments)),
+	}
+	for _, d := range c.Anomalies.Deployments {
+		opts.Deployments[d.key()] = d.thresholds()
+	}
+	return opts
+}
+
+// ControllerOptions ...
+func (c *Config) ControllerOptions() (controller.Options, error) {
+	selector, err := labels.Parse(c.Filters.LabelSelector)
+	if err != nil {
+		return controller.Options{}, err
+	}
+
+	return controller.Options{
+		Workers:        c.Controller.Workers,
+		MaxRetries:     c.Controller.MaxRetries,
+		LabelSelector:  selector,
+		MaxSubscribers: c.Server.MaxSubscribers,
+		RateLimiter: controller.RateLimiterOptions{
+			BaseDelay: c.Controller.QueueBaseDelay.Duration,
+			MaxDelay:  c.Controller.QueueMaxDelay.Duration,
+			QPS:       c.Controller.QueueQPS,
+			Burst:     c.Controller.QueueBurst,
+		},
+	}, nil
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeConfig writes data to a config file in a temporary directory.
+func writeConfig(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "dwserver.yaml")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  listenAddress: 127.0.0.1:7000
+  maxSubscribers: 10
+controller:
+  workers: 2
+informer:
+  resync: 1m
+`)
+	t.Setenv("DWSERVER_MAX_SUBSCRIBERS", "20")
+	t.Setenv("DWSERVER_WORKERS", "3")
+
+	fs := pflag.NewFlagSet("dwserver", pflag.ContinueOnError)
+	AddFlags(fs)
+	if err := fs.Parse([]string{"--workers", "4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Resolve(path, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{"file", cfg.Server.ListenAddress, "127.0.0.1:7000"},
+		{"file over defaults", cfg.Informer.Resync.Duration, time.Minute},
+		{"env over file", cfg.Server.MaxSubscribers, 20},
+		{"flags over env", cfg.Controller.Workers, 4},
+		{"defaults", cfg.Controller.MaxRetries, Default().Controller.MaxRetries},
+	} {
+		if test.value != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, test.value)
+		}
+	}
+}
+
+func TestInformerAndQueueFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("dwserver", pflag.ContinueOnError)
+	AddFlags(fs)
+	if err := fs.Parse([]string{
+		"--resync", "0", "--list-page-size", "500", "--workers", "3",
+		"--queue-base-delay", "10ms", "--queue-max-delay", "1m", "--queue-qps", "5", "--queue-burst", "50",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Resolve("", fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	watcherOpts, err := cfg.WatcherOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllerOpts, err := cfg.ControllerOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{"resync", watcherOpts.Resync, time.Duration(0)},
+		{"list page size", watcherOpts.ListPageSize, int64(500)},
+		{"workers", controllerOpts.Workers, 3},
+		{"queue base delay", controllerOpts.RateLimiter.BaseDelay, 10 * time.Millisecond},
+		{"queue max delay", controllerOpts.RateLimiter.MaxDelay, time.Minute},
+		{"queue qps", controllerOpts.RateLimiter.QPS, 5.0},
+		{"queue burst", controllerOpts.RateLimiter.Burst, 50},
+	} {
+		if test.value != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, test.value)
+		}
+	}
+}
+
+func TestOptionalFeaturesAreOptIn(t *testing.T) {
+	if Default().Timelines.Enabled() {
+		t.Error("expected the timelines to be off by default")
+	}
+	if Default().Anomalies.Enabled() {
+		t.Error("expected the anomaly detection to be off by default")
+	}
+
+	fs := pflag.NewFlagSet("dwserver", pflag.ContinueOnError)
+	AddFlags(fs)
+	if err := fs.Parse([]string{"--timeline-max-age", "24h", "--detect-anomalies"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Resolve("", fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Timelines.Enabled() {
+		t.Error("expected --timeline-max-age to turn the timelines on")
+	}
+	if !cfg.Anomalies.Enabled() {
+		t.Error("expected --detect-anomalies to turn the detection on")
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  listenAdress: 127.0.0.1:7000
+`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "listenAdress") {
+		t.Errorf("expected the unknown key to be rejected, got %v", err)
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	env := map[string]string{"DWSERVER_RESYNC": "ten minutes"}
+	err := ApplyEnv(Default(), func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+	if err == nil || !strings.Contains(err.Error(), "DWSERVER_RESYNC") {
+		t.Errorf("expected an invalid DWSERVER_RESYNC, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		configure func(*Config)
+		expected  string
+	}{
+		{"listen address", func(c *Config) { c.Server.ListenAddress = "8088" }, "server.listenAddress"},
+		{"http address", func(c *Config) { c.Server.HTTPAddress = "localhost" }, "server.httpAddress"},
+		{"unary burst", func(c *Config) { c.Server.UnaryQPS, c.Server.UnaryBurst = 10, 0 }, "server.unaryBurst must be at least 1"},
+		{"shutdown timeout", func(c *Config) { c.Server.ShutdownTimeout = metav1.Duration{Duration: -time.Second} }, "server.shutdownTimeout must not be negative"},
+		{"namespace", func(c *Config) { c.Namespaces = []string{"Default"} }, `namespaces: "Default"`},
+		{"cert without key", func(c *Config) { c.TLS.CertFile = "tls.crt" }, "tls.certFile and tls.keyFile must be set together"},
+		{"secret and files", func(c *Config) { c.TLS.Secret, c.TLS.CertFile, c.TLS.KeyFile = "ns/name", "tls.crt", "tls.key" }, "tls.secret cannot be combined"},
+		{"secret key", func(c *Config) { c.TLS.Secret = "name" }, "tls.secret: \"name\" is not of the form namespace/name"},
+		{"client CA without certificate", func(c *Config) { c.TLS.ClientCAFile = "ca.crt" }, "tls.clientCAFile requires a server certificate"},
+		{"missing certificate file", func(c *Config) { c.TLS.CertFile, c.TLS.KeyFile = "missing.crt", "missing.key" }, "tls.certFile: stat missing.crt"},
+		{"client certificates without CA", func(c *Config) { c.Auth.ClientCertificates = true }, "auth.clientCertificates requires tls.clientCAFile"},
+		{"audiences without token review", func(c *Config) { c.Auth.TokenAudiences = []string{"dwserver"} }, "auth.tokenAudiences requires auth.tokenReview"},
+		{"SubjectAccessReview without authentication", func(c *Config) { c.Auth.Authorization = AuthorizationSubjectAccessReview }, "requires an authentication method"},
+		{"authorization mode", func(c *Config) { c.Auth.Authorization = "RBAC" }, `unsupported mode "RBAC"`},
+		{"resync", func(c *Config) { c.Informer.Resync = metav1.Duration{Duration: -time.Minute} }, "informer.resync must not be negative"},
+		{"workers", func(c *Config) { c.Controller.Workers = 0 }, "controller.workers must be at least 1"},
+		{"queue base delay", func(c *Config) { c.Controller.QueueBaseDelay = metav1.Duration{} }, "controller.queueBaseDelay must be positive"},
+		{"queue max delay", func(c *Config) { c.Controller.QueueMaxDelay = metav1.Duration{Duration: time.Nanosecond} }, "controller.queueMaxDelay must not be less than"},
+		{"label selector", func(c *Config) { c.Filters.LabelSelector = "app in (" }, "filters.labelSelector"},
+		{"sample ratio", func(c *Config) { c.Tracing.SampleRatio = 2 }, "tracing.sampleRatio must be between 0 and 1"},
+		{"pod sink", func(c *Config) { c.Sinks.Pods = []SinkConfig{{Type: "syslog"}} }, "sinks.pods[0]"},
+		{"webhook", func(c *Config) { c.Webhooks = []WebhookConfig{{}} }, "webhooks[0]"},
+		{"history max age", func(c *Config) { c.History.MaxAge = metav1.Duration{} }, "history.maxAge must be positive"},
+		{"event log max age", func(c *Config) { c.EventLog.Dir, c.EventLog.MaxAge = "/var/lib/dwserver", metav1.Duration{} }, "eventLog.maxAge must be positive"},
+		{"timelines max age", func(c *Config) { c.Timelines.MaxAge = metav1.Duration{Duration: -time.Hour} }, "timelines.maxAge must not be negative"},
+		{"anomaly type", func(c *Config) { c.Anomalies.Ignore = []string{"Evicted"} }, `anomalies.ignore: unknown anomaly type "Evicted"`},
+		{"anomaly deployment", func(c *Config) { c.Anomalies.Deployments = []DeploymentAnomaliesConfig{{}} }, "anomalies.deployments[0].name must be set"},
+		{"log format", func(c *Config) { c.Logging.Format = "xml" }, `logging.format: unsupported format "xml"`},
+	} {
+		cfg := Default()
+		test.configure(cfg)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// EnvPrefix prefixes the environment variables that override the
+// configuration file.
+const EnvPrefix = "DWSERVER_"
+
+// setting is a configuration value that can be overridden from the
+// environment and from the command line. The environment variable is the
+// flag name in upper case, with dashes replaced by underscores and EnvPrefix
+// prepended.
+type setting struct {
+	flag      string
+	shorthand string
+	usage     string
+	field     field
+}
+
+var settings = []setting{
+	{flag: "listen-address", usage: "gRPC listen address", field: stringField(func(c *Config) *string { return &c.Server.ListenAddress })},
+	{flag: "http-address", usage: "listen address of the metrics, health and debug endpoints, empty to disable them", field: stringField(func(c *Config) *string { return &c.Server.HTTPAddress })},
+	{flag: "profiling", usage: "serve pprof under /debug/pprof/ and the streams under /debug/subscribers on the HTTP address", field: boolField(func(c *Config) *bool { return &c.Server.Profiling })},
+	{flag: "max-subscribers", usage: "maximum number of pod status streams, 0 for no limit", field: intField(func(c *Config) *int { return &c.Server.MaxSubscribers })},
+	{flag: "max-streams-per-client", usage: "maximum number of concurrent streams of each client, 0 for no limit", field: intField(func(c *Config) *int { return &c.Server.MaxStreamsPerClient })},
+	{flag: "unary-qps", usage: "rate of unary calls allowed to each client, 0 for no limit", field: float64Field(func(c *Config) *float64 { return &c.Server.UnaryQPS })},
+	{flag: "unary-burst", usage: "burst of unary calls allowed to each client", field: intField(func(c *Config) *int { return &c.Server.UnaryBurst })},
+	{flag: "shutdown-timeout", usage: "time given to open streams to finish on shutdown", field: durationField(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout.Duration })},
+	{flag: "namespace", shorthand: "n", usage: "comma separated namespaces to watch, empty for all namespaces", field: listField(func(c *Config) *[]string { return &c.Namespaces })},
+	{flag: "tls-cert", usage: "server certificate file", field: stringField(func(c *Config) *string { return &c.TLS.CertFile })},
+	{flag: "tls-key", usage: "server private key file", field: stringField(func(c *Config) *string { return &c.TLS.KeyFile })},
+	{flag: "tls-secret", usage: "namespace/name of a kubernetes.io/tls Secret holding the server certificate", field: stringField(func(c *Config) *string { return &c.TLS.Secret })},
+	{flag: "tls-ca", usage: "CA bundle used to verify client certificates", field: stringField(func(c *Config) *string { return &c.TLS.ClientCAFile })},
+	{flag: "auth-client-certificates", usage: "authenticate clients by their TLS client certificate", field: boolField(func(c *Config) *bool { return &c.Auth.ClientCertificates })},
+	{flag: "auth-token-review", usage: "authenticate bearer tokens with the TokenReview API", field: boolField(func(c *Config) *bool { return &c.Auth.TokenReview })},
+	{flag: "auth-token-audiences", usage: "comma separated audiences of reviewed tokens, empty for the API server audience", field: listField(func(c *Config) *[]string { return &c.Auth.TokenAudiences })},
+	{flag: "auth-static-token-file", usage: "CSV file of static bearer tokens: token,user,uid,\"groups\"", field: stringField(func(c *Config) *string { return &c.Auth.StaticTokenFile })},
+	{flag: "authorization-mode", usage: "AlwaysAllow or SubjectAccessReview", field: stringField(func(c *Config) *string { return &c.Auth.Authorization })},
+	{flag: "resync", usage: "informer resync period, 0 disables resyncs", field: durationField(func(c *Config) *time.Duration { return &c.Informer.Resync.Duration })},
+	{flag: "list-page-size", usage: "objects per page when informers list, 0 for the default", field: int64Field(func(c *Config) *int64 { return &c.Informer.ListPageSize })},
+	{flag: "trim-objects", usage: "strip managedFields, annotations and unused spec fields before caching", field: boolField(func(c *Config) *bool { return &c.Informer.TrimObjects })},
+	{flag: "metadata-only", usage: "cache only the metadata of deployments", field: boolField(func(c *Config) *bool { return &c.Informer.MetadataOnlyDeployments })},
+	{flag: "workers", usage: "number of workers processing pod events", field: intField(func(c *Config) *int { return &c.Controller.Workers })},
+	{flag: "max-retries", usage: "retries of a failed pod event before it is dropped", field: intField(func(c *Config) *int { return &c.Controller.MaxRetries })},
+	{flag: "queue-base-delay", usage: "initial per-item retry delay", field: durationField(func(c *Config) *time.Duration { return &c.Controller.QueueBaseDelay.Duration })},
+	{flag: "queue-max-delay", usage: "maximum per-item retry delay", field: durationField(func(c *Config) *time.Duration { return &c.Controller.QueueMaxDelay.Duration })},
+	{flag: "queue-qps", usage: "overall rate of queue additions", field: float64Field(func(c *Config) *float64 { return &c.Controller.QueueQPS })},
+	{flag: "queue-burst", usage: "burst of queue additions", field: intField(func(c *Config) *int { return &c.Controller.QueueBurst })},
+	{flag: "label-selector", usage: "only report pods matching this label selector", field: stringField(func(c *Config) *string { return &c.Filters.LabelSelector })},
+	{flag: "log-level", usage: "log verbosity", field: intField(func(c *Config) *int { return &c.Logging.Level })},
+	{flag: "log-format", usage: "log format, text or json", field: stringField(func(c *Config) *string { return &c.Logging.Format })},
+	{flag: "tracing-endpoint", usage: "host:port of the OTLP gRPC receiver traces are exported to, empty to disable tracing", field: stringField(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{flag: "tracing-insecure", usage: "export traces without TLS", field: boolField(func(c *Config) *bool { return &c.Tracing.Insecure })},
+	{flag: "tracing-sample-ratio", usage: "fraction of pod events traced", field: float64Field(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{flag: "pod-sinks", usage: "comma separated sinks of pod events: log, stdout[=cloudevents], file=PATH, webhook=URL, nats=URL/SUBJECT or kafka=BROKER/TOPIC", field: sinkListField(func(c *Config) *[]SinkConfig { return &c.Sinks.Pods })},
+	{flag: "deployment-sinks", usage: "comma separated sinks of deployment events: log, stdout[=cloudevents], file=PATH, webhook=URL, nats=URL/SUBJECT or kafka=BROKER/TOPIC", field: sinkListField(func(c *Config) *[]SinkConfig { return &c.Sinks.Deployments })},
+	{flag: "history-max-events", usage: "events kept per pod and per deployment for QueryEvents, 0 disables the history", field: intField(func(c *Config) *int { return &c.History.MaxEvents })},
+	{flag: "history-max-age", usage: "how long events are kept for QueryEvents", field: durationField(func(c *Config) *time.Duration { return &c.History.MaxAge.Duration })},
+	{flag: "event-log-dir", usage: "directory of the on-disk event log served by ReplayEvents, empty to disable it", field: stringField(func(c *Config) *string { return &c.EventLog.Dir })},
+	{flag: "event-log-max-age", usage: "how long the event log keeps events", field: durationField(func(c *Config) *time.Duration { return &c.EventLog.MaxAge.Duration })},
+	{flag: "event-log-max-size-mb", usage: "size limit of the event log in MB, 0 for no limit", field: intField(func(c *Config) *int { return &c.EventLog.MaxSizeMB })},
+	{flag: "timeline-max-age", usage: "how long the lifecycle timelines of deleted pods are kept for GetPodTimeline, such as 24h; 0, the default, disables the timelines", field: durationField(func(c *Config) *time.Duration { return &c.Timelines.MaxAge.Duration })},
+	{flag: "detect-anomalies", usage: "detect crash loops, OOMKills, image pull failures and restart storms of containers", field: boolField(func(c *Config) *bool { return &c.Anomalies.Detect })},
+	{flag: "anomaly-crash-loop-restarts", usage: "restart count from which a container in CrashLoopBackOff is reported", field: intField(func(c *Config) *int { return &c.Anomalies.CrashLoopRestarts })},
+	{flag: "anomaly-restart-storm-restarts", usage: "restarts of a container within the restart storm window reported as a restart storm", field: intField(func(c *Config) *int { return &c.Anomalies.RestartStormRestarts })},
+	{flag: "anomaly-restart-storm-window", usage: "window the restarts of a restart storm are counted in", field: durationField(func(c *Config) *time.Duration { return &c.Anomalies.RestartStormWindow.Duration })},
+	{flag: "anomaly-ignore", usage: "comma separated anomaly types not reported: CrashLoopBackOff, OOMKilled, ImagePullFailure or RestartStorm", field: listField(func(c *Config) *[]string { return &c.Anomalies.Ignore })},
+	{flag: "diff-ignore-paths", usage: "comma separated field paths left out of the changes of updates, * matches any key or index", field: listField(func(c *Config) *[]string { return &c.Diff.IgnorePaths })},
+}
+
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(s.flag, "-", "_", -1))
+}
+
+// AddFlags registers a flag for every setting, defaulting to the built-in
+// defaults.
+func AddFlags(fs *pflag.FlagSet) {
+	defaults := Default()
+	for _, s := range settings {
+		s.field.addFlag(fs, s.flag, s.shorthand, fmt.Sprintf("%s (env %s)", s.usage, s.env()), defaults)
+	}
+}
+
+// Resolve loads the configuration file at path and applies the environment
+// and the flags of fs that were set on the command line on top of it.
+func Resolve(path string, fs *pflag.FlagSet) (*Config, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ApplyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := ApplyFlags(cfg, fs); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ApplyEnv overrides c with the environment variables that are set.
+func ApplyEnv(c *Config, lookupEnv func(string) (string, bool)) error {
+	for _, s := range settings {
+		value, ok := lookupEnv(s.env())
+		if !ok {
+			continue
+		}
+		if err := s.field.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s=%q: %v", s.env(), value, err)
+		}
+	}
+	return nil
+}
+
+// ApplyFlags overrides c with the flags of fs that were set on the command
+// line.
+func ApplyFlags(c *Config, fs *pflag.FlagSet) error {
+	for _, s := range settings {
+		flag := fs.Lookup(s.flag)
+		if flag == nil || !flag.Changed {
+			continue
+		}
+		if err := s.field.set(c, flag.Value.String()); err != nil {
+			return fmt.Errorf("invalid --%s=%q: %v", s.flag, flag.Value.String(), err)
+		}
+	}
+	return nil
+}
+
+// field is a typed configuration field.
+type field interface {
+	set(c *Config, value string) error
+	addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config)
+}
+
+type stringField func(*Config) *string
+
+func (f stringField) set(c *Config, value string) error {
+	*f(c) = value
+	return nil
+}
+
+func (f stringField) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.StringP(name, shorthand, *f(defaults), usage)
+}
+
+// listField is a comma separated list of strings.
+type listField func(*Config) *[]string
+
+func (f listField) set(c *Config, value string) error {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*f(c) = list
+	return nil
+}
+
+func (f listField) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.StringP(name, shorthand, strings.Join(*f(defaults), ","), usage)
+}
+
+// sinkListField is a comma separated list of sinks in the short form of
+// watcher.ParseSink. Other settings of the sinks keep their defaults.
+type sinkListField func(*Config) *[]SinkConfig
+
+func (f sinkListField) set(c *Config, value string) error {
+	var sinks []SinkConfig
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		sink, err := ParseSink(item)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	*f(c) = sinks
+	return nil
+}
+
+func (f sinkListField) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	var items []string
+	for _, sink := range *f(defaults) {
+		items = append(items, sink.Options().String())
+	}
+	fs.StringP(name, shorthand, strings.Join(items, ","), usage)
+}
+
+type boolField func(*Config) *bool
+
+func (f boolField) set(c *Config, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*f(c) = b
+	return nil
+}
+
+func (f boolField) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.BoolP(name, shorthand, *f(defaults), usage)
+}
+
+type intField func(*Config) *int
+
+func (f intField) set(c *Config, value string) error {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*f(c) = i
+	return nil
+}
+
+func (f intField) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.IntP(name, shorthand, *f(defaults), usage)
+}
+
+type int64Field func(*Config) *int64
+
+func (f int64Field) set(c *Config, value string) error {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	*f(c) = i
+	return nil
+}
+
+func (f int64Field) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.Int64P(name, shorthand, *f(defaults), usage)
+}
+
+type float64Field func(*Config) *float64
+
+func (f float64Field) set(c *Config, value string) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*f(c) = v
+	return nil
+}
+
+func (f float64Field) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.Float64P(name, shorthand, *f(defaults), usage)
+}
+
+type durationField func(*Config) *time.Duration
+
+func (f durationField) set(c *Config, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*f(c) = d
+	return nil
+}
+
+func (f durationField) addFlag(fs *pflag.FlagSet, name, shorthand, usage string, defaults *Config) {
+	fs.DurationP(name, shorthand, *f(defaults), usage)
+}
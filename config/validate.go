@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/diff"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validate checks c for errors and returns all of them at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Server.ListenAddress); err != nil {
+		errs = append(errs, fmt.Errorf("server.listenAddress: %v", err))
+	}
+	if c.Server.HTTPAddress != "" {
+		if _, _, err := net.SplitHostPort(c.Server.HTTPAddress); err != nil {
+			errs = append(errs, fmt.Errorf("server.httpAddress: %v", err))
+		}
+	}
+	if c.Server.MaxSubscribers < 0 {
+		errs = append(errs, fmt.Errorf("server.maxSubscribers must not be negative"))
+	}
+	if c.Server.MaxStreamsPerClient < 0 {
+		errs = append(errs, fmt.Errorf("server.maxStreamsPerClient must not be negative"))
+	}
+	if c.Server.UnaryQPS < 0 {
+		errs = append(errs, fmt.Errorf("server.unaryQPS must not be negative"))
+	}
+	if c.Server.UnaryQPS > 0 && c.Server.UnaryBurst < 1 {
+		errs = append(errs, fmt.Errorf("server.unaryBurst must be at least 1"))
+	}
+	if c.Server.ShutdownTimeout.Duration < 0 {
+		errs = append(errs, fmt.Errorf("server.shutdownTimeout must not be negative"))
+	}
+
+	for _, ns := range c.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, fmt.Errorf("namespaces: %q: %s", ns, msg))
+		}
+	}
+
+	errs = append(errs, c.TLS.validate()...)
+	errs = append(errs, c.Auth.validate(c.TLS)...)
+
+	if c.Informer.Resync.Duration < 0 {
+		errs = append(errs, fmt.Errorf("informer.resync must not be negative"))
+	}
+	if c.Informer.ListPageSize < 0 {
+		errs = append(errs, fmt.Errorf("informer.listPageSize must not be negative"))
+	}
+
+	if c.Controller.Workers < 1 {
+		errs = append(errs, fmt.Errorf("controller.workers must be at least 1"))
+	}
+	if c.Controller.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("controller.maxRetries must not be negative"))
+	}
+	if c.Controller.QueueBaseDelay.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("controller.queueBaseDelay must be positive"))
+	}
+	if c.Controller.QueueMaxDelay.Duration < c.Controller.QueueBaseDelay.Duration {
+		errs = append(errs, fmt.Errorf("controller.queueMaxDelay must not be less than controller.queueBaseDelay"))
+	}
+	if c.Controller.QueueQPS <= 0 {
+		errs = append(errs, fmt.Errorf("controller.queueQPS must be positive"))
+	}
+	if c.Controller.QueueBurst < 1 {
+		errs = append(errs, fmt.Errorf("controller.queueBurst must be at least 1"))
+	}
+
+	if _, err := labels.Parse(c.Filters.LabelSelector); err != nil {
+		errs = append(errs, fmt.Errorf("filters.labelSelector: %v", err))
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sampleRatio must be between 0 and 1"))
+	}
+
+	if _, err := diff.New(diff.Options{IgnorePaths: c.Diff.IgnorePaths}); err != nil {
+		errs = append(errs, fmt.Errorf("diff.ignorePaths: %v", err))
+	}
+
+	for i, s := range c.Sinks.Pods {
+		if err := s.Options().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.pods[%d]: %v", i, err))
+		}
+	}
+	for i, s := range c.Sinks.Deployments {
+		if err := s.Options().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.deployments[%d]: %v", i, err))
+		}
+	}
+
+	for i, w := range c.Webhooks {
+		opts, err := w.Options()
+		if err == nil {
+			err = opts.Validate()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: %v", i, err))
+		}
+	}
+
+	if c.History.MaxEvents < 0 {
+		errs = append(errs, fmt.Errorf("history.maxEvents must not be negative"))
+	}
+	if c.History.Enabled() && c.History.MaxAge.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("history.maxAge must be positive"))
+	}
+
+	if c.EventLog.SegmentSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("eventLog.segmentSizeMB must not be negative"))
+	}
+	if c.EventLog.MaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("eventLog.maxSizeMB must not be negative"))
+	}
+	if c.EventLog.Enabled() && c.EventLog.MaxAge.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("eventLog.maxAge must be positive"))
+	}
+	if c.EventLog.Enabled() && c.EventLog.CompactInterval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("eventLog.compactInterval must be positive"))
+	}
+
+	if c.Timelines.MaxAge.Duration < 0 {
+		errs = append(errs, fmt.Errorf("timelines.maxAge must not be negative"))
+	}
+
+	errs = append(errs, c.Anomalies.validate()...)
+
+	if c.Logging.Level < 0 {
+		errs = append(errs, fmt.Errorf("logging.level must not be negative"))
+	}
+	if c.Logging.Format != LogFormatText && c.Logging.Format != LogFormatJSON {
+		errs = append(errs, fmt.Errorf("logging.format: unsupported format %q", c.Logging.Format))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (a AnomaliesConfig) validate() []error {
+	errs := a.AnomalyThresholds.validate("anomalies")
+
+	seen := map[string]bool{}
+	for i, d := range a.Deployments {
+		field := fmt.Sprintf("anomalies.deployments[%d]", i)
+		if d.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name must be set", field))
+		} else if seen[d.key()] {
+			errs = append(errs, fmt.Errorf("%s: duplicate deployment %s", field, d.key()))
+		}
+		seen[d.key()] = true
+		errs = append(errs, d.AnomalyThresholds.validate(field)...)
+	}
+	return errs
+}
+
+func (t AnomalyThresholds) validate(field string) []error {
+	var errs []error
+
+	if t.CrashLoopRestarts < 0 {
+		errs = append(errs, fmt.Errorf("%s.crashLoopRestarts must not be negative", field))
+	}
+	if t.RestartStormRestarts < 0 {
+		errs = append(errs, fmt.Errorf("%s.restartStormRestarts must not be negative", field))
+	}
+	if t.RestartStormWindow.Duration < 0 {
+		errs = append(errs, fmt.Errorf("%s.restartStormWindow must not be negative", field))
+	}
+	for _, ignored := range t.Ignore {
+		known := false
+		for _, anomalyType := range anomaly.Types {
+			known = known || strings.EqualFold(ignored, anomalyType)
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("%s.ignore: unknown anomaly type %q", field, ignored))
+		}
+	}
+	return errs
+}
+
+func (t TLSConfig) validate() []error {
+	var errs []error
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("tls.certFile and tls.keyFile must be set together"))
+	}
+	if t.Secret != "" {
+		if t.CertFile != "" || t.KeyFile != "" {
+			errs = append(errs, fmt.Errorf("tls.secret cannot be combined with tls.certFile and tls.keyFile"))
+		}
+		if _, _, err := t.SecretKey(); err != nil {
+			errs = append(errs, fmt.Errorf("tls.secret: %v", err))
+		}
+	}
+	if t.ClientCAFile != "" && !t.Enabled() {
+		errs = append(errs, fmt.Errorf("tls.clientCAFile requires a server certificate"))
+	}
+
+	for name, path := range map[string]string{
+		"tls.certFile":     t.CertFile,
+		"tls.keyFile":      t.KeyFile,
+		"tls.clientCAFile": t.ClientCAFile,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+	}
+
+	return errs
+}
+
+func (a AuthConfig) validate(t TLSConfig) []error {
+	var errs []error
+
+	if a.ClientCertificates && t.ClientCAFile == "" {
+		errs = append(errs, fmt.Errorf("auth.clientCertificates requires tls.clientCAFile"))
+	}
+	if len(a.TokenAudiences) > 0 && !a.TokenReview {
+		errs = append(errs, fmt.Errorf("auth.tokenAudiences requires auth.tokenReview"))
+	}
+	if a.StaticTokenFile != "" {
+		if _, err := os.Stat(a.StaticTokenFile); err != nil {
+			errs = append(errs, fmt.Errorf("auth.staticTokenFile: %v", err))
+		}
+	}
+
+	switch a.Authorization {
+	case AuthorizationAlwaysAllow:
+	case AuthorizationSubjectAccessReview:
+		if !a.AuthenticationEnabled() {
+			errs = append(errs, fmt.Errorf("auth.authorization %s requires an authentication method", a.Authorization))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth.authorization: unsupported mode %q", a.Authorization))
+	}
+
+	return errs
+}
@@ -1,13 +1,73 @@
 package controller
 
 import (
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
 
 type controller struct {
-	client   kubernetes.Interface
-	informer cache.SharedIndexInformer
-	queue    workqueue.RateLimitingInterface
+	client kubernetes.Interface
+	queue  workqueue.RateLimitingInterface
+}
+
+// Options ...
+type Options struct {
+	// Workers is the number of goroutines processing the queue.
+	Workers int
+
+	// MaxRetries is the number of times an event is retried before it is
+	// dropped from the queue.
+	MaxRetries int
+
+	// LabelSelector selects the pods reported to clients.
+	LabelSelector labels.Selector
+
+	// MaxSubscribers limits the number of open channels. Zero means no
+	// limit.
+	MaxSubscribers int
+
+	// Sinks receive the pod events too, before they are queued for the
+	// subscribers. The controller does not close them.
+	Sinks []watcher.EventSink
+
+	RateLimiter RateLimiterOptions
+}
+
+// RateLimiterOptions configures the queue rate limiter: the maximum of a
+// per-item exponential backoff and an overall token bucket.
+type RateLimiterOptions struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	QPS       float64
+	Burst     int
+}
+
+// DefaultOptions returns the options matching
+// workqueue.DefaultControllerRateLimiter with a single worker.
+func DefaultOptions() Options {
+	return Options{
+		Workers:       1,
+		MaxRetries:    5,
+		LabelSelector: labels.Everything(),
+		Sinks:         []watcher.EventSink{watcher.LogSink()},
+		RateLimiter: RateLimiterOptions{
+			BaseDelay: 5 * time.Millisecond,
+			MaxDelay:  1000 * time.Second,
+			QPS:       10,
+			Burst:     100,
+		},
+	}
+}
+
+// NewRateLimiter ...
+func (o RateLimiterOptions) NewRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(o.BaseDelay, o.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(o.QPS), o.Burst)},
+	)
 }
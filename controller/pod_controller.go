@@ -1,66 +1,147 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/bobbybho/k8s-deployment-watcher/cloudevents"
 	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/diff"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"github.com/bobbybho/k8s-deployment-watcher/tracing"
 	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 
 	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
 )
 
+// subscriberBuffer is the number of messages buffered for a subscriber.
+// Messages to a subscriber whose buffer is full are dropped.
+const subscriberBuffer = 100
+
+// ErrTooManySubscribers is returned by OpenChannel when MaxSubscribers
+// channels are open already.
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// ErrDuplicateSubscription is returned by OpenChannel when a channel is
+// open already for the client ID.
+var ErrDuplicateSubscription = errors.New("subscription ID already in use")
+
+// ErrShuttingDown is returned by OpenChannel once Shutdown has been called.
+var ErrShuttingDown = errors.New("server shutting down")
+
 // PodController ...
 type PodController struct {
 	controller
-	PQ   map[string]chan pb.PodStatReply
-	lock sync.RWMutex
+	manager *watcher.Manager
+	events  *eventQueue
+	PQ      map[string]chan Update
+	lock    sync.RWMutex
+
+	// subscriptions describes the channels of PQ, guarded by lock.
+	subscriptions map[string]*subscription
+
+	// shuttingDown is set by Shutdown and guarded by lock.
+	shuttingDown bool
+
+	// configLock guards the settings that can be changed while running.
+	configLock sync.RWMutex
+	opts       Options
+	informers  map[string]cache.SharedIndexInformer
+	stopper    <-chan struct{}
 }
 
-func NewPodController(clientset kubernetes.Interface, namespace string) *PodController {
-	pc := &PodController{}
+// NewPodController watches the pods of namespaces. An empty list watches all
+// namespaces.
+func NewPodController(manager *watcher.Manager, clientset kubernetes.Interface, namespaces []string, opts Options) *PodController {
+	pc := &PodController{manager: manager, opts: opts}
 
-	q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), common.PodQueue)
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	limiter := opts.RateLimiter.NewRateLimiter()
+	pc.queue = workqueue.NewNamedRateLimitingQueue(limiter, common.PodQueue)
+	pc.events = newEventQueue(pc.queue, limiter)
 
-	pw := watcher.NewPodWatcher(clientset, namespace, q)
-	pc.informer = pw.GetShareIndexInformer()
-	pc.queue = q
+	pc.informers = make(map[string]cache.SharedIndexInformer, len(namespaces))
+	for _, namespace := range namespaces {
+		pw := watcher.NewPodWatcher(manager, namespace, pc.sinks()...)
+		pc.informers[namespace] = pw.GetShareIndexInformer()
+	}
 
 	pc.client = clientset
 
-	pc.PQ = make(map[string]chan pb.PodStatReply)
+	pc.PQ = make(map[string]chan Update)
+	pc.subscriptions = make(map[string]*subscription)
 
 	return pc
 }
 
+// sinks returns the sinks of the pod watchers: the configured ones and the
+// queue of the controller, which broadcasts to the subscribers.
+func (pc *PodController) sinks() []watcher.EventSink {
+	return append(append([]watcher.EventSink(nil), pc.opts.Sinks...), pc.events)
+}
+
+// Run starts the controller and blocks until stopper is closed.
 func (pc *PodController) Run(stopper <-chan struct{}) {
 	defer utilruntime.HandleCrash()
-	defer pc.queue.ShutDown()
 
-	klog.Infof("Starting PodController...")
+	if err := pc.Start(stopper); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	<-stopper
+}
+
+// Start starts the informers, waits for their caches to sync and starts the
+// workers. The informers, the workers and the queue stop when stopper is
+// closed.
+func (pc *PodController) Start(stopper <-chan struct{}) error {
+	klog.Info("Starting PodController")
+
+	go func() {
+		<-stopper
+		pc.queue.ShutDown()
+	}()
+
+	pc.configLock.Lock()
+	pc.stopper = stopper
+	pc.configLock.Unlock()
 
-	go pc.informer.Run(stopper)
+	// the informer is shared with every other watcher registered with the
+	// manager, so start it through the manager instead of running it here.
+	pc.manager.Start(stopper)
 
-	klog.Info("Synchronizing events...")
+	klog.Info("Waiting for informer caches to sync")
 
 	//synchronize the cache before starting to process events
-	if !cache.WaitForCacheSync(stopper, pc.informer.HasSynced) {
-		utilruntime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
-		klog.Info("synchronization failed...")
-		return
+	if err := pc.manager.WaitForCacheSync(stopper); err != nil {
+		return fmt.Errorf("Timed out waiting for caches to sync: %v", err)
 	}
 
-	klog.Info("Synchronizing completed")
+	klog.InfoS("Informer caches synced, starting workers", "workers", pc.opts.Workers)
+	for i := 0; i < pc.opts.Workers; i++ {
+		go wait.Until(pc.runWorker, time.Second, stopper)
+	}
 
-	wait.Until(pc.runWorker, time.Second, stopper)
+	return nil
 }
 
 func (pc *PodController) runWorker() {
@@ -70,64 +151,401 @@ func (pc *PodController) runWorker() {
 }
 
 func (pc *PodController) processNextItem() bool {
-	item, stop := pc.queue.Get()
-
-	if stop {
+	item, quit := pc.queue.Get()
+	if quit {
 		return false
 	}
 
-	err := pc.processItem(item.(common.Event))
-	if err == nil {
-		pc.queue.Forget(item)
+	// tell the queue we are done with this key so it can be handed to
+	// another worker if it was added again in the meantime. Until then, no
+	// other worker processes the events of the pod.
+	defer pc.queue.Done(item)
+
+	// the key is queued again when its backoff has passed, its retries are
+	// only forgotten once its events are processed
+	key := item.(string)
+	events := pc.events.take(key)
+	if len(events) == 0 {
 		return true
 	}
+	for i, e := range events {
+		ctx, span := tracing.Tracer().Start(tracing.EventContext(e), tracing.SpanProcess,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(tracing.KeyRetries.Int(pc.queue.NumRequeues(key))))
+		err := pc.processItem(ctx, e)
+		tracing.EndSpan(span, err)
+
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		metrics.EventsProcessed.WithLabelValues(e.ResourceType, e.EventType, result).Inc()
+
+		if err != nil {
+			pc.handleErr(err, key, events[i:])
+			return true
+		}
+		tracing.EndEvent(e, nil)
+	}
+	pc.queue.Forget(key)
 
 	return true
 }
 
-func (pc *PodController) processItem(e common.Event) error {
-	obj, _, err := pc.informer.GetIndexer().GetByKey(e.Key)
+// handleErr retries the failed event, the first of events, with backoff
+// until it has been retried MaxRetries times, then drops it. The events of
+// the pod queued after it wait for it, they do not cut the backoff short.
+func (pc *PodController) handleErr(err error, key string, events []common.Event) {
+	e := events[0]
+	if pc.queue.NumRequeues(key) < pc.opts.MaxRetries {
+		klog.ErrorS(err, "Failed to process event, retrying", e.LogValues()...)
+		pc.events.retry(key, events)
+		return
+	}
+
+	pc.queue.Forget(key)
+	tracing.EndEvent(e, err)
+	utilruntime.HandleError(fmt.Errorf("dropping %s event of %s out of the queue after %d retries: %v", e.EventType, key, pc.opts.MaxRetries, err))
+	if len(events) > 1 {
+		pc.events.putBack(key, events[1:])
+		pc.queue.Add(key)
+	}
+}
+
+func (pc *PodController) processItem(ctx context.Context, e common.Event) error {
+	pod, err := pc.podForEvent(e)
 	if err != nil {
-		return fmt.Errorf("failted to fetch object with key %s from store: %v", e.Key, err)
+		return err
 	}
 
-	pod := obj.(*v1.Pod)
+	if pod == nil {
+		klog.V(2).InfoS("Pod no longer exists, skipping event", e.LogValues()...)
+		return nil
+	}
+
+	pc.configLock.RLock()
+	selector := pc.opts.LabelSelector
+	pc.configLock.RUnlock()
+
+	if !selector.Matches(labels.Set(pod.Labels)) {
+		klog.V(4).InfoS("Pod does not match the label selector, skipping event", append(e.LogValues(), "selector", selector.String())...)
+		return nil
+	}
 
-	klog.Infof("processed item %v for pod %v labels: %v", pod.Name, pod.Labels)
+	klog.V(2).InfoS("Processing event", append(e.LogValues(), "labels", pod.Labels)...)
 
-	podStatReply := pb.PodStatReply{}
+	podStatReply := &pb.PodStatReply{}
 	podStatReply.Message = e.EventType
-	podStatReply.Podstat = &pb.PodStat{
+	podStatReply.Podstat = PodStat(pod)
+
+	pc.lock.RLock()
+	defer pc.lock.RUnlock()
+
+	// the changes and the CloudEvent are only built once some subscriber
+	// asks for them
+	replies := map[replyKind]*pb.PodStatReply{{}: podStatReply}
+
+	span := trace.SpanFromContext(ctx)
+	for clientID, podStatusChan := range pc.PQ {
+		subscription := pc.subscriptions[clientID]
+		if !subscription.matches(pod.Namespace) {
+			continue
+		}
+
+		kind := replyKind{changes: subscription.opts.Changes, cloudEvent: subscription.opts.CloudEvents}
+		reply := pc.reply(replies, kind, e, pod)
+
+		update := Update{Reply: reply, SpanContext: span.SpanContext(), Queued: time.Now()}
+
+		// a slow subscriber must not hold up the others
+		select {
+		case podStatusChan <- update:
+			subscription.queued()
+			klog.V(4).InfoS("Queued event for subscriber", append(e.LogValues(), "subscriber", clientID)...)
+		default:
+			subscription.drop()
+			metrics.SubscriberDroppedMessages.Inc()
+			span.AddEvent("dropped", trace.WithAttributes(tracing.KeySubscriber.String(clientID)))
+			klog.V(2).InfoS("Subscriber is not keeping up, dropped event", append(e.LogValues(), "subscriber", clientID)...)
+		}
+	}
+
+	return nil
+}
+
+// replyKind selects the optional fields of a reply.
+type replyKind struct {
+	changes    bool
+	cloudEvent bool
+}
+
+// reply returns the reply of kind to e, building it from the replies of
+// the other kinds and adding it to replies.
+func (pc *PodController) reply(replies map[replyKind]*pb.PodStatReply, kind replyKind, e common.Event, pod *v1.Pod) *pb.PodStatReply {
+	if reply, ok := replies[kind]; ok {
+		return reply
+	}
+
+	var reply *pb.PodStatReply
+	if kind.cloudEvent {
+		reply = pc.withCloudEvent(pc.reply(replies, replyKind{changes: kind.changes}, e, pod), e, pod)
+	} else {
+		reply = pc.withChanges(replies[replyKind{}], e)
+	}
+	replies[kind] = reply
+	return reply
+}
+
+// withCloudEvent returns a copy of reply with e as a CloudEvent of the
+// reported state of the pod.
+func (pc *PodController) withCloudEvent(reply *pb.PodStatReply, e common.Event, pod *v1.Pod) *pb.PodStatReply {
+	e.Object = pod
+	event, err := cloudevents.Structured(e)
+	if err != nil {
+		klog.ErrorS(err, "Failed to encode CloudEvent", e.LogValues()...)
+		return reply
+	}
+	return &pb.PodStatReply{Message: reply.Message, Podstat: reply.Podstat, Changes: reply.Changes, Cloudevent: event}
+}
+
+// withChanges returns a copy of reply with the changes of the modified event
+// e, from the previous state of the pod to the state e carries. The reported
+// state may be newer, its changes are reported by the events that follow.
+// Other events have no changes.
+func (pc *PodController) withChanges(reply *pb.PodStatReply, e common.Event) *pb.PodStatReply {
+	if e.Old == nil {
+		return reply
+	}
+
+	changes, err := pc.manager.Differ().Diff(e.Old, e.Object)
+	if err != nil {
+		klog.ErrorS(err, "Failed to diff update", e.LogValues()...)
+		return reply
+	}
+
+	withChanges := &pb.PodStatReply{Message: reply.Message, Podstat: reply.Podstat}
+	for _, change := range changes {
+		fieldChange := &pb.FieldChange{Op: string(change.Op), Path: change.Path.String()}
+		if change.Op != diff.OpAdd {
+			fieldChange.Old = diff.Value(change.Old)
+		}
+		if change.Op != diff.OpRemove {
+			fieldChange.New = diff.Value(change.New)
+		}
+		withChanges.Changes = append(withChanges.Changes, fieldChange)
+	}
+	return withChanges
+}
+
+// podForEvent returns the pod to report for e. Deleted pods are gone from the
+// store, so they are reported with the state carried by the event. Other
+// events report the latest cached state, falling back to the carried state
+// if the pod has been deleted since.
+func (pc *PodController) podForEvent(e common.Event) (*v1.Pod, error) {
+	carried, _ := e.Object.(*v1.Pod)
+	if e.EventType == common.EventDeleted {
+		return carried, nil
+	}
+
+	namespace, _, err := cache.SplitMetaNamespaceKey(e.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	// the namespace is no longer watched
+	informer, ok := pc.informerFor(namespace)
+	if !ok {
+		return carried, nil
+	}
+
+	obj, exists, err := informer.GetIndexer().GetByKey(e.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch object with key %s from store: %v", e.Key, err)
+	}
+
+	if !exists {
+		return carried, nil
+	}
+
+	return obj.(*v1.Pod), nil
+}
+
+// PodStat returns the state of pod replied to the gRPC clients.
+func PodStat(pod *v1.Pod) *pb.PodStat {
+	nodeName := pod.Spec.NodeName
+	if nodeName == "" {
+		nodeName = pod.Status.NominatedNodeName
+	}
+
+	return &pb.PodStat{
 		Podstate: string(pod.Status.Phase),
 		Podip:    pod.Status.PodIP,
-		Nodename: pod.Status.NominatedNodeName,
+		Nodename: nodeName,
 		Podname:  pod.Name,
 		Hostip:   pod.Status.HostIP,
+		Reason:   podReason(pod),
+		Restarts: podRestarts(pod),
 	}
+}
 
-	pc.lock.RLock()
-	defer pc.lock.RUnlock()
+// podReason returns why pod is not running as expected, the way kubectl
+// reports it: the reason of the pod, or of the first waiting or terminated
+// container, init containers first.
+func podReason(pod *v1.Pod) string {
+	if pod.Status.Reason != "" {
+		return pod.Status.Reason
+	}
+	for _, s := range pod.Status.InitContainerStatuses {
+		if reason := containerReason(s); reason != "" && reason != "Completed" {
+			return "Init:" + reason
+		}
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if reason := containerReason(s); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func containerReason(s v1.ContainerStatus) string {
+	switch {
+	case s.State.Waiting != nil:
+		return s.State.Waiting.Reason
+	case s.State.Terminated != nil:
+		return s.State.Terminated.Reason
+	}
+	return ""
+}
 
-	for _, podStatusChan := range pc.PQ {
-		podStatusChan <- podStatReply
+// podRestarts returns the sum of the restart counts of the containers of
+// pod.
+func podRestarts(pod *v1.Pod) int32 {
+	var restarts int32
+	for _, s := range pod.Status.ContainerStatuses {
+		restarts += s.RestartCount
 	}
+	return restarts
+}
 
-	return nil
+// informerFor returns the pod informer watching namespace, if any.
+func (pc *PodController) informerFor(namespace string) (cache.SharedIndexInformer, bool) {
+	pc.configLock.RLock()
+	defer pc.configLock.RUnlock()
+
+	informer, ok := pc.informers[namespace]
+	if !ok {
+		informer, ok = pc.informers[metav1.NamespaceAll]
+	}
+	return informer, ok
+}
+
+// HasPod reports whether the pod namespace/name with uid is in the cache of
+// the watched namespaces.
+func (pc *PodController) HasPod(namespace, name string, uid types.UID) bool {
+	informer, ok := pc.informerFor(namespace)
+	if !ok {
+		return false
+	}
+
+	obj, exists, err := informer.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil || !exists {
+		return false
+	}
+	pod, ok := obj.(*v1.Pod)
+	return ok && pod.UID == uid
+}
+
+// SetNamespaces changes the watched namespaces. The informers of namespaces
+// that are no longer watched are stopped, new ones are started and synced
+// if the controller is running already.
+func (pc *PodController) SetNamespaces(namespaces []string) error {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	wanted := sets.NewString(namespaces...)
+
+	pc.configLock.Lock()
+	for namespace := range pc.informers {
+		if !wanted.Has(namespace) {
+			pc.manager.StopNamespace(namespace)
+			delete(pc.informers, namespace)
+			klog.InfoS("Stopped watching pods", "namespace", namespace)
+		}
+	}
+
+	added := 0
+	for _, namespace := range wanted.List() {
+		if _, ok := pc.informers[namespace]; !ok {
+			pw := watcher.NewPodWatcher(pc.manager, namespace, pc.sinks()...)
+			pc.informers[namespace] = pw.GetShareIndexInformer()
+			added++
+			klog.InfoS("Started watching pods", "namespace", namespace)
+		}
+	}
+	stopper := pc.stopper
+	pc.configLock.Unlock()
+
+	if stopper == nil || added == 0 {
+		return nil
+	}
+
+	pc.manager.Start(stopper)
+	return pc.manager.WaitForCacheSync(stopper)
+}
+
+// SetLabelSelector changes the selector of the pods reported to clients.
+func (pc *PodController) SetLabelSelector(selector labels.Selector) {
+	pc.configLock.Lock()
+	defer pc.configLock.Unlock()
+
+	pc.opts.LabelSelector = selector
+}
+
+// SetMaxSubscribers changes the limit of open channels. Channels open
+// already are kept even if they exceed the new limit.
+func (pc *PodController) SetMaxSubscribers(max int) {
+	pc.configLock.Lock()
+	defer pc.configLock.Unlock()
+
+	pc.opts.MaxSubscribers = max
 }
 
 // OpenChannel ...
-func (pc *PodController) OpenChannel(clientID string) chan pb.PodStatReply {
+func (pc *PodController) OpenChannel(clientID string) (chan Update, error) {
+	return pc.Subscribe(clientID, SubscribeOptions{Namespace: metav1.NamespaceAll})
+}
+
+// Subscribe opens a channel described by opts. Client IDs must be unique
+// among the open channels.
+func (pc *PodController) Subscribe(clientID string, opts SubscribeOptions) (chan Update, error) {
+	pc.configLock.RLock()
+	max := pc.opts.MaxSubscribers
+	pc.configLock.RUnlock()
+
 	pc.lock.Lock()
 	defer pc.lock.Unlock()
 
-	if _, ok := pc.PQ[clientID]; !ok {
-		pc.PQ[clientID] = make(chan pb.PodStatReply, 1)
+	if pc.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+
+	if _, ok := pc.PQ[clientID]; ok {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateSubscription, clientID)
 	}
+	if max > 0 && len(pc.PQ) >= max {
+		return nil, fmt.Errorf("%w: server.maxSubscribers limit of %d concurrent streams reached", ErrTooManySubscribers, max)
+	}
+	pc.PQ[clientID] = make(chan Update, subscriberBuffer)
+	metrics.Subscribers.Set(float64(len(pc.PQ)))
+
+	pc.subscriptions[clientID] = &subscription{opts: opts, opened: time.Now()}
+	klog.V(2).InfoS("Subscriber connected", "subscriber", clientID, "client", opts.Client, "namespace", opts.Namespace)
 
-	return pc.PQ[clientID]
+	return pc.PQ[clientID], nil
 }
 
-// OpenChannel ...
+// CloseChannel ...
 func (pc *PodController) CloseChannel(clientID string) {
 	pc.lock.Lock()
 	defer pc.lock.Unlock()
@@ -135,5 +553,23 @@ func (pc *PodController) CloseChannel(clientID string) {
 	if _, ok := pc.PQ[clientID]; ok {
 		close(pc.PQ[clientID])
 		delete(pc.PQ, clientID)
+		delete(pc.subscriptions, clientID)
+		metrics.Subscribers.Set(float64(len(pc.PQ)))
+		klog.V(2).InfoS("Subscriber disconnected", "subscriber", clientID)
+	}
+}
+
+// Shutdown refuses new channels and closes the open ones. Events already
+// buffered in a channel are still delivered before it reports closed.
+func (pc *PodController) Shutdown() {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	pc.shuttingDown = true
+	for clientID, ch := range pc.PQ {
+		close(ch)
+		delete(pc.PQ, clientID)
+		delete(pc.subscriptions, clientID)
 	}
+	metrics.Subscribers.Set(0)
 }
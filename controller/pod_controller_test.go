@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// startController starts a PodController watching the default namespace
+// of clientset, with the default options changed by configure, and
+// subscribes to it.
+func startController(t *testing.T, clientset *fake.Clientset, configure func(*Options)) (*PodController, chan Update, chan struct{}) {
+	opts := DefaultOptions()
+	opts.Sinks = nil
+	if configure != nil {
+		configure(&opts)
+	}
+
+	pc := NewPodController(watcher.NewManager(clientset, watcher.DefaultOptions()), clientset, []string{"default"}, opts)
+	stop := make(chan struct{})
+	if err := pc.Start(stop); err != nil {
+		close(stop)
+		t.Fatal(err)
+	}
+
+	updates, err := pc.OpenChannel("test")
+	if err != nil {
+		close(stop)
+		t.Fatal(err)
+	}
+	return pc, updates, stop
+}
+
+func TestEventsOfAPodStayInOrder(t *testing.T) {
+	pc, updates, stop := startController(t, fake.NewSimpleClientset(), func(opts *Options) {
+		opts.Workers = 4
+	})
+	defer close(stop)
+
+	// the pods are not in the informer cache, so every event is reported
+	// with the state it carries, numbered in its IP
+	const pods, events = 5, 15
+	go func() {
+		for i := 0; i < events; i++ {
+			for p := 0; p < pods; p++ {
+				eventType := common.EventModified
+				if i == events-1 {
+					eventType = common.EventDeleted
+				}
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("web-%d", p), Namespace: "default"},
+					Status:     v1.PodStatus{PodIP: fmt.Sprintf("10.0.%d.%d", p, i)},
+				}
+				pc.events.Send(common.Event{Key: "default/" + pod.Name, EventType: eventType, ResourceType: common.ResourcePod, Object: pod})
+			}
+		}
+	}()
+
+	next := map[string]int{}
+	timeout := time.After(30 * time.Second)
+	for received := 0; received < pods*events; received++ {
+		select {
+		case update := <-updates:
+			stat := update.Reply.GetPodstat()
+			var p, i int
+			fmt.Sscanf(stat.GetPodip(), "10.0.%d.%d", &p, &i)
+			if i != next[stat.GetPodname()] {
+				t.Fatalf("expected event %d of %s, got %d", next[stat.GetPodname()], stat.GetPodname(), i)
+			}
+			next[stat.GetPodname()]++
+			if i == events-1 && update.Reply.GetMessage() != common.EventDeleted {
+				t.Errorf("expected the last event of %s to be the delete, got %s", stat.GetPodname(), update.Reply.GetMessage())
+			}
+		case <-timeout:
+			t.Fatalf("timed out after %d events", received)
+		}
+	}
+}
+
+func TestDeletedPodReachesSubscribers(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	_, updates, stop := startController(t, clientset, nil)
+	defer close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+		Spec:       v1.PodSpec{NodeName: "node-a"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.0.0.1"},
+	}
+	if _, err := clientset.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().Pods("default").Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the pod is gone from the cache once the delete is processed, it is
+	// reported with its last state
+	for _, expected := range []string{common.EventAdded, common.EventDeleted} {
+		select {
+		case update := <-updates:
+			stat := update.Reply.GetPodstat()
+			if update.Reply.GetMessage() != expected || stat.GetPodip() != "10.0.0.1" || stat.GetNodename() != "node-a" {
+				t.Errorf("expected %s of web-1 with its IP and node, got %v", expected, update.Reply)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+}
+
+func TestFailingEventIsRetriedThenDropped(t *testing.T) {
+	pc, updates, stop := startController(t, fake.NewSimpleClientset(), func(opts *Options) {
+		opts.MaxRetries = 2
+		opts.RateLimiter = RateLimiterOptions{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, QPS: 1000, Burst: 100}
+	})
+	defer close(stop)
+
+	failures := metrics.EventsProcessed.WithLabelValues(common.ResourcePod, common.EventModified, "error")
+	before := testutil.ToFloat64(failures)
+
+	// a key that is not namespace/name fails to be processed
+	const key = "default/web-1/x"
+	pc.events.Send(common.Event{Key: key, EventType: common.EventModified, ResourceType: common.ResourcePod, Object: &v1.Pod{}})
+
+	// the first attempt and MaxRetries retries
+	deadline := time.Now().Add(30 * time.Second)
+	for testutil.ToFloat64(failures)-before < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 3 attempts, got %v", testutil.ToFloat64(failures)-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if attempts := testutil.ToFloat64(failures) - before; attempts != 3 {
+		t.Errorf("expected the event to be dropped after 3 attempts, got %v", attempts)
+	}
+	if requeues := pc.queue.NumRequeues(key); requeues != 0 {
+		t.Errorf("expected the dropped key to be forgotten, got %d requeues", requeues)
+	}
+
+	// the other pods are not held up
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "default"}}
+	pc.events.Send(common.Event{Key: "default/web-2", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: pod})
+	select {
+	case update := <-updates:
+		if update.Reply.GetPodstat().GetPodname() != "web-2" {
+			t.Errorf("expected an update of web-2, got %v", update.Reply)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for web-2")
+	}
+}
+
+func TestRetriesOfAPodReceivingEvents(t *testing.T) {
+	pc, _, stop := startController(t, fake.NewSimpleClientset(), func(opts *Options) {
+		opts.MaxRetries = 2
+		opts.RateLimiter = RateLimiterOptions{BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond, QPS: 1000, Burst: 100}
+	})
+	defer close(stop)
+
+	failures := metrics.EventsProcessed.WithLabelValues(common.ResourcePod, common.EventModified, "error")
+	before := testutil.ToFloat64(failures)
+
+	// events keep arriving for a pod that always fails to be processed
+	const key, sent = "default/web-1/x", 20
+	for i := 0; i < sent; i++ {
+		pc.events.Send(common.Event{Key: key, EventType: common.EventModified, ResourceType: common.ResourcePod, Object: &v1.Pod{}})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// every event is attempted once and retried MaxRetries times, then
+	// dropped
+	deadline := time.Now().Add(30 * time.Second)
+	for testutil.ToFloat64(failures)-before < 3*sent {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d attempts, got %v", 3*sent, testutil.ToFloat64(failures)-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if attempts := testutil.ToFloat64(failures) - before; attempts != 3*sent {
+		t.Errorf("expected %d attempts, got %v", 3*sent, attempts)
+	}
+	if requeues := pc.queue.NumRequeues(key); requeues != 0 {
+		t.Errorf("expected the key to be forgotten once its events are dropped, got %d requeues", requeues)
+	}
+}
+
+func TestEventsDoNotCutTheBackoffShort(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Sinks = nil
+	opts.MaxRetries = 2
+	opts.RateLimiter.BaseDelay, opts.RateLimiter.MaxDelay = time.Hour, time.Hour
+	clientset := fake.NewSimpleClientset()
+	pc := NewPodController(watcher.NewManager(clientset, watcher.DefaultOptions()), clientset, []string{"default"}, opts)
+	defer pc.queue.ShutDown()
+
+	failures := metrics.EventsProcessed.WithLabelValues(common.ResourcePod, common.EventModified, "error")
+	before := testutil.ToFloat64(failures)
+
+	// the first event fails, the second arrives while it backs off
+	const key = "default/web-1/x"
+	for i := 0; i < 2; i++ {
+		pc.events.Send(common.Event{Key: key, EventType: common.EventModified, ResourceType: common.ResourcePod, Object: &v1.Pod{}})
+		pc.processNextItem()
+	}
+
+	if attempts := testutil.ToFloat64(failures) - before; attempts != 1 {
+		t.Errorf("expected the events to wait for the backoff, got %v attempts", attempts)
+	}
+	if requeues := pc.queue.NumRequeues(key); requeues != 1 {
+		t.Errorf("expected the retry to be counted, got %d requeues", requeues)
+	}
+}
+
+func TestSubscriberMetrics(t *testing.T) {
+	pc, _, stop := startController(t, fake.NewSimpleClientset(), nil)
+	defer close(stop)
+
+	if _, err := pc.OpenChannel("second"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.OpenChannel("second"); err == nil {
+		t.Fatal("expected a duplicate subscription to be refused")
+	}
+	if value := testutil.ToFloat64(metrics.Subscribers); value != 2 {
+		t.Errorf("expected 2 subscribers, got %v", value)
+	}
+
+	pc.CloseChannel("second")
+	if value := testutil.ToFloat64(metrics.Subscribers); value != 1 {
+		t.Errorf("expected 1 subscriber once one closed, got %v", value)
+	}
+
+	pc.Shutdown()
+	if value := testutil.ToFloat64(metrics.Subscribers); value != 0 {
+		t.Errorf("expected no subscribers after shutdown, got %v", value)
+	}
+}
+
+func TestChangesOfTheEvent(t *testing.T) {
+	// the cache holds a newer state than the event carries
+	cached := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"generation": "3"}}}
+	pc, _, stop := startController(t, fake.NewSimpleClientset(cached), nil)
+	defer close(stop)
+
+	updates, err := pc.Subscribe("changes", SubscribeOptions{Changes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, pod := cached.DeepCopy(), cached.DeepCopy()
+	old.Labels["generation"], pod.Labels["generation"] = "1", "2"
+	pc.events.Send(common.Event{Key: "default/web-1", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: pod, Old: old})
+
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case update := <-updates:
+			// skip the pod added by the informer
+			if update.Reply.GetMessage() != common.EventModified {
+				continue
+			}
+			changes := update.Reply.GetChanges()
+			if len(changes) != 1 || changes[0].GetPath() != "metadata.labels.generation" || changes[0].GetOld() != `"1"` || changes[0].GetNew() != `"2"` {
+				t.Errorf("expected the label to change from 1 to 2, got %v", changes)
+			}
+			return
+		case <-timeout:
+			t.Fatal("timed out waiting for the update")
+		}
+	}
+}
+
+func TestHasPod(t *testing.T) {
+	cached := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", UID: "uid-web-1"}}
+	pc, _, stop := startController(t, fake.NewSimpleClientset(cached), nil)
+	defer close(stop)
+
+	for _, test := range []struct {
+		namespace, name string
+		uid             types.UID
+		expected        bool
+	}{
+		{"default", "web-1", "uid-web-1", true},
+		{"default", "web-1", "uid-web-0", false},
+		{"default", "web-2", "uid-web-2", false},
+		{"production", "web-1", "uid-web-1", false},
+	} {
+		if exists := pc.HasPod(test.namespace, test.name, test.uid); exists != test.expected {
+			t.Errorf("%s/%s %s: expected %v, got %v", test.namespace, test.name, test.uid, test.expected, exists)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/tracing"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// eventQueue is the EventSink feeding the PodController. It queues the keys
+// of the events and holds the events of every key until a worker takes
+// them. The workqueue hands a key to one worker at a time, so the events of
+// a pod are processed in order whatever the number of workers.
+type eventQueue struct {
+	queue   workqueue.RateLimitingInterface
+	limiter workqueue.RateLimiter
+
+	lock    sync.Mutex
+	pending map[string][]common.Event
+	// retryAt holds when the keys backing off are due again.
+	retryAt map[string]time.Time
+}
+
+// newEventQueue returns the queue of events feeding queue, which limits its
+// retries with limiter.
+func newEventQueue(queue workqueue.RateLimitingInterface, limiter workqueue.RateLimiter) *eventQueue {
+	return &eventQueue{queue: queue, limiter: limiter, pending: map[string][]common.Event{}, retryAt: map[string]time.Time{}}
+}
+
+func (q *eventQueue) Name() string { return "queue" }
+
+// Send queues e, starting its trace.
+func (q *eventQueue) Send(e common.Event) error {
+	q.lock.Lock()
+	q.pending[e.Key] = append(q.pending[e.Key], tracing.StartEvent(e))
+	q.lock.Unlock()
+
+	q.queue.Add(e.Key)
+	return nil
+}
+
+// Close leaves the queue to the controller.
+func (q *eventQueue) Close() error { return nil }
+
+// take removes and returns the pending events of key, the oldest first.
+// Nothing is returned while key backs off, even if events were sent since.
+func (q *eventQueue) take(key string) []common.Event {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if retryAt, ok := q.retryAt[key]; ok {
+		if time.Now().Before(retryAt) {
+			return nil
+		}
+		delete(q.retryAt, key)
+	}
+
+	events := q.pending[key]
+	delete(q.pending, key)
+	return events
+}
+
+// putBack returns events taken from key and not processed yet ahead of the
+// events of key queued since.
+func (q *eventQueue) putBack(key string, events []common.Event) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.pending[key] = append(append([]common.Event(nil), events...), q.pending[key]...)
+}
+
+// retry puts back events taken from key and queues key again once its
+// backoff has passed.
+func (q *eventQueue) retry(key string, events []common.Event) {
+	q.putBack(key, events)
+
+	delay := q.limiter.When(key)
+	q.lock.Lock()
+	q.retryAt[key] = time.Now().Add(delay)
+	q.lock.Unlock()
+
+	q.queue.AddAfter(key, delay)
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+// Update is a reply queued for a subscriber.
+type Update struct {
+	Reply *pb.PodStatReply
+
+	// SpanContext is the span that processed the event of the reply, the
+	// parent of the span sending it.
+	SpanContext trace.SpanContext
+
+	// Queued is the time the reply was queued.
+	Queued time.Time
+}
+
+// SubscribeOptions describes a new channel.
+type SubscribeOptions struct {
+	// Namespace restricts the channel to the pods of a namespace.
+	// NamespaceAll receives every pod.
+	Namespace string
+
+	// Client names the client of the channel, for debugging.
+	Client string
+
+	// Changes adds the changed fields of updated pods to the replies.
+	Changes bool
+
+	// CloudEvents adds the events as CloudEvents to the replies.
+	CloudEvents bool
+}
+
+// Subscription describes an open channel.
+type Subscription struct {
+	ID          string    `json:"id"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Client      string    `json:"client,omitempty"`
+	Changes     bool      `json:"changes,omitempty"`
+	CloudEvents bool      `json:"cloudEvents,omitempty"`
+	Opened      time.Time `json:"opened"`
+
+	// Buffered is the number of messages waiting to be sent, the lag of
+	// the client.
+	Buffered int `json:"buffered"`
+
+	// Dropped is the number of messages dropped because the buffer was
+	// full.
+	Dropped uint64 `json:"dropped"`
+
+	// LastEvent is the time the last message was queued for the client.
+	LastEvent *time.Time `json:"lastEvent,omitempty"`
+}
+
+// subscription is the state of an open channel. The counters are updated
+// while broadcasting under the read lock, so they are atomic.
+type subscription struct {
+	opts      SubscribeOptions
+	opened    time.Time
+	dropped   uint64
+	lastEvent int64
+}
+
+func (s *subscription) matches(namespace string) bool {
+	return s.opts.Namespace == metav1.NamespaceAll || s.opts.Namespace == namespace
+}
+
+func (s *subscription) queued() {
+	atomic.StoreInt64(&s.lastEvent, time.Now().UnixNano())
+}
+
+func (s *subscription) drop() {
+	atomic.AddUint64(&s.dropped, 1)
+}
+
+// Subscriptions describes the open channels, sorted by ID.
+func (pc *PodController) Subscriptions() []Subscription {
+	pc.lock.RLock()
+	defer pc.lock.RUnlock()
+
+	subscriptions := make([]Subscription, 0, len(pc.subscriptions))
+	for id, s := range pc.subscriptions {
+		subscription := Subscription{
+			ID:          id,
+			Namespace:   s.opts.Namespace,
+			Client:      s.opts.Client,
+			Changes:     s.opts.Changes,
+			CloudEvents: s.opts.CloudEvents,
+			Opened:      s.opened,
+			Buffered:    len(pc.PQ[id]),
+			Dropped:     atomic.LoadUint64(&s.dropped),
+		}
+		if lastEvent := atomic.LoadInt64(&s.lastEvent); lastEvent != 0 {
+			t := time.Unix(0, lastEvent)
+			subscription.LastEvent = &t
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+	return subscriptions
+}
@@ -0,0 +1,193 @@
+// Package diff reports the changes between two versions of an object as a
+// list of changed fields, instead of a dump of both objects.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Op is the kind of a change, named after the JSON Patch operations.
+type Op string
+
+// Ops
+const (
+	OpAdd     Op = "add"
+	OpRemove  Op = "remove"
+	OpReplace Op = "replace"
+)
+
+// DefaultIgnorePaths are left out of diffs unless configured otherwise:
+// they change on every update.
+var DefaultIgnorePaths = []string{
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+}
+
+// Change is a changed field. Old is unset for added fields and New for
+// removed ones.
+type Change struct {
+	Op   Op          `json:"op"`
+	Path Path        `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Options ...
+type Options struct {
+	// IgnorePaths are the fields left out of diffs, with their children.
+	// A * segment matches any key or index, as in
+	// status.containerStatuses[*].lastState.
+	IgnorePaths []string
+}
+
+// DefaultOptions ...
+func DefaultOptions() Options {
+	return Options{IgnorePaths: DefaultIgnorePaths}
+}
+
+// Differ compares objects.
+type Differ struct {
+	ignore []Path
+}
+
+// New returns a Differ ignoring the paths of opts.
+func New(opts Options) (*Differ, error) {
+	d := &Differ{}
+	for _, s := range opts.IgnorePaths {
+		p, err := ParsePath(s)
+		if err != nil {
+			return nil, err
+		}
+		d.ignore = append(d.ignore, p)
+	}
+	return d, nil
+}
+
+// Diff returns the changes from old to new. The objects are compared in
+// their JSON form: Kubernetes objects, structs and maps can be compared.
+// Changes are sorted by path, except that the removed elements of a list
+// come last to first, so that they apply in order as a JSON Patch.
+func (d *Differ) Diff(old, new interface{}) ([]Change, error) {
+	o, err := toUnstructured(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toUnstructured(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	d.compare(Path{}, o, n, &changes)
+	return changes, nil
+}
+
+func (d *Differ) ignored(path Path) bool {
+	for _, p := range d.ignore {
+		if p.matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Differ) compare(path Path, old, new interface{}, changes *[]Change) {
+	if d.ignored(path) {
+		return
+	}
+
+	switch o := old.(type) {
+	case map[string]interface{}:
+		n, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(o)+len(n))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		for k := range n {
+			if _, ok := o[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			switch {
+			case !inOld:
+				d.add(path.child(k), OpAdd, nil, nv, changes)
+			case !inNew:
+				d.add(path.child(k), OpRemove, ov, nil, changes)
+			default:
+				d.compare(path.child(k), ov, nv, changes)
+			}
+		}
+		return
+
+	case []interface{}:
+		n, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+
+		common := len(o)
+		if len(n) < common {
+			common = len(n)
+		}
+		for i := 0; i < common; i++ {
+			d.compare(path.child(i), o[i], n[i], changes)
+		}
+		for i := common; i < len(n); i++ {
+			d.add(path.child(i), OpAdd, nil, n[i], changes)
+		}
+		for i := len(o) - 1; i >= common; i-- {
+			d.add(path.child(i), OpRemove, o[i], nil, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Op: OpReplace, Path: path, Old: old, New: new})
+	}
+}
+
+func (d *Differ) add(path Path, op Op, old, new interface{}, changes *[]Change) {
+	if d.ignored(path) {
+		return
+	}
+	*changes = append(*changes, Change{Op: op, Path: path, Old: old, New: new})
+}
+
+// toUnstructured returns the JSON form of obj.
+func toUnstructured(obj interface{}) (interface{}, error) {
+	switch o := obj.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return o, nil
+	case runtime.Object:
+		if reflect.ValueOf(o).IsNil() {
+			return nil, nil
+		}
+		return runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %T: %v", obj, err)
+	}
+	var u interface{}
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
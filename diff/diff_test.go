@@ -0,0 +1,174 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod(phase v1.PodPhase, resourceVersion string, labels map[string]string, containers ...string) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-1",
+			Namespace:       "default",
+			ResourceVersion: resourceVersion,
+			Labels:          labels,
+			ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "kubelet-" + resourceVersion}},
+		},
+		Status: v1.PodStatus{Phase: phase},
+	}
+	for _, image := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "app", Image: image})
+	}
+	return pod
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		ignore []string
+		old    interface{}
+		new    interface{}
+		text   string
+		patch  string
+	}{
+		{
+			name:  "noise only",
+			old:   testPod(v1.PodRunning, "1", nil),
+			new:   testPod(v1.PodRunning, "2", nil),
+			text:  "",
+			patch: `[]`,
+		},
+		{
+			name: "phase and labels",
+			old:  testPod(v1.PodPending, "1", map[string]string{"app": "web", "app.kubernetes.io/version": "1"}),
+			new:  testPod(v1.PodRunning, "2", map[string]string{"tier": "front", "app.kubernetes.io/version": "2"}),
+			text: `metadata.labels.app: removed "web"
+metadata.labels["app.kubernetes.io/version"]: "1" -> "2"
+metadata.labels.tier: added "front"
+status.phase: "Pending" -> "Running"
+`,
+			patch: `[{"op":"remove","path":"/metadata/labels/app"},` +
+				`{"op":"replace","path":"/metadata/labels/app.kubernetes.io~1version","value":"2"},` +
+				`{"op":"add","path":"/metadata/labels/tier","value":"front"},` +
+				`{"op":"replace","path":"/status/phase","value":"Running"}]`,
+		},
+		{
+			name: "list elements",
+			old:  testPod(v1.PodRunning, "1", nil, "a:1", "b:1", "c:1"),
+			new:  testPod(v1.PodRunning, "1", nil, "a:2"),
+			text: `spec.containers[0].image: "a:1" -> "a:2"
+spec.containers[2]: removed {"image":"c:1","name":"app","resources":{}}
+spec.containers[1]: removed {"image":"b:1","name":"app","resources":{}}
+`,
+			patch: `[{"op":"replace","path":"/spec/containers/0/image","value":"a:2"},` +
+				`{"op":"remove","path":"/spec/containers/2"},` +
+				`{"op":"remove","path":"/spec/containers/1"}]`,
+		},
+		{
+			name:   "wildcard ignore",
+			ignore: []string{"spec.containers[*].image", "metadata.*"},
+			old:    testPod(v1.PodPending, "1", map[string]string{"app": "web"}, "a:1"),
+			new:    testPod(v1.PodRunning, "2", nil, "a:2"),
+			text:   "status.phase: \"Pending\" -> \"Running\"\n",
+			patch:  `[{"op":"replace","path":"/status/phase","value":"Running"}]`,
+		},
+		{
+			name:  "maps",
+			old:   map[string]interface{}{"a": 1, "b": nil},
+			new:   map[string]interface{}{"a": "1", "b": nil},
+			text:  "a: 1 -> \"1\"\n",
+			patch: `[{"op":"replace","path":"/a","value":"1"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if test.ignore != nil {
+				opts.IgnorePaths = append(opts.IgnorePaths, test.ignore...)
+			}
+			d, err := New(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changes, err := d.Diff(test.old, test.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if text := Text(changes); text != test.text {
+				t.Errorf("expected text\n%s\ngot\n%s", test.text, text)
+			}
+
+			patch, err := Format(changes, FormatJSONPatch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(patch) != test.patch {
+				t.Errorf("expected patch\n%s\ngot\n%s", test.patch, patch)
+			}
+
+			if _, err := Format(changes, FormatJSON); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected Path
+		// renders is the String of the parsed path, if it differs from path
+		renders string
+		err     bool
+	}{
+		{path: "status.phase", expected: Path{"status", "phase"}},
+		{path: "spec.containers[0].image", expected: Path{"spec", "containers", 0, "image"}},
+		{path: `metadata.annotations["a.b/c]"]`, expected: Path{"metadata", "annotations", "a.b/c]"}},
+		{path: "status.containerStatuses[*].state.*", expected: Path{"status", "containerStatuses", wildcard{}, "state", wildcard{}}, renders: "status.containerStatuses.*.state.*"},
+		{path: "", err: true},
+		{path: "spec..x", err: true},
+		{path: "spec.containers[-1]", err: true},
+		{path: `metadata.labels["x`, err: true},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePath(test.path)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", test.path, p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(p, test.expected) {
+			t.Errorf("%q: expected %#v, got %#v", test.path, test.expected, p)
+		}
+		renders := test.renders
+		if renders == "" {
+			renders = test.path
+		}
+		if s := p.String(); s != renders {
+			t.Errorf("%q: renders as %q, expected %q", test.path, s, renders)
+		}
+	}
+}
+
+func TestChangeJSON(t *testing.T) {
+	data, err := json.Marshal(Change{Op: OpReplace, Path: Path{"spec", "replicas"}, Old: 1, New: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"op":"replace","path":"spec.replicas","old":1,"new":3}`; string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
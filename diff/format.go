@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Formats of a list of changes.
+const (
+	// FormatText is one line per change, for humans.
+	FormatText = "text"
+
+	// FormatJSON is the list of changes as a JSON array.
+	FormatJSON = "json"
+
+	// FormatJSONPatch is a JSON Patch (RFC 6902) turning the old object
+	// into the new one.
+	FormatJSONPatch = "json-patch"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatText, FormatJSON, FormatJSONPatch}
+
+// Format renders changes in format.
+func Format(changes []Change, format string) ([]byte, error) {
+	switch format {
+	case FormatText:
+		return []byte(Text(changes)), nil
+	case FormatJSON:
+		if changes == nil {
+			changes = []Change{}
+		}
+		return json.Marshal(changes)
+	case FormatJSONPatch:
+		return json.Marshal(Patch(changes))
+	default:
+		return nil, fmt.Errorf("unsupported diff format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Text renders changes one per line:
+//
+//	status.phase: "Pending" -> "Running"
+//	metadata.labels.tier: added "web"
+//	spec.nodeSelector: removed {"disk":"ssd"}
+func Text(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		path := c.Path.String()
+		if path == "" {
+			path = "."
+		}
+		switch c.Op {
+		case OpAdd:
+			fmt.Fprintf(&b, "%s: added %s\n", path, Value(c.New))
+		case OpRemove:
+			fmt.Fprintf(&b, "%s: removed %s\n", path, Value(c.Old))
+		default:
+			fmt.Fprintf(&b, "%s: %s -> %s\n", path, Value(c.Old), Value(c.New))
+		}
+	}
+	return b.String()
+}
+
+// Operation is an operation of a JSON Patch.
+type Operation struct {
+	Op    Op
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON leaves the value out of remove operations only, a null value
+// is a valid value to add or replace.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == OpRemove {
+		return json.Marshal(struct {
+			Op   Op     `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    Op          `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// Patch returns the JSON Patch applying changes.
+func Patch(changes []Change) []Operation {
+	patch := make([]Operation, 0, len(changes))
+	for _, c := range changes {
+		op := Operation{Op: c.Op, Path: c.Path.Pointer()}
+		if c.Op != OpRemove {
+			op.Value = c.New
+		}
+		patch = append(patch, op)
+	}
+	return patch
+}
+
+// Paths returns the paths of changes.
+func Paths(changes []Change) []string {
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path.String())
+	}
+	return paths
+}
+
+// Value renders v as compact JSON.
+func Value(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
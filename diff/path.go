@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Path locates a field of an object. Map keys are strings and list indexes
+// are ints.
+type Path []interface{}
+
+// wildcard is the pattern segment matching any key or index.
+type wildcard struct{}
+
+// identifier matches the keys written without quotes in a path.
+var identifier = regexp.MustCompile(`^[A-Za-z0-9_$-]+$`)
+
+// String renders p with dots between keys and brackets around indexes, as
+// in spec.containers[0].image. Keys that are not identifiers are quoted:
+// metadata.labels["app.kubernetes.io/name"].
+func (p Path) String() string {
+	var b strings.Builder
+	for i, segment := range p {
+		switch s := segment.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		case wildcard:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteByte('*')
+		case string:
+			if !identifier.MatchString(s) {
+				fmt.Fprintf(&b, "[%s]", strconv.Quote(s))
+				continue
+			}
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// Pointer renders p as a JSON Pointer (RFC 6901).
+func (p Path) Pointer() string {
+	var b strings.Builder
+	for _, segment := range p {
+		b.WriteByte('/')
+		switch s := segment.(type) {
+		case int:
+			b.WriteString(strconv.Itoa(s))
+		case string:
+			b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(s))
+		}
+	}
+	return b.String()
+}
+
+// MarshalJSON renders p as its String.
+func (p Path) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// child returns a copy of p extended by segment.
+func (p Path) child(segment interface{}) Path {
+	c := make(Path, len(p), len(p)+1)
+	copy(c, p)
+	return append(c, segment)
+}
+
+// matches reports whether the pattern p matches path or one of its
+// parents.
+func (p Path) matches(path Path) bool {
+	if len(p) > len(path) {
+		return false
+	}
+	for i, segment := range p {
+		if _, ok := segment.(wildcard); ok {
+			continue
+		}
+		if segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePath parses the String form of a path. A * segment, written .* or
+// [*], matches any key or index.
+func ParsePath(s string) (Path, error) {
+	var p Path
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated [", s)
+			}
+			inner := s[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				// the quoted key may contain ], find its closing quote
+				key, rest, err := unquotePrefix(s[i+1:])
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: %v", s, err)
+				}
+				if !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("invalid path %q: expected ] after quoted key", s)
+				}
+				p = append(p, key)
+				i = len(s) - len(rest) + 1
+				break
+			}
+			switch index, err := strconv.Atoi(inner); {
+			case inner == "*":
+				p = append(p, wildcard{})
+			case err == nil && index >= 0:
+				p = append(p, index)
+			default:
+				return nil, fmt.Errorf("invalid path %q: bad index %q", s, inner)
+			}
+			i += end + 1
+		case s[i] == '.' && i > 0:
+			i++
+			fallthrough
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			key := s[i : i+end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key", s)
+			}
+			if key == "*" {
+				p = append(p, wildcard{})
+			} else {
+				p = append(p, key)
+			}
+			i += end
+		}
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return p, nil
+}
+
+// unquotePrefix unquotes the Go string literal at the start of s and
+// returns the rest of s.
+func unquotePrefix(s string) (string, string, error) {
+	for end := 1; end < len(s); end++ {
+		switch s[end] {
+		case '\\':
+			end++
+		case '"':
+			key, err := strconv.Unquote(s[:end+1])
+			return key, s[end+1:], err
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted key")
+}
@@ -0,0 +1,571 @@
+// Package eventlog records the pod and deployment events on disk, so that
+// they can be replayed after dwserver restarts.
+//
+// The log is a directory of append-only segments of JSON lines. Events are
+// appended to the newest segment, which is sealed once it reaches the
+// segment size. Sealed segments are compacted in the background: expired
+// events and events of an object observed again at the same resourceVersion,
+// such as the relist of a restarted dwserver, are dropped.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/history"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultSegmentSize is the size segments are sealed at.
+	DefaultSegmentSize = 64 << 20
+
+	// DefaultMaxAge is how long events are kept.
+	DefaultMaxAge = 7 * 24 * time.Hour
+
+	// DefaultCompactInterval is how often the sealed segments are
+	// compacted.
+	DefaultCompactInterval = 10 * time.Minute
+)
+
+// segmentSuffix is the extension of the segment files, which are named
+// after their sequence number.
+const segmentSuffix = ".jsonl"
+
+// maxLineSize bounds the size of an event in a segment.
+const maxLineSize = 16 << 20
+
+// Options describes a Log.
+type Options struct {
+	// Dir is the directory of the segments, created if it does not exist.
+	Dir string
+
+	// SegmentSize is the size in bytes segments are sealed at. Zero uses
+	// DefaultSegmentSize.
+	SegmentSize int64
+
+	// MaxSize bounds the size in bytes of the sealed segments, the oldest
+	// are removed first. Zero means no limit.
+	MaxSize int64
+
+	// MaxAge is how long events are kept. Zero uses DefaultMaxAge.
+	MaxAge time.Duration
+
+	// CompactInterval is how often the sealed segments are compacted. Zero
+	// uses DefaultCompactInterval.
+	CompactInterval time.Duration
+}
+
+// record is an event in a segment.
+type record struct {
+	Time            time.Time       `json:"time"`
+	Type            string          `json:"type"`
+	Resource        string          `json:"resource"`
+	Namespace       string          `json:"namespace,omitempty"`
+	Name            string          `json:"name"`
+	Deployment      string          `json:"deployment,omitempty"`
+	UID             string          `json:"uid,omitempty"`
+	ResourceVersion string          `json:"resourceVersion,omitempty"`
+	Object          json.RawMessage `json:"object,omitempty"`
+}
+
+// dedupKey identifies the observations of an object at a resourceVersion,
+// empty if the event has no resourceVersion. Deletes are told apart, they
+// may carry the resourceVersion of the last update.
+func (r record) dedupKey() string {
+	if r.ResourceVersion == "" {
+		return ""
+	}
+	key := strings.Join([]string{r.Resource, r.Namespace, r.Name, r.UID, r.ResourceVersion}, "/")
+	if r.Type == common.EventDeleted {
+		key += "/deleted"
+	}
+	return key
+}
+
+// entry returns the history entry of r.
+func (r record) entry() history.Entry {
+	e := history.Entry{
+		Time:            r.Time,
+		Type:            r.Type,
+		Resource:        r.Resource,
+		Namespace:       r.Namespace,
+		Name:            r.Name,
+		Deployment:      r.Deployment,
+		ResourceVersion: r.ResourceVersion,
+	}
+
+	var obj interface{}
+	switch r.Resource {
+	case common.ResourcePod:
+		obj = &v1.Pod{}
+	case common.ResourceDeployment:
+		obj = &appv1.Deployment{}
+	case common.ResourceAnomaly:
+		obj = &anomaly.Anomaly{}
+	}
+	if obj != nil && len(r.Object) > 0 && json.Unmarshal(r.Object, obj) == nil {
+		e.SetObject(obj)
+	}
+	return e
+}
+
+// segment describes a segment file.
+type segment struct {
+	seq   int64
+	path  string
+	size  int64
+	first time.Time
+	last  time.Time
+}
+
+// add accounts for a line of n bytes of an event at t.
+func (s *segment) add(t time.Time, n int) {
+	if s.first.IsZero() || t.Before(s.first) {
+		s.first = t
+	}
+	if t.After(s.last) {
+		s.last = t
+	}
+	s.size += int64(n)
+}
+
+// Log is an EventSink appending the events it is sent to the segments of a
+// directory.
+type Log struct {
+	opts Options
+
+	// lock guards segments, the last of which is the active segment that
+	// file appends to
+	lock     sync.Mutex
+	segments []segment
+	file     *os.File
+
+	// compactLock serializes compactions
+	compactLock sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+
+	now func() time.Time
+}
+
+// Open opens the log in opts.Dir and starts compacting it. Events are
+// appended to a new segment, so that a segment torn by a crash is never
+// appended to.
+func Open(opts Options) (*Log, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("event log needs a directory")
+	}
+	if opts.SegmentSize == 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.CompactInterval == 0 {
+		opts.CompactInterval = DefaultCompactInterval
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+	segments, err := readSegments(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		opts:     opts,
+		segments: segments,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		now:      time.Now,
+	}
+	if err := l.newSegment(); err != nil {
+		return nil, err
+	}
+
+	go l.compactLoop()
+	return l, nil
+}
+
+// readSegments returns the segments of dir, the oldest first.
+func readSegments(dir string) ([]segment, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, f := range files {
+		seq, err := strconv.ParseInt(strings.TrimSuffix(f.Name(), segmentSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(f.Name(), segmentSuffix) || f.IsDir() {
+			continue
+		}
+
+		s := segment{seq: seq, path: filepath.Join(dir, f.Name())}
+		err = scan(s.path, func(line []byte) error {
+			var r struct {
+				Time time.Time `json:"time"`
+			}
+			if json.Unmarshal(line, &r) == nil {
+				s.add(r.Time, 0)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.size = f.Size()
+		segments = append(segments, s)
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].seq < segments[j].seq })
+	return segments, nil
+}
+
+// newSegment starts a new active segment. The caller holds the lock or is
+// opening the log.
+func (l *Log) newSegment() error {
+	var seq int64 = 1
+	if len(l.segments) > 0 {
+		seq = l.segments[len(l.segments)-1].seq + 1
+	}
+
+	s := segment{seq: seq, path: filepath.Join(l.opts.Dir, fmt.Sprintf("%016d%s", seq, segmentSuffix))}
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			klog.ErrorS(err, "Failed to close segment", "path", l.segments[len(l.segments)-1].path)
+		}
+	}
+	l.file = file
+	l.segments = append(l.segments, s)
+	return nil
+}
+
+// Name implements watcher.EventSink.
+func (l *Log) Name() string { return "eventlog:" + l.opts.Dir }
+
+// Send appends e to the active segment.
+func (l *Log) Send(e common.Event) error {
+	entry := history.NewEntry(e)
+	if entry.Time.IsZero() {
+		entry.Time = l.now()
+	}
+
+	r := record{
+		Time:            entry.Time,
+		Type:            entry.Type,
+		Resource:        entry.Resource,
+		Namespace:       entry.Namespace,
+		Name:            entry.Name,
+		Deployment:      entry.Deployment,
+		ResourceVersion: entry.ResourceVersion,
+	}
+	if obj, err := meta.Accessor(e.Object); err == nil {
+		r.UID = string(obj.GetUID())
+	}
+	if e.Object != nil {
+		data, err := json.Marshal(e.Object)
+		if err != nil {
+			return err
+		}
+		r.Object = data
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return watcher.ErrSinkClosed
+	}
+	active := &l.segments[len(l.segments)-1]
+	if active.size > 0 && active.size+int64(len(line)) > l.opts.SegmentSize {
+		if err := l.newSegment(); err != nil {
+			return err
+		}
+		active = &l.segments[len(l.segments)-1]
+	}
+
+	n, err := l.file.Write(line)
+	active.add(r.Time, n)
+	return err
+}
+
+// Close stops the compactions and closes the active segment.
+func (l *Log) Close() error {
+	l.lock.Lock()
+	if l.file == nil {
+		l.lock.Unlock()
+		return nil
+	}
+	file := l.file
+	l.file = nil
+	l.lock.Unlock()
+
+	close(l.stop)
+	<-l.done
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Replay calls f with the events selected by q, in the order they were
+// appended, until f fails. q.Limit bounds the number of events.
+func (l *Log) Replay(q history.Query, f func(history.Entry) error) error {
+	l.lock.Lock()
+	segments := append([]segment(nil), l.segments...)
+	l.lock.Unlock()
+
+	cutoff := l.now().Add(-l.opts.MaxAge)
+	sent := 0
+	for _, s := range segments {
+		if s.size == 0 || s.last.Before(cutoff) || (!q.Since.IsZero() && s.last.Before(q.Since)) {
+			continue
+		}
+		if !q.Until.IsZero() && s.first.After(q.Until) {
+			break
+		}
+
+		err := scan(s.path, func(line []byte) error {
+			var r record
+			if err := json.Unmarshal(line, &r); err != nil {
+				// the tail of the active segment may be in the middle of
+				// being written
+				klog.V(2).InfoS("Skipping unreadable event", "path", s.path, "err", err)
+				return nil
+			}
+			e := r.entry()
+			if e.Time.Before(cutoff) || !q.Matches(e) {
+				return nil
+			}
+			if err := f(e); err != nil {
+				return err
+			}
+			if sent++; q.Limit > 0 && sent >= q.Limit {
+				return errLimit
+			}
+			return nil
+		})
+		switch {
+		case err == errLimit:
+			return nil
+		case os.IsNotExist(err):
+			// removed by a compaction since
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// errLimit stops a replay once the limit is reached.
+var errLimit = fmt.Errorf("limit reached")
+
+// scan calls f with every line of the file at path until f fails.
+func scan(path string, f func(line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := f(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (l *Log) compactLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.opts.CompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.Compact(); err != nil {
+				klog.ErrorS(err, "Failed to compact the event log", "dir", l.opts.Dir)
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Compact removes the sealed segments that expired or exceed the size
+// limit, and rewrites the others without their expired and duplicate
+// events.
+func (l *Log) Compact() error {
+	l.compactLock.Lock()
+	defer l.compactLock.Unlock()
+
+	// new segments are only ever appended, so the sealed segments stay at
+	// the head of l.segments while they are compacted
+	l.lock.Lock()
+	sealed := append([]segment(nil), l.segments[:len(l.segments)-1]...)
+	l.lock.Unlock()
+
+	cutoff := l.now().Add(-l.opts.MaxAge)
+	seen := map[string]bool{}
+	compacted := make([]segment, 0, len(sealed))
+	var errs []string
+	for _, s := range sealed {
+		// every Open starts a segment, the ones left empty are removed
+		// whatever their age
+		if s.size == 0 || s.last.Before(cutoff) {
+			if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+				compacted = append(compacted, s)
+			}
+			continue
+		}
+
+		s, err := rewrite(s, cutoff, seen)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		if s.size > 0 {
+			compacted = append(compacted, s)
+		}
+	}
+
+	l.lock.Lock()
+	l.segments = append(compacted, l.segments[len(sealed):]...)
+	l.lock.Unlock()
+
+	if l.opts.MaxSize > 0 {
+		if err := l.trim(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// rewrite drops the events of s older than cutoff and the ones seen
+// already, and returns the segment left. The segment is only rewritten if
+// events were dropped, and removed if none are left.
+func rewrite(s segment, cutoff time.Time, seen map[string]bool) (segment, error) {
+	var (
+		kept    [][]byte
+		dropped int
+		left    = segment{seq: s.seq, path: s.path}
+	)
+	err := scan(s.path, func(line []byte) error {
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			dropped++
+			return nil
+		}
+		key := r.dedupKey()
+		if r.Time.Before(cutoff) || (key != "" && seen[key]) {
+			dropped++
+			return nil
+		}
+		if key != "" {
+			seen[key] = true
+		}
+		kept = append(kept, append([]byte(nil), line...))
+		left.add(r.Time, len(line)+1)
+		return nil
+	})
+	if err != nil || dropped == 0 {
+		return s, err
+	}
+
+	if len(kept) == 0 {
+		return left, os.Remove(s.path)
+	}
+
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return s, err
+	}
+	w := bufio.NewWriter(file)
+	for _, line := range kept {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err = w.Flush(); err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return s, err
+	}
+
+	klog.V(2).InfoS("Compacted event log segment", "path", s.path, "dropped", dropped, "kept", len(kept))
+	return left, nil
+}
+
+// trim removes the oldest sealed segments until the log fits MaxSize.
+func (l *Log) trim() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var total int64
+	for _, s := range l.segments {
+		total += s.size
+	}
+
+	removed := 0
+	for removed < len(l.segments)-1 && total > l.opts.MaxSize {
+		s := l.segments[removed]
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			l.segments = l.segments[removed:]
+			return err
+		}
+		total -= s.size
+		removed++
+	}
+	l.segments = l.segments[removed:]
+	return nil
+}
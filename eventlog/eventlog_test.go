@@ -0,0 +1,206 @@
+package eventlog
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/history"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// at returns the time minutes into the tests.
+func at(minutes int) time.Time {
+	return time.Unix(0, 0).UTC().Add(time.Duration(minutes) * time.Minute)
+}
+
+func podEvent(eventType, name, rv string, minutes int) common.Event {
+	return common.Event{
+		Key:          "default/" + name,
+		EventType:    eventType,
+		ResourceType: common.ResourcePod,
+		Object: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name), ResourceVersion: rv},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		},
+		Time: at(minutes),
+	}
+}
+
+// openLog opens a log whose clock is at minutes.
+func openLog(t *testing.T, opts Options, minutes *int) *Log {
+	l, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return at(*minutes) }
+	return l
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "eventlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// replay returns name@resourceVersion of the events q selects.
+func replay(t *testing.T, l *Log, q history.Query) []string {
+	var got []string
+	err := l.Replay(q, func(e history.Entry) error {
+		if e.Podstat.GetPodname() != e.Name || e.Podstat.GetPodstate() != string(v1.PodRunning) {
+			t.Errorf("expected the state of the pod %s, got %v", e.Name, e.Podstat)
+		}
+		got = append(got, e.Name+"@"+e.ResourceVersion)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestReplayAcrossRestarts(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	now := 30
+	opts := Options{Dir: dir, SegmentSize: 1}
+	l := openLog(t, opts, &now)
+	for i, rv := range []string{"1", "2", "3"} {
+		if err := l.Send(podEvent(common.EventModified, "web-1", rv, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.segments) != 3 {
+		t.Errorf("expected a segment per event, got %d segments", len(l.segments))
+	}
+
+	l = openLog(t, opts, &now)
+	defer l.Close()
+	if err := l.Send(podEvent(common.EventDeleted, "web-1", "4", 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replay(t, l, history.Query{}); !reflect.DeepEqual(got, []string{"web-1@1", "web-1@2", "web-1@3", "web-1@4"}) {
+		t.Errorf("expected every event, got %v", got)
+	}
+	if got := replay(t, l, history.Query{Since: at(1), Types: []string{common.EventModified}}); !reflect.DeepEqual(got, []string{"web-1@2", "web-1@3"}) {
+		t.Errorf("expected the updates since 1m, got %v", got)
+	}
+	if got := replay(t, l, history.Query{Limit: 1}); !reflect.DeepEqual(got, []string{"web-1@1"}) {
+		t.Errorf("expected the first event, got %v", got)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	now := 0
+	l := openLog(t, Options{Dir: dir, SegmentSize: 1 << 20, MaxAge: time.Hour}, &now)
+	l.Send(podEvent(common.EventAdded, "web-1", "1", 0))
+	l.Send(podEvent(common.EventAdded, "web-2", "2", 30))
+	l.Close()
+
+	// a restarted dwserver lists the pods again
+	l = openLog(t, Options{Dir: dir, SegmentSize: 1 << 20, MaxAge: time.Hour}, &now)
+	l.Send(podEvent(common.EventAdded, "web-2", "2", 40))
+	l.Send(podEvent(common.EventModified, "web-2", "3", 41))
+	l.Close()
+
+	l = openLog(t, Options{Dir: dir, SegmentSize: 1 << 20, MaxAge: time.Hour}, &now)
+	defer l.Close()
+
+	now = 45
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if got := replay(t, l, history.Query{}); !reflect.DeepEqual(got, []string{"web-1@1", "web-2@2", "web-2@3"}) {
+		t.Errorf("expected the duplicate to be dropped, got %v", got)
+	}
+
+	now = 70
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if got := replay(t, l, history.Query{}); !reflect.DeepEqual(got, []string{"web-2@2", "web-2@3"}) {
+		t.Errorf("expected the expired event to be dropped, got %v", got)
+	}
+
+	now = 120
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.segments) != 1 {
+		t.Errorf("expected only the active segment to be left, got %d segments", len(l.segments))
+	}
+}
+
+func TestCompactMaxSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	now := 10
+	l := openLog(t, Options{Dir: dir, SegmentSize: 1, MaxSize: 1}, &now)
+	defer l.Close()
+	for i, rv := range []string{"1", "2", "3"} {
+		l.Send(podEvent(common.EventModified, "web-1", rv, i))
+	}
+
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if got := replay(t, l, history.Query{}); !reflect.DeepEqual(got, []string{"web-1@3"}) {
+		t.Errorf("expected only the active segment to be kept, got %v", got)
+	}
+}
+
+func TestCompactRemovesEmptySegments(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// every restart starts a segment, most of them get no events
+	now := 0
+	for i := 0; i < 3; i++ {
+		l := openLog(t, Options{Dir: dir, MaxAge: time.Hour}, &now)
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := openLog(t, Options{Dir: dir, MaxAge: time.Hour}, &now)
+	if err := l.Send(podEvent(common.EventAdded, "web-1", "1", 0)); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	l = openLog(t, Options{Dir: dir, MaxAge: time.Hour}, &now)
+	defer l.Close()
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if len(names) != 2 || len(l.segments) != 2 {
+		t.Errorf("expected the segment of web-1 and the active segment to be left, got files %v and %d segments", names, len(l.segments))
+	}
+	if got := replay(t, l, history.Query{}); !reflect.DeepEqual(got, []string{"web-1@1"}) {
+		t.Errorf("expected the event of web-1, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ErrNoCredentials is returned when a call carries none of the credentials
+// the configured authenticators accept.
+var ErrNoCredentials = errors.New("no credentials provided")
+
+// Authenticator identifies the client of a call. ok is false if the call
+// carries no credentials of the kind the authenticator handles.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (id *Identity, ok bool, err error)
+}
+
+// AuthenticatorFunc ...
+type AuthenticatorFunc func(ctx context.Context) (*Identity, bool, error)
+
+// Authenticate ...
+func (f AuthenticatorFunc) Authenticate(ctx context.Context) (*Identity, bool, error) {
+	return f(ctx)
+}
+
+// Union tries each authenticator in turn and returns the first identity
+// found. It fails if none of them accepted the call.
+func Union(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (*Identity, bool, error) {
+		var errs []error
+		for _, a := range authenticators {
+			id, ok, err := a.Authenticate(ctx)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if ok {
+				return id, true, nil
+			}
+		}
+
+		if len(errs) == 0 {
+			return nil, false, ErrNoCredentials
+		}
+		return nil, false, utilerrors.NewAggregate(errs)
+	})
+}
+
+// CertificateAuthenticator identifies clients by their verified TLS client
+// certificate: the common name is the user and the organizations are the
+// groups, as for the Kubernetes API server.
+type CertificateAuthenticator struct{}
+
+// Authenticate ...
+func (CertificateAuthenticator) Authenticate(ctx context.Context) (*Identity, bool, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, false, nil
+	}
+
+	cert := info.State.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, false, errors.New("client certificate has no common name")
+	}
+
+	return &Identity{
+		User:   cert.Subject.CommonName,
+		Groups: cert.Subject.Organization,
+	}, true, nil
+}
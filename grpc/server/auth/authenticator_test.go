@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	authnv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// withCertificate returns ctx as a call over TLS with a verified client
+// certificate of subject.
+func withCertificate(ctx context.Context, subject pkix.Name) context.Context {
+	cert := &x509.Certificate{Subject: subject}
+	return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{cert}},
+	}}})
+}
+
+func TestCertificateAuthenticator(t *testing.T) {
+	id, ok, err := CertificateAuthenticator{}.Authenticate(withCertificate(context.Background(), pkix.Name{CommonName: "alice", Organization: []string{"dev"}}))
+	if err != nil || !ok || id.User != "alice" || len(id.Groups) != 1 || id.Groups[0] != "dev" {
+		t.Errorf("expected alice of dev, got %v, %v, %v", id, ok, err)
+	}
+
+	if _, ok, err := (CertificateAuthenticator{}).Authenticate(withCertificate(context.Background(), pkix.Name{})); ok || err == nil {
+		t.Errorf("expected a certificate without a common name to be rejected, got %v, %v", ok, err)
+	}
+	if _, ok, err := (CertificateAuthenticator{}).Authenticate(context.Background()); ok || err != nil {
+		t.Errorf("expected a call without a certificate to be left to other authenticators, got %v, %v", ok, err)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	static, err := NewStaticTokenAuthenticator(writeTokens(t, "static,bob,uid-2\nshared,carol,uid-3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientset := fake.NewSimpleClientset()
+	unavailable := false
+	reviews := tokenReviews(clientset, map[string]authnv1.UserInfo{
+		"reviewed": {Username: "dave"},
+		"shared":   {Username: "erin"},
+	}, &unavailable)
+
+	// in the order the server configures them
+	a := Union(CertificateAuthenticator{}, static, NewTokenReviewAuthenticator(clientset, nil))
+	for _, test := range []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"certificate first", withCertificate(withToken("Bearer static"), pkix.Name{CommonName: "alice"}), "alice"},
+		{"static token", withToken("Bearer static"), "bob"},
+		{"static before token review", withToken("Bearer shared"), "carol"},
+		{"token review", withToken("Bearer reviewed"), "dave"},
+	} {
+		id, ok, err := a.Authenticate(test.ctx)
+		if err != nil || !ok || id.User != test.expected {
+			t.Errorf("%s: expected %s, got %v, %v, %v", test.name, test.expected, id, ok, err)
+		}
+	}
+	if *reviews != 1 {
+		t.Errorf("expected only the token unknown to the static file to be reviewed, got %d reviews", *reviews)
+	}
+
+	if _, ok, err := a.Authenticate(context.Background()); ok || err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v, %v", ok, err)
+	}
+	if _, ok, err := a.Authenticate(withToken("Bearer unknown")); ok || err == nil {
+		t.Errorf("expected an unknown token to be rejected, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// allowedCacheTTL and deniedCacheTTL are how long SubjectAccessReview
+	// decisions are reused.
+	allowedCacheTTL = 5 * time.Minute
+	deniedCacheTTL  = 30 * time.Second
+)
+
+// Authorizer decides whether id may read the pods of namespace. An empty
+// namespace stands for all namespaces.
+type Authorizer interface {
+	Authorize(ctx context.Context, id *Identity, namespace string) error
+}
+
+// AlwaysAllow authorizes every call.
+type AlwaysAllow struct{}
+
+// Authorize ...
+func (AlwaysAllow) Authorize(context.Context, *Identity, string) error {
+	return nil
+}
+
+// SubjectAccessReviewAuthorizer allows clients that can list pods in the
+// namespace, as decided by a SubjectAccessReview.
+type SubjectAccessReviewAuthorizer struct {
+	client kubernetes.Interface
+	cache  *cache.LRUExpireCache
+}
+
+// NewSubjectAccessReviewAuthorizer ...
+func NewSubjectAccessReviewAuthorizer(client kubernetes.Interface) *SubjectAccessReviewAuthorizer {
+	return &SubjectAccessReviewAuthorizer{
+		client: client,
+		cache:  cache.NewLRUExpireCache(cacheSize),
+	}
+}
+
+// Authorize ...
+func (s *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, id *Identity, namespace string) error {
+	if id == nil {
+		return fmt.Errorf("anonymous clients are not authorized")
+	}
+
+	key := id.key() + "|" + namespace
+	if cached, ok := s.cache.Get(key); ok {
+		if cached == nil {
+			return nil
+		}
+		return cached.(error)
+	}
+
+	review := &authzv1.SubjectAccessReview{
+		Spec: authzv1.SubjectAccessReviewSpec{
+			User:   id.User,
+			UID:    id.UID,
+			Groups: id.Groups,
+			ResourceAttributes: &authzv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Resource:  "pods",
+			},
+		},
+	}
+	if len(id.Extra) > 0 {
+		review.Spec.Extra = make(map[string]authzv1.ExtraValue, len(id.Extra))
+		for k, v := range id.Extra {
+			review.Spec.Extra[k] = v
+		}
+	}
+
+	review, err := s.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		// the API server may be unavailable, do not cache the failure
+		return fmt.Errorf("subject access review failed: %v", err)
+	}
+
+	if review.Status.Allowed {
+		s.cache.Add(key, nil, allowedCacheTTL)
+		return nil
+	}
+
+	scope := fmt.Sprintf("namespace %q", namespace)
+	if namespace == "" {
+		scope = "all namespaces"
+	}
+	err = fmt.Errorf("%s cannot list pods in %s", id.User, scope)
+	if review.Status.Reason != "" {
+		err = fmt.Errorf("%v: %s", err, review.Status.Reason)
+	}
+
+	s.cache.Add(key, err, deniedCacheTTL)
+	return err
+}
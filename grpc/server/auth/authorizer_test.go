@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSubjectAccessReviewAuthorizer(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	unavailable := false
+	var specs []authzv1.SubjectAccessReviewSpec
+	clientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview).DeepCopy()
+		specs = append(specs, review.Spec)
+		if unavailable {
+			return true, nil, errors.New("connection refused")
+		}
+
+		// alice may list the pods of default only
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice" && attributes.Namespace == "default" && attributes.Verb == "list" && attributes.Resource == "pods"
+		if !review.Status.Allowed {
+			review.Status.Reason = "no RBAC policy matched"
+		}
+		return true, review, nil
+	})
+	a := NewSubjectAccessReviewAuthorizer(clientset)
+	alice := &Identity{User: "alice", UID: "uid-1", Groups: []string{"dev"}, Extra: map[string][]string{"scope": {"pods"}}}
+
+	for i := 0; i < 2; i++ {
+		if err := a.Authorize(context.Background(), alice, "default"); err != nil {
+			t.Errorf("expected alice to be allowed in default, got %v", err)
+		}
+		if err := a.Authorize(context.Background(), alice, "production"); err == nil || err.Error() != `alice cannot list pods in namespace "production": no RBAC policy matched` {
+			t.Errorf("expected alice to be denied in production, got %v", err)
+		}
+		if err := a.Authorize(context.Background(), alice, ""); err == nil || !strings.Contains(err.Error(), "alice cannot list pods in all namespaces") {
+			t.Errorf("expected alice to be denied in all namespaces, got %v", err)
+		}
+	}
+	if len(specs) != 3 {
+		t.Errorf("expected the decisions to be cached, got %d reviews", len(specs))
+	}
+	expected := authzv1.SubjectAccessReviewSpec{
+		User:               "alice",
+		UID:                "uid-1",
+		Groups:             []string{"dev"},
+		Extra:              map[string]authzv1.ExtraValue{"scope": {"pods"}},
+		ResourceAttributes: &authzv1.ResourceAttributes{Namespace: "default", Verb: "list", Resource: "pods"},
+	}
+	if len(specs) > 0 && !reflect.DeepEqual(specs[0], expected) {
+		t.Errorf("expected review %v, got %v", expected, specs[0])
+	}
+
+	// failed reviews are not cached
+	unavailable = true
+	bob := &Identity{User: "bob"}
+	for i := 0; i < 2; i++ {
+		if err := a.Authorize(context.Background(), bob, "default"); err == nil || !strings.Contains(err.Error(), "subject access review failed") {
+			t.Errorf("expected the review to fail, got %v", err)
+		}
+	}
+	if len(specs) != 5 {
+		t.Errorf("expected failed reviews to be retried, got %d reviews", len(specs))
+	}
+
+	if err := a.Authorize(context.Background(), nil, "default"); err == nil {
+		t.Error("expected anonymous clients to be denied")
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/peer"
+)
+
+// Identity is an authenticated client.
+type Identity struct {
+	User   string
+	UID    string
+	Groups []string
+	Extra  map[string][]string
+}
+
+// String ...
+func (id *Identity) String() string {
+	return id.User
+}
+
+// key identifies id in the decision cache.
+func (id *Identity) key() string {
+	groups := append([]string(nil), id.Groups...)
+	sort.Strings(groups)
+
+	extra := make([]string, 0, len(id.Extra))
+	for k, v := range id.Extra {
+		extra = append(extra, k+"="+strings.Join(v, ","))
+	}
+	sort.Strings(extra)
+
+	return strings.Join([]string{id.User, id.UID, strings.Join(groups, ","), strings.Join(extra, ";")}, "|")
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity of the client of ctx, if it was
+// authenticated.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// ClientName names the client of ctx by its identity, or by its address if
+// it was not authenticated.
+func ClientName(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return "user " + id.User
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "address " + host
+		}
+		return "address " + p.Addr.String()
+	}
+
+	return "unknown client"
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// namespaced is implemented by the requests that select a namespace.
+type namespaced interface {
+	GetNamespace() string
+}
+
+// Interceptors authenticate every call and authorize access to the
+// namespace of its request. A nil Authenticator lets every call through
+// unauthenticated.
+type Interceptors struct {
+	Authenticator Authenticator
+	Authorizer    Authorizer
+}
+
+// Unary returns the unary server interceptor.
+func (i *Interceptors) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := i.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the stream server interceptor. Requests are authorized as
+// the handler receives them.
+func (i *Interceptors) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx, interceptors: i, method: info.FullMethod})
+	}
+}
+
+func (i *Interceptors) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if i.Authenticator == nil {
+		return ctx, nil
+	}
+
+	id, ok, err := i.Authenticator.Authenticate(ctx)
+	if err != nil || !ok {
+		if err == nil {
+			err = ErrNoCredentials
+		}
+		klog.V(2).InfoS("Unauthenticated call", "method", method, "err", err)
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+
+	klog.V(4).InfoS("Authenticated call", "method", method, "user", id.User)
+	return NewContext(ctx, id), nil
+}
+
+func (i *Interceptors) authorize(ctx context.Context, method string, req interface{}) error {
+	if i.Authorizer == nil {
+		return nil
+	}
+
+	namespace := ""
+	if r, ok := req.(namespaced); ok {
+		namespace = r.GetNamespace()
+	}
+
+	id, _ := FromContext(ctx)
+	if err := i.Authorizer.Authorize(ctx, id, namespace); err != nil {
+		klog.V(2).InfoS("Denied call", "method", method, "client", ClientName(ctx), "namespace", namespace, "err", err)
+		return status.Errorf(codes.PermissionDenied, "%v", err)
+	}
+	return nil
+}
+
+// authorizedStream carries the identity of the client and authorizes every
+// request received on the stream.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx          context.Context
+	interceptors *Interceptors
+	method       string
+}
+
+// Context ...
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// RecvMsg ...
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.interceptors.authorize(s.ctx, s.method, m)
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	authnv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// authorizationHeader carries the bearer token of a call.
+	authorizationHeader = "authorization"
+
+	bearerPrefix = "bearer "
+
+	cacheSize = 1024
+
+	// tokenCacheTTL is how long TokenReview results are reused.
+	tokenCacheTTL = time.Minute
+)
+
+var errInvalidToken = errors.New("invalid bearer token")
+
+// bearerToken returns the bearer token of the call, if any.
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, value := range md.Get(authorizationHeader) {
+		if len(value) > len(bearerPrefix) && strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+			return strings.TrimSpace(value[len(bearerPrefix):]), true
+		}
+	}
+	return "", false
+}
+
+// TokenReviewAuthenticator validates bearer tokens with the Kubernetes
+// TokenReview API. Results are cached for a minute.
+type TokenReviewAuthenticator struct {
+	client    kubernetes.Interface
+	audiences []string
+	cache     *cache.LRUExpireCache
+}
+
+// NewTokenReviewAuthenticator reviews tokens for audiences, or for the API
+// server audience if none are given.
+func NewTokenReviewAuthenticator(client kubernetes.Interface, audiences []string) *TokenReviewAuthenticator {
+	return &TokenReviewAuthenticator{
+		client:    client,
+		audiences: audiences,
+		cache:     cache.NewLRUExpireCache(cacheSize),
+	}
+}
+
+type tokenReviewResult struct {
+	id  *Identity
+	err error
+}
+
+// Authenticate ...
+func (t *TokenReviewAuthenticator) Authenticate(ctx context.Context) (*Identity, bool, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if cached, ok := t.cache.Get(key); ok {
+		result := cached.(tokenReviewResult)
+		return result.id, result.err == nil, result.err
+	}
+
+	review, err := t.client.AuthenticationV1().TokenReviews().Create(ctx, &authnv1.TokenReview{
+		Spec: authnv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: t.audiences,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		// the API server may be unavailable, do not cache the failure
+		return nil, false, fmt.Errorf("token review failed: %v", err)
+	}
+
+	result := tokenReviewResult{}
+	if !review.Status.Authenticated {
+		result.err = errInvalidToken
+		if review.Status.Error != "" {
+			result.err = fmt.Errorf("invalid bearer token: %s", review.Status.Error)
+		}
+	} else {
+		user := review.Status.User
+		result.id = &Identity{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+		}
+		if len(user.Extra) > 0 {
+			result.id.Extra = make(map[string][]string, len(user.Extra))
+			for k, v := range user.Extra {
+				result.id.Extra[k] = v
+			}
+		}
+	}
+
+	t.cache.Add(key, result, tokenCacheTTL)
+	return result.id, result.err == nil, result.err
+}
+
+// StaticTokenAuthenticator accepts the bearer tokens of a fixed table.
+type StaticTokenAuthenticator struct {
+	tokens map[string]*Identity
+}
+
+// NewStaticTokenAuthenticator reads the tokens of path, a CSV file in the
+// format of the API server token file: token,user,uid,"group1,group2".
+// The groups column is optional.
+func NewStaticTokenAuthenticator(path string) (*StaticTokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	tokens := make(map[string]*Identity)
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		if len(record) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected token,user,uid[,groups]", path, line)
+		}
+		if record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("%s:%d: token and user must not be empty", path, line)
+		}
+		if _, ok := tokens[record[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate token", path, line)
+		}
+
+		id := &Identity{User: record[1], UID: record[2]}
+		if len(record) > 3 && record[3] != "" {
+			for _, group := range strings.Split(record[3], ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					id.Groups = append(id.Groups, group)
+				}
+			}
+		}
+		tokens[record[0]] = id
+	}
+
+	return &StaticTokenAuthenticator{tokens: tokens}, nil
+}
+
+// Authenticate ...
+func (s *StaticTokenAuthenticator) Authenticate(ctx context.Context) (*Identity, bool, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	id, ok := s.tokens[token]
+	if !ok {
+		return nil, false, errInvalidToken
+	}
+	return id, true, nil
+}
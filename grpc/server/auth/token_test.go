@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	authnv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// writeTokens writes a static token file in a temporary directory.
+func writeTokens(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "tokens.csv")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// withToken returns a context of a call carrying the authorization header
+// values.
+func withToken(values ...string) context.Context {
+	md := metadata.MD{}
+	for _, value := range values {
+		md.Append(authorizationHeader, value)
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestNewStaticTokenAuthenticator(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		data     string
+		expected map[string]*Identity
+		err      string
+	}{
+		{
+			name: "groups",
+			data: "secret,alice,uid-1,\"dev, ops\"\n",
+			expected: map[string]*Identity{
+				"secret": {User: "alice", UID: "uid-1", Groups: []string{"dev", "ops"}},
+			},
+		},
+		{
+			name: "optional groups",
+			data: "secret1,alice,uid-1\nsecret2, bob,,\n",
+			expected: map[string]*Identity{
+				"secret1": {User: "alice", UID: "uid-1"},
+				"secret2": {User: "bob"},
+			},
+		},
+		{name: "columns", data: "secret,alice\n", err: ":1: expected token,user,uid[,groups]"},
+		{name: "empty user", data: "secret1,alice,uid-1\nsecret2,,uid-2\n", err: ":2: token and user must not be empty"},
+		{name: "duplicate", data: "secret,alice,uid-1\nsecret,bob,uid-2\n", err: ":2: duplicate token"},
+		{name: "quotes", data: "secret,\"alice,uid-1\n", err: "extraneous or missing \" in quoted-field"},
+	} {
+		s, err := NewStaticTokenAuthenticator(writeTokens(t, test.data))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(s.tokens, test.expected) {
+			t.Errorf("%s: expected tokens %v, got %v", test.name, test.expected, s.tokens)
+		}
+	}
+
+	if _, err := NewStaticTokenAuthenticator("missing.csv"); err == nil {
+		t.Error("expected a missing token file to fail")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		ctx      context.Context
+		expected string
+		ok       bool
+	}{
+		{"no metadata", context.Background(), "", false},
+		{"no header", withToken(), "", false},
+		{"bearer", withToken("Bearer secret"), "secret", true},
+		{"case insensitive", withToken("bEaReR secret"), "secret", true},
+		{"spaces", withToken("Bearer  secret "), "secret", true},
+		{"basic", withToken("Basic c2VjcmV0"), "", false},
+		{"empty token", withToken("Bearer "), "", false},
+		{"first bearer", withToken("Basic c2VjcmV0", "Bearer secret"), "secret", true},
+	} {
+		token, ok := bearerToken(test.ctx)
+		if token != test.expected || ok != test.ok {
+			t.Errorf("%s: expected %q, %v, got %q, %v", test.name, test.expected, test.ok, token, ok)
+		}
+	}
+}
+
+func TestStaticTokenAuthenticator(t *testing.T) {
+	s, err := NewStaticTokenAuthenticator(writeTokens(t, "secret,alice,uid-1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok, err := s.Authenticate(withToken("Bearer secret")); err != nil || !ok || id.User != "alice" {
+		t.Errorf("expected alice, got %v, %v, %v", id, ok, err)
+	}
+	if _, ok, err := s.Authenticate(withToken("Bearer other")); ok || err != errInvalidToken {
+		t.Errorf("expected an invalid token, got %v, %v", ok, err)
+	}
+	if _, ok, err := s.Authenticate(context.Background()); ok || err != nil {
+		t.Errorf("expected a call without a token to be left to other authenticators, got %v, %v", ok, err)
+	}
+}
+
+// tokenReviews answers the TokenReviews of clientset from tokens, and fails
+// them if unavailable is set. It returns the number of reviews made.
+func tokenReviews(clientset *fake.Clientset, tokens map[string]authnv1.UserInfo, unavailable *bool) *int {
+	reviews := 0
+	clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		if *unavailable {
+			return true, nil, errors.New("connection refused")
+		}
+
+		review := action.(k8stesting.CreateAction).GetObject().(*authnv1.TokenReview).DeepCopy()
+		if user, ok := tokens[review.Spec.Token]; ok {
+			review.Status = authnv1.TokenReviewStatus{Authenticated: true, User: user}
+		} else {
+			review.Status = authnv1.TokenReviewStatus{Error: "token expired"}
+		}
+		return true, review, nil
+	})
+	return &reviews
+}
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	unavailable := false
+	reviews := tokenReviews(clientset, map[string]authnv1.UserInfo{
+		"valid": {Username: "system:serviceaccount:default:bot", UID: "uid-1", Groups: []string{"system:serviceaccounts"}, Extra: map[string]authnv1.ExtraValue{"scope": {"pods"}}},
+	}, &unavailable)
+	a := NewTokenReviewAuthenticator(clientset, []string{"dwserver"})
+
+	expected := &Identity{User: "system:serviceaccount:default:bot", UID: "uid-1", Groups: []string{"system:serviceaccounts"}, Extra: map[string][]string{"scope": {"pods"}}}
+	for i := 0; i < 2; i++ {
+		if id, ok, err := a.Authenticate(withToken("Bearer valid")); err != nil || !ok || !reflect.DeepEqual(id, expected) {
+			t.Errorf("expected %v, got %v, %v, %v", expected, id, ok, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok, err := a.Authenticate(withToken("Bearer expired")); ok || err == nil || !strings.Contains(err.Error(), "token expired") {
+			t.Errorf("expected the expired token to be rejected, got %v, %v", ok, err)
+		}
+	}
+	if *reviews != 2 {
+		t.Errorf("expected the valid and the rejected token to be reviewed once, got %d reviews", *reviews)
+	}
+
+	// failed reviews are not cached
+	unavailable = true
+	for i := 0; i < 2; i++ {
+		if _, ok, err := a.Authenticate(withToken("Bearer other")); ok || err == nil || !strings.Contains(err.Error(), "token review failed") {
+			t.Errorf("expected the review to fail, got %v, %v", ok, err)
+		}
+	}
+	if *reviews != 4 {
+		t.Errorf("expected failed reviews to be retried, got %d reviews", *reviews)
+	}
+
+	if _, ok, err := a.Authenticate(context.Background()); ok || err != nil || *reviews != 4 {
+		t.Errorf("expected a call without a token not to be reviewed, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,138 @@
+package limit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/grpc/server/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxClients bounds the number of clients whose rate limiters are kept.
+	maxClients = 10000
+
+	// limiterTTL is how long the rate limiter of an idle client is kept.
+	limiterTTL = 10 * time.Minute
+)
+
+// Options ...
+type Options struct {
+	// MaxStreamsPerClient limits the concurrent streams of a client. Zero
+	// means no limit.
+	MaxStreamsPerClient int
+
+	// UnaryQPS and UnaryBurst rate limit the unary calls of a client. Zero
+	// QPS means no limit.
+	UnaryQPS   float64
+	UnaryBurst int
+}
+
+// Limiter enforces per client limits on gRPC calls. Clients are told apart
+// by their authenticated identity, or by their address if they are not
+// authenticated.
+type Limiter struct {
+	lock     sync.Mutex
+	opts     Options
+	streams  map[string]int
+	limiters *cache.LRUExpireCache
+}
+
+// New ...
+func New(opts Options) *Limiter {
+	return &Limiter{
+		opts:     opts,
+		streams:  make(map[string]int),
+		limiters: cache.NewLRUExpireCache(maxClients),
+	}
+}
+
+// SetOptions changes the limits. Streams open already are kept even if they
+// exceed the new limit.
+func (l *Limiter) SetOptions(opts Options) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if opts.UnaryQPS != l.opts.UnaryQPS || opts.UnaryBurst != l.opts.UnaryBurst {
+		l.limiters = cache.NewLRUExpireCache(maxClients)
+	}
+	l.opts = opts
+}
+
+// Unary returns the unary server interceptor.
+func (l *Limiter) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := auth.ClientName(ctx)
+		if err := l.allow(client); err != nil {
+			klog.V(2).InfoS("Rejected call", "method", info.FullMethod, "client", client, "err", err)
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the stream server interceptor.
+func (l *Limiter) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		client := auth.ClientName(ss.Context())
+		if err := l.openStream(client); err != nil {
+			klog.V(2).InfoS("Rejected call", "method", info.FullMethod, "client", client, "err", err)
+			return err
+		}
+		defer l.closeStream(client)
+
+		return handler(srv, ss)
+	}
+}
+
+func (l *Limiter) allow(client string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.opts.UnaryQPS <= 0 {
+		return nil
+	}
+
+	var limiter *rate.Limiter
+	if cached, ok := l.limiters.Get(client); ok {
+		limiter = cached.(*rate.Limiter)
+	} else {
+		limiter = rate.NewLimiter(rate.Limit(l.opts.UnaryQPS), l.opts.UnaryBurst)
+	}
+	// refresh the expiry of the limiter
+	l.limiters.Add(client, limiter, limiterTTL)
+
+	if !limiter.Allow() {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s: server.unaryQPS limit of %v calls per second, burst %d",
+			client, l.opts.UnaryQPS, l.opts.UnaryBurst)
+	}
+	return nil
+}
+
+func (l *Limiter) openStream(client string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if max := l.opts.MaxStreamsPerClient; max > 0 && l.streams[client] >= max {
+		return status.Errorf(codes.ResourceExhausted, "too many streams for %s: server.maxStreamsPerClient limit of %d concurrent streams reached",
+			client, max)
+	}
+	l.streams[client]++
+	return nil
+}
+
+func (l *Limiter) closeStream(client string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.streams[client]--; l.streams[client] <= 0 {
+		delete(l.streams, client)
+	}
+}
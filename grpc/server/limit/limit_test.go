@@ -0,0 +1,127 @@
+package limit
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fromAddress returns the context of a call from the client at address.
+func fromAddress(address string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(address), Port: 40000}})
+}
+
+// serverStream is a stream of a call from a client.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// openStream opens a stream from the client at address through l. The
+// stream is served until the returned function is called, which returns the
+// error of the call.
+func openStream(l *Limiter, address string) (end func() error) {
+	serving := make(chan struct{})
+	done := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- l.Stream()(nil, &serverStream{ctx: fromAddress(address)}, &grpc.StreamServerInfo{FullMethod: "/podstat.PodStatIntf/ListenPodStatus"},
+			func(interface{}, grpc.ServerStream) error {
+				close(serving)
+				<-done
+				return nil
+			})
+	}()
+
+	select {
+	case <-serving:
+		return func() error {
+			close(done)
+			return <-errc
+		}
+	case err := <-errc:
+		return func() error { return err }
+	}
+}
+
+func TestMaxStreamsPerClient(t *testing.T) {
+	l := New(Options{MaxStreamsPerClient: 2})
+
+	var ends []func() error
+	for i := 0; i < 2; i++ {
+		ends = append(ends, openStream(l, "10.0.0.1"))
+	}
+	err := openStream(l, "10.0.0.1")()
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the third stream to be rejected, got %v", err)
+	}
+	if expected := "too many streams for address 10.0.0.1: server.maxStreamsPerClient limit of 2 concurrent streams reached"; status.Convert(err).Message() != expected {
+		t.Errorf("expected %q, got %q", expected, status.Convert(err).Message())
+	}
+
+	// other clients have their own streams
+	if err := openStream(l, "10.0.0.2")(); err != nil {
+		t.Errorf("expected another client to open a stream, got %v", err)
+	}
+
+	// the slot is released when a stream ends
+	if err := ends[0](); err != nil {
+		t.Fatal(err)
+	}
+	end := openStream(l, "10.0.0.1")
+	if err := end(); err != nil {
+		t.Errorf("expected a stream to be opened once another ended, got %v", err)
+	}
+	if err := ends[1](); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.streams) != 0 {
+		t.Errorf("expected no streams left, got %v", l.streams)
+	}
+}
+
+func TestUnaryRateLimit(t *testing.T) {
+	l := New(Options{UnaryQPS: 0.001, UnaryBurst: 2})
+	call := func(address string) error {
+		_, err := l.Unary()(fromAddress(address), nil, &grpc.UnaryServerInfo{FullMethod: "/podstat.PodStatIntf/QueryEvents"},
+			func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := call("10.0.0.1"); err != nil {
+			t.Fatalf("expected call %d within the burst, got %v", i, err)
+		}
+	}
+	err := call("10.0.0.1")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the call beyond the burst to be rejected, got %v", err)
+	}
+	if expected := "rate limit exceeded for address 10.0.0.1: server.unaryQPS limit of 0.001 calls per second, burst 2"; status.Convert(err).Message() != expected {
+		t.Errorf("expected %q, got %q", expected, status.Convert(err).Message())
+	}
+	if err := call("10.0.0.2"); err != nil {
+		t.Errorf("expected another client to be allowed, got %v", err)
+	}
+
+	// new limits start with a full burst
+	l.SetOptions(Options{UnaryQPS: 0.001, UnaryBurst: 3})
+	if err := call("10.0.0.1"); err != nil {
+		t.Errorf("expected the new limits to apply, got %v", err)
+	}
+	l.SetOptions(Options{})
+	for i := 0; i < 5; i++ {
+		if err := call("10.0.0.1"); err != nil {
+			t.Fatalf("expected no limit, got %v", err)
+		}
+	}
+}
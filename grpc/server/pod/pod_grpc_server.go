@@ -2,42 +2,119 @@ package podserver
 
 import (
 	"context"
-	"log"
+	"errors"
+	"time"
 
 	pc "github.com/bobbybho/k8s-deployment-watcher/controller"
+	"github.com/bobbybho/k8s-deployment-watcher/eventlog"
+	"github.com/bobbybho/k8s-deployment-watcher/grpc/server/auth"
+	"github.com/bobbybho/k8s-deployment-watcher/history"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
 	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+	"github.com/bobbybho/k8s-deployment-watcher/timeline"
+	"github.com/bobbybho/k8s-deployment-watcher/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
 )
 
 // PodServer ...
 type PodServer struct {
 	pb.UnimplementedPodStatIntfServer
 	PodController *pc.PodController
+
+	// History serves QueryEvents, which is unavailable if it is nil.
+	History *history.Store
+
+	// EventLog serves ReplayEvents, which is unavailable if it is nil.
+	EventLog *eventlog.Log
+
+	// Timelines serves GetPodTimeline, which is unavailable if it is nil.
+	Timelines *timeline.Tracker
 }
 
+// ShutdownMessage is the message of the last reply sent on a
+// ListenPodStatus stream when the server shuts down.
+const ShutdownMessage = "server shutting down"
+
+// SubscriptionIDHeader is the response header of ListenPodStatus holding
+// the ID of the subscription. The server assigns one if the request has no
+// client ID.
+const SubscriptionIDHeader = "subscription-id"
+
 // ListenPodStatus ...
 func (p *PodServer) ListenPodStatus(r *pb.PodStatRequest, stream pb.PodStatIntf_ListenPodStatusServer) error {
 	clientID := r.GetClientid()
+	if clientID == "" {
+		clientID = string(uuid.NewUUID())
+	}
+
+	ch, err := p.PodController.Subscribe(clientID, pc.SubscribeOptions{
+		Namespace:   r.GetNamespace(),
+		Client:      auth.ClientName(stream.Context()),
+		Changes:     r.GetIncludeChanges(),
+		CloudEvents: r.GetCloudevents(),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, pc.ErrTooManySubscribers):
+		return status.Errorf(codes.ResourceExhausted, "%v", err)
+	case errors.Is(err, pc.ErrDuplicateSubscription):
+		return status.Errorf(codes.AlreadyExists, "%v", err)
+	case errors.Is(err, pc.ErrShuttingDown):
+		return status.Errorf(codes.Unavailable, "%v", err)
+	default:
+		return err
+	}
 
-	ch := p.PodController.OpenChannel(clientID)
-
-	if ch != nil {
-		for {
-			select {
-			case <-stream.Context().Done():
-				log.Printf("stream.Context.Done(): clientID: %v\n", clientID)
-			case msg := <-ch:
-				if err := stream.Send(&msg); err != nil {
-					p.PodController.CloseChannel(clientID)
-					log.Printf("Failed to send podstat err=%v\n", err.Error())
-					return err
-				}
+	if err := stream.SendHeader(metadata.Pairs(SubscriptionIDHeader, clientID)); err != nil {
+		p.PodController.CloseChannel(clientID)
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			klog.V(2).InfoS("Stream closed by the client", "subscriber", clientID, "err", stream.Context().Err())
+			p.PodController.CloseChannel(clientID)
+			return stream.Context().Err()
+		case update, ok := <-ch:
+			if !ok {
+				// the controller is shutting down, tell the client before
+				// ending the stream.
+				return stream.Send(&pb.PodStatReply{Message: ShutdownMessage})
+			}
+			if err := send(stream, clientID, update); err != nil {
+				p.PodController.CloseChannel(clientID)
+				klog.ErrorS(err, "Failed to send pod status", "subscriber", clientID, "event", update.Reply.GetMessage(), "pod", update.Reply.GetPodstat().GetPodname())
+				return err
 			}
 		}
-	} else {
-		log.Printf("podstat channel is empty\n")
 	}
+}
 
-	return nil
+// send sends the reply of update on stream. The send is traced as a child
+// of the span that processed the event, linked to the span of the stream,
+// from the time the reply was queued for the subscriber.
+func send(stream pb.PodStatIntf_ListenPodStatusServer, clientID string, update pc.Update) error {
+	ctx := trace.ContextWithSpanContext(context.Background(), update.SpanContext)
+	_, span := tracing.Tracer().Start(ctx, tracing.SpanSend,
+		trace.WithTimestamp(update.Queued),
+		trace.WithLinks(trace.LinkFromContext(stream.Context())),
+		trace.WithAttributes(tracing.KeySubscriber.String(clientID)))
+	span.AddEvent("dequeued")
+
+	start := time.Now()
+	err := stream.Send(update.Reply)
+	metrics.SubscriberSendDuration.Observe(time.Since(start).Seconds())
+
+	tracing.EndSpan(span, err)
+	return err
 }
 
 // GetAllPodStatus ...
@@ -46,8 +123,161 @@ func (p *PodServer) GetAllPodStatus(r *pb.PodStatRequest, stream pb.PodStatIntf_
 	return nil
 }
 
-//GetPodStatusByName ...
+// GetPodStatusByName ...
 func (p *PodServer) GetPodStatusByName(ctx context.Context, r *pb.PodStatRequest) (*pb.PodStatReply, error) {
 	// TODO: implement this function
 	return &pb.PodStatReply{}, nil
 }
+
+// QueryEvents returns the events of the history selected by r, the oldest
+// first.
+func (p *PodServer) QueryEvents(ctx context.Context, r *pb.QueryEventsRequest) (*pb.QueryEventsReply, error) {
+	if p.History == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the event history is disabled")
+	}
+	q, err := query(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, truncated := p.History.Query(q)
+	reply := &pb.QueryEventsReply{Events: make([]*pb.Event, 0, len(entries)), Truncated: truncated}
+	for _, e := range entries {
+		reply.Events = append(reply.Events, event(e))
+	}
+	return reply, nil
+}
+
+// ReplayEvents streams the events of the event log selected by r, in the
+// order they were recorded.
+func (p *PodServer) ReplayEvents(r *pb.QueryEventsRequest, stream pb.PodStatIntf_ReplayEventsServer) error {
+	if p.EventLog == nil {
+		return status.Error(codes.FailedPrecondition, "the event log is disabled")
+	}
+	q, err := query(r)
+	if err != nil {
+		return err
+	}
+
+	return p.EventLog.Replay(q, func(e history.Entry) error {
+		if err := stream.Context().Err(); err != nil {
+			return err
+		}
+		return stream.Send(event(e))
+	})
+}
+
+// query returns the history query of r.
+func query(r *pb.QueryEventsRequest) (history.Query, error) {
+	if r.GetLimit() < 0 {
+		return history.Query{}, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	q := history.Query{
+		Namespace:  r.GetNamespace(),
+		Deployment: r.GetDeployment(),
+		Pod:        r.GetPod(),
+		Resource:   r.GetResource(),
+		Types:      r.GetTypes(),
+		Limit:      int(r.GetLimit()),
+	}
+	if r.GetSince() != nil {
+		q.Since = r.GetSince().AsTime()
+	}
+	if r.GetUntil() != nil {
+		q.Until = r.GetUntil().AsTime()
+	}
+	return q, nil
+}
+
+// event returns the reply of e.
+func event(e history.Entry) *pb.Event {
+	return &pb.Event{
+		Time:            timestamppb.New(e.Time),
+		Type:            e.Type,
+		Resource:        e.Resource,
+		Namespace:       e.Namespace,
+		Name:            e.Name,
+		Deployment:      e.Deployment,
+		ResourceVersion: e.ResourceVersion,
+		Podstat:         e.Podstat,
+		Anomaly:         e.Anomaly,
+	}
+}
+
+// GetPodTimeline returns the lifecycle timelines of the pods selected by r
+// and the percentiles of their latencies per deployment and per zone.
+func (p *PodServer) GetPodTimeline(ctx context.Context, r *pb.PodTimelineRequest) (*pb.PodTimelineReply, error) {
+	if p.Timelines == nil {
+		return nil, status.Error(codes.FailedPrecondition, "pod timelines are disabled")
+	}
+
+	timelines := p.Timelines.Timelines(timeline.Query{
+		Namespace:  r.GetNamespace(),
+		Deployment: r.GetDeployment(),
+		Pod:        r.GetPod(),
+	})
+	if r.GetPod() != "" && len(timelines) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no timeline of pod %s", r.GetPod())
+	}
+
+	reply := &pb.PodTimelineReply{Timelines: make([]*pb.PodTimeline, 0, len(timelines))}
+	for _, t := range timelines {
+		reply.Timelines = append(reply.Timelines, podTimeline(t))
+	}
+
+	deployments, zones := timeline.Summarize(timelines)
+	for _, s := range deployments {
+		reply.Deployments = append(reply.Deployments, latencySummary(s))
+	}
+	for _, s := range zones {
+		reply.Zones = append(reply.Zones, latencySummary(s))
+	}
+	return reply, nil
+}
+
+// podTimeline returns the reply of t.
+func podTimeline(t timeline.Timeline) *pb.PodTimeline {
+	reply := &pb.PodTimeline{
+		Namespace:         t.Namespace,
+		Name:              t.Name,
+		Deployment:        t.Deployment,
+		Node:              t.Node,
+		Zone:              t.Zone,
+		SchedulingLatency: durationpb.New(t.SchedulingLatency()),
+		StartupLatency:    durationpb.New(t.StartupLatency()),
+		TimeToReady:       durationpb.New(t.TimeToReady()),
+	}
+	for _, step := range timeline.Steps {
+		s := &pb.TimelineStep{Name: step}
+		if at := t.Step(step); !at.IsZero() {
+			s.Time = timestamppb.New(at)
+		}
+		reply.Steps = append(reply.Steps, s)
+	}
+	if !t.Deleted.IsZero() {
+		reply.Deleted = timestamppb.New(t.Deleted)
+	}
+	return reply
+}
+
+// latencySummary returns the reply of s.
+func latencySummary(s timeline.Summary) *pb.LatencySummary {
+	stats := func(s timeline.Stats) *pb.LatencyStats {
+		return &pb.LatencyStats{
+			Count: int32(s.Count),
+			P50:   durationpb.New(s.P50),
+			P90:   durationpb.New(s.P90),
+			P99:   durationpb.New(s.P99),
+			Max:   durationpb.New(s.Max),
+		}
+	}
+	return &pb.LatencySummary{
+		Namespace:   s.Namespace,
+		Deployment:  s.Deployment,
+		Zone:        s.Zone,
+		Scheduling:  stats(s.Scheduling),
+		Startup:     stats(s.Startup),
+		TimeToReady: stats(s.TimeToReady),
+	}
+}
@@ -0,0 +1,290 @@
+// Package history keeps the recent events of every pod and deployment in
+// memory, so that what happened to them can be queried after the fact.
+package history
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/controller"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+const (
+	// DefaultMaxEvents is the number of events kept per object.
+	DefaultMaxEvents = 100
+
+	// DefaultMaxAge is how long events are kept.
+	DefaultMaxAge = time.Hour
+)
+
+// pruneInterval is how often the events older than the retention are
+// dropped from the histories of every object, including deleted ones.
+const pruneInterval = time.Minute
+
+// Options describes the retention of a Store.
+type Options struct {
+	// MaxEvents is the number of events kept per pod and per deployment.
+	// Zero uses DefaultMaxEvents.
+	MaxEvents int
+
+	// MaxAge is how long events are kept. Zero uses DefaultMaxAge.
+	MaxAge time.Duration
+}
+
+// Entry is an event of the history.
+type Entry struct {
+	Time       time.Time
+	Type       string
+	Resource   string
+	Namespace  string
+	Name       string
+	Deployment string
+
+	ResourceVersion string
+
+	// Podstat is the state of the pod of pod events. Only the state
+	// QueryEvents returns is kept, not the whole object.
+	Podstat *pb.PodStat
+
+	// Anomaly describes the anomaly of anomaly events.
+	Anomaly *pb.Anomaly
+}
+
+// SetObject keeps the state of obj, the object of the event of e, that
+// QueryEvents returns: the state of pods and the description of anomalies.
+// Nothing is kept of other objects.
+func (e *Entry) SetObject(obj interface{}) {
+	switch obj := obj.(type) {
+	case *v1.Pod:
+		e.Podstat = controller.PodStat(obj)
+	case *anomaly.Anomaly:
+		e.Anomaly = &pb.Anomaly{
+			Type:         obj.Type,
+			Container:    obj.Container,
+			Reason:       obj.Reason,
+			Message:      obj.Message,
+			ExitCode:     obj.ExitCode,
+			RestartCount: obj.RestartCount,
+			Restarts:     obj.Restarts,
+		}
+	}
+}
+
+// key identifies the object of e.
+func (e Entry) key() string {
+	return e.Resource + "/" + e.Namespace + "/" + e.Name
+}
+
+// ring holds the latest events of an object, the oldest at start once it is
+// full.
+type ring struct {
+	entries []Entry
+	start   int
+}
+
+func (r *ring) add(e Entry, max int) {
+	if len(r.entries) < max {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.start] = e
+	r.start = (r.start + 1) % len(r.entries)
+}
+
+// each calls f with the events of r, the oldest first.
+func (r *ring) each(f func(Entry)) {
+	for i := range r.entries {
+		f(r.entries[(r.start+i)%len(r.entries)])
+	}
+}
+
+// prune drops the events older than cutoff and reports whether events are
+// left.
+func (r *ring) prune(cutoff time.Time) bool {
+	kept := make([]Entry, 0, len(r.entries))
+	r.each(func(e Entry) {
+		if !e.Time.Before(cutoff) {
+			kept = append(kept, e)
+		}
+	})
+	if len(kept) < len(r.entries) {
+		r.entries, r.start = kept, 0
+	}
+	return len(r.entries) > 0
+}
+
+// Store is an EventSink keeping the history of the objects of the events it
+// is sent.
+type Store struct {
+	opts Options
+
+	lock      sync.RWMutex
+	rings     map[string]*ring
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+// New returns an empty Store.
+func New(opts Options) *Store {
+	if opts.MaxEvents == 0 {
+		opts.MaxEvents = DefaultMaxEvents
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	return &Store{opts: opts, rings: map[string]*ring{}, now: time.Now}
+}
+
+// Name implements watcher.EventSink.
+func (s *Store) Name() string { return "history" }
+
+// Send records e in the history of its object.
+func (s *Store) Send(e common.Event) error {
+	s.Add(NewEntry(e))
+	return nil
+}
+
+// Close implements watcher.EventSink, the history stays queryable.
+func (s *Store) Close() error { return nil }
+
+// NewEntry returns the entry of e.
+func NewEntry(e common.Event) Entry {
+	entry := Entry{
+		Time:       e.Time,
+		Type:       e.EventType,
+		Resource:   e.ResourceType,
+		Deployment: e.Deployment(),
+	}
+	entry.SetObject(e.Object)
+	if obj, err := meta.Accessor(e.Object); err == nil {
+		entry.Namespace = obj.GetNamespace()
+		entry.Name = obj.GetName()
+		entry.ResourceVersion = obj.GetResourceVersion()
+	}
+	return entry
+}
+
+// Add records e in the history of its object.
+func (s *Store) Add(e Entry) {
+	now := s.now()
+	if e.Time.IsZero() {
+		e.Time = now
+	}
+	key := e.key()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r, ok := s.rings[key]
+	if !ok {
+		r = &ring{}
+		s.rings[key] = r
+	}
+	r.add(e, s.opts.MaxEvents)
+
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		s.prune(now)
+	}
+}
+
+// prune drops the expired events, and the histories left empty.
+func (s *Store) prune(now time.Time) {
+	cutoff := now.Add(-s.opts.MaxAge)
+	for key, r := range s.rings {
+		if !r.prune(cutoff) {
+			delete(s.rings, key)
+		}
+	}
+	s.lastPrune = now
+}
+
+// Query selects events of the history. Empty fields select every event.
+type Query struct {
+	// Since and Until bound the time of the events, both inclusive.
+	Since time.Time
+	Until time.Time
+
+	Namespace  string
+	Deployment string
+
+	// Pod selects the events and the anomalies of a pod.
+	Pod string
+
+	// Resource is common.ResourcePod, common.ResourceDeployment or
+	// common.ResourceAnomaly.
+	Resource string
+
+	// Types are the event types selected.
+	Types []string
+
+	// Limit is the number of events returned, zero returns every event.
+	Limit int
+}
+
+// Matches reports whether q selects e, regardless of its Limit.
+func (q Query) Matches(e Entry) bool {
+	switch {
+	case !q.Since.IsZero() && e.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && e.Time.After(q.Until):
+		return false
+	case q.Namespace != "" && e.Namespace != q.Namespace:
+		return false
+	case q.Deployment != "" && e.Deployment != q.Deployment:
+		return false
+	case q.Pod != "" && (e.Resource == common.ResourceDeployment || e.Name != q.Pod):
+		return false
+	case q.Resource != "" && e.Resource != q.Resource:
+		return false
+	}
+
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if strings.EqualFold(t, e.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the events selected by q, the oldest first, and whether
+// more events than q.Limit were selected. Expired events are left out.
+func (s *Store) Query(q Query) ([]Entry, bool) {
+	cutoff := s.now().Add(-s.opts.MaxAge)
+
+	var entries []Entry
+	s.lock.RLock()
+	for _, r := range s.rings {
+		r.each(func(e Entry) {
+			if !e.Time.Before(cutoff) && q.Matches(e) {
+				entries = append(entries, e)
+			}
+		})
+	}
+	s.lock.RUnlock()
+
+	// the events of an object stay in the order they were added
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return a.key() < b.key()
+	})
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		return entries[:q.Limit], true
+	}
+	return entries, false
+}
@@ -0,0 +1,160 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var start = time.Date(2022, 3, 1, 2, 0, 0, 0, time.UTC)
+
+func pod(name, rv string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		ResourceVersion: rv,
+		Labels:          map[string]string{appv1.DefaultDeploymentUniqueLabelKey: "5d4f8c"},
+		OwnerReferences: []metav1.OwnerReference{{
+			Kind:       "ReplicaSet",
+			Name:       "web-5d4f8c",
+			Controller: func() *bool { b := true; return &b }(),
+		}},
+	}}
+}
+
+func event(eventType string, obj interface{}, minutes int) common.Event {
+	resource := common.ResourcePod
+	if _, ok := obj.(*appv1.Deployment); ok {
+		resource = common.ResourceDeployment
+	}
+	return common.Event{
+		EventType:    eventType,
+		ResourceType: resource,
+		Object:       obj,
+		Time:         start.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+// newStore returns a store whose clock is at minutes past start.
+func newStore(opts Options, minutes *int) *Store {
+	s := New(opts)
+	s.now = func() time.Time { return start.Add(time.Duration(*minutes) * time.Minute) }
+	return s
+}
+
+func names(entries []Entry) []string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Resource+":"+e.Name+"@"+e.ResourceVersion)
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQuery(t *testing.T) {
+	now := 30
+	s := newStore(Options{}, &now)
+
+	deployment := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", ResourceVersion: "1"}}
+	standalone := pod("debug", "9")
+	standalone.OwnerReferences = nil
+	other := pod("api-1", "7")
+	other.Namespace = "other"
+	other.OwnerReferences[0].Name = "api-5d4f8c"
+
+	for _, e := range []common.Event{
+		event(common.EventAdded, deployment, 0),
+		event(common.EventAdded, pod("web-1", "2"), 1),
+		event(common.EventModified, pod("web-1", "3"), 5),
+		event(common.EventAdded, standalone, 6),
+		event(common.EventAdded, other, 7),
+		event(common.EventDeleted, pod("web-1", "4"), 15),
+	} {
+		if err := s.Send(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name      string
+		q         Query
+		want      []string
+		truncated bool
+	}{
+		{"all", Query{}, []string{"deployment:web@1", "pod:web-1@2", "pod:web-1@3", "pod:debug@9", "pod:api-1@7", "pod:web-1@4"}, false},
+		{"time range", Query{Since: start.Add(5 * time.Minute), Until: start.Add(6 * time.Minute)}, []string{"pod:web-1@3", "pod:debug@9"}, false},
+		{"namespace", Query{Namespace: "other"}, []string{"pod:api-1@7"}, false},
+		{"deployment", Query{Deployment: "web"}, []string{"deployment:web@1", "pod:web-1@2", "pod:web-1@3", "pod:web-1@4"}, false},
+		{"pod", Query{Pod: "web-1", Types: []string{"deleted"}}, []string{"pod:web-1@4"}, false},
+		{"resource", Query{Resource: common.ResourceDeployment}, []string{"deployment:web@1"}, false},
+		{"limit", Query{Limit: 2}, []string{"deployment:web@1", "pod:web-1@2"}, true},
+	} {
+		got, truncated := s.Query(test.q)
+		if !equal(names(got), test.want) || truncated != test.truncated {
+			t.Errorf("%s: expected %v (truncated %v), got %v (truncated %v)", test.name, test.want, test.truncated, names(got), truncated)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	now := 0
+	s := newStore(Options{MaxEvents: 2, MaxAge: 10 * time.Minute}, &now)
+
+	for i, rv := range []string{"1", "2", "3"} {
+		s.Send(event(common.EventModified, pod("web-1", rv), i))
+	}
+	if got := names(first(s.Query(Query{}))); !equal(got, []string{"pod:web-1@2", "pod:web-1@3"}) {
+		t.Errorf("expected the latest 2 events, got %v", got)
+	}
+
+	now = 12
+	if got := names(first(s.Query(Query{}))); !equal(got, []string{"pod:web-1@3"}) {
+		t.Errorf("expected the events older than 10m to expire, got %v", got)
+	}
+
+	// a later event prunes the history of the deleted pod
+	now = 20
+	s.Send(event(common.EventAdded, pod("web-2", "5"), now))
+	if len(s.rings) != 1 {
+		t.Errorf("expected the expired history to be dropped, got %d histories", len(s.rings))
+	}
+}
+
+func first(entries []Entry, _ bool) []Entry { return entries }
+
+func TestNewEntryKeepsTheQueriedState(t *testing.T) {
+	p := pod("web-1", "2")
+	p.Spec.NodeName = "node-a"
+	p.Status.Phase = v1.PodRunning
+	entry := NewEntry(event(common.EventModified, p, 0))
+	if entry.Podstat.GetPodname() != "web-1" || entry.Podstat.GetNodename() != "node-a" || entry.Podstat.GetPodstate() != string(v1.PodRunning) || entry.Anomaly != nil {
+		t.Errorf("expected the state of web-1, got %+v", entry)
+	}
+
+	a := &anomaly.Anomaly{ObjectMeta: p.ObjectMeta, Type: anomaly.TypeOOMKilled, Container: "app", ExitCode: 137}
+	entry = NewEntry(common.Event{EventType: a.Type, ResourceType: common.ResourceAnomaly, Object: a})
+	if entry.Anomaly.GetType() != anomaly.TypeOOMKilled || entry.Anomaly.GetContainer() != "app" || entry.Anomaly.GetExitCode() != 137 || entry.Podstat != nil {
+		t.Errorf("expected the anomaly of web-1, got %+v", entry)
+	}
+
+	deployment := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", ResourceVersion: "1"}}
+	if entry = NewEntry(event(common.EventModified, deployment, 0)); entry.Podstat != nil || entry.Anomaly != nil || entry.Name != "web" {
+		t.Errorf("expected only the metadata of the deployment, got %+v", entry)
+	}
+}
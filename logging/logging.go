@@ -0,0 +1,119 @@
+// Package logging configures the klog logger shared by the dwserver and dwcl
+// commands.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+)
+
+// Formats of the logs.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// maxVerbosity lets every V level through the JSON logger. The klog
+// verbosity decides what is logged.
+const maxVerbosity = 127
+
+var klogFlags = flag.NewFlagSet("klog", flag.ExitOnError)
+
+// current is the format the logger was configured with. klog does not guard
+// its logger against concurrent use, so it is only replaced when the format
+// changes.
+var current string
+
+func init() {
+	klog.InitFlags(klogFlags)
+}
+
+// SetLevel sets the klog verbosity.
+func SetLevel(level int) error {
+	return klogFlags.Set("v", strconv.Itoa(level))
+}
+
+// Configure sets the verbosity and the format of the logs. Text logs are
+// written in the klog format, JSON logs one object per line with the
+// structured values of the log call as keys.
+func Configure(level int, format string) error {
+	if err := SetLevel(level); err != nil {
+		return err
+	}
+
+	if format == "" {
+		format = FormatText
+	}
+	if format == current {
+		return nil
+	}
+
+	switch format {
+	case FormatText:
+		klog.ClearLogger()
+	case FormatJSON:
+		json := funcr.NewJSON(func(obj string) {
+			fmt.Fprintln(os.Stderr, obj)
+		}, funcr.Options{
+			LogCaller:    funcr.All,
+			LogTimestamp: true,
+			Verbosity:    maxVerbosity,
+		})
+		// skip the frame of trimSink when looking up the caller
+		klog.SetLogger(logr.New(trimSink{json.WithCallDepth(1).GetSink()}))
+	default:
+		return fmt.Errorf("unsupported log format %q", format)
+	}
+
+	current = format
+	return nil
+}
+
+// trimSink drops the newline klog appends to the messages of its printf
+// style functions, which would otherwise end up in the JSON messages.
+type trimSink struct {
+	sink logr.LogSink
+}
+
+// Init does nothing, the wrapped sink is initialized already.
+func (s trimSink) Init(info logr.RuntimeInfo) {}
+
+// Enabled ...
+func (s trimSink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+// Info ...
+func (s trimSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+// Error ...
+func (s trimSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+// WithValues ...
+func (s trimSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return trimSink{s.sink.WithValues(keysAndValues...)}
+}
+
+// WithName ...
+func (s trimSink) WithName(name string) logr.LogSink {
+	return trimSink{s.sink.WithName(name)}
+}
+
+// WithCallDepth ...
+func (s trimSink) WithCallDepth(depth int) logr.LogSink {
+	if sink, ok := s.sink.(logr.CallDepthLogSink); ok {
+		return trimSink{sink.WithCallDepth(depth)}
+	}
+	return s
+}
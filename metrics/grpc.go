@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC server metrics follow the names of go-grpc-prometheus, so that
+// existing dashboards work.
+var (
+	grpcStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_started_total",
+		Help: "Total number of RPCs started on the server.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+
+	grpcHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of RPCs completed on the server, regardless of success or failure.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"})
+
+	grpcHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Histogram of response latency (seconds) of gRPC that had been application-level handled by the server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+
+	grpcMsgReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_msg_received_total",
+		Help: "Total number of RPC stream messages received on the server.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+
+	grpcMsgSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_msg_sent_total",
+		Help: "Total number of gRPC stream messages sent by the server.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		grpcStarted,
+		grpcHandled,
+		grpcHandlingSeconds,
+		grpcMsgReceived,
+		grpcMsgSent,
+	)
+}
+
+// UnaryServerInterceptor records the metrics of unary calls.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		m := newCallMetrics("unary", info.FullMethod)
+		m.msgReceived()
+
+		resp, err := handler(ctx, req)
+		if err == nil {
+			m.msgSent()
+		}
+		m.handled(err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records the metrics of streaming calls.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		m := newCallMetrics(streamType(info), info.FullMethod)
+
+		err := handler(srv, &monitoredStream{ServerStream: ss, metrics: m})
+		m.handled(err)
+
+		return err
+	}
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return "bidi_stream"
+	case info.IsClientStream:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
+
+// callMetrics records the metrics of one call.
+type callMetrics struct {
+	labels []string
+	start  time.Time
+}
+
+func newCallMetrics(callType, fullMethod string) *callMetrics {
+	service, method := splitMethodName(fullMethod)
+	m := &callMetrics{labels: []string{callType, service, method}, start: time.Now()}
+	grpcStarted.WithLabelValues(m.labels...).Inc()
+	return m
+}
+
+func (m *callMetrics) handled(err error) {
+	grpcHandled.WithLabelValues(append(m.labels, status.Code(err).String())...).Inc()
+	grpcHandlingSeconds.WithLabelValues(m.labels...).Observe(time.Since(m.start).Seconds())
+}
+
+func (m *callMetrics) msgReceived() {
+	grpcMsgReceived.WithLabelValues(m.labels...).Inc()
+}
+
+func (m *callMetrics) msgSent() {
+	grpcMsgSent.WithLabelValues(m.labels...).Inc()
+}
+
+// monitoredStream counts the messages of a stream.
+type monitoredStream struct {
+	grpc.ServerStream
+	metrics *callMetrics
+}
+
+// SendMsg ...
+func (s *monitoredStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.metrics.msgSent()
+	}
+	return err
+}
+
+// RecvMsg ...
+func (s *monitoredStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.metrics.msgReceived()
+	}
+	return err
+}
+
+// splitMethodName splits /package.Service/Method into its service and
+// method.
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", "unknown"
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serverStream is a stream that sends and receives without a connection.
+type serverStream struct {
+	grpc.ServerStream
+}
+
+func (serverStream) SendMsg(m interface{}) error { return nil }
+
+func (serverStream) RecvMsg(m interface{}) error { return nil }
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/podstat.PodStatIntf/QueryEvents"}
+	labels := []string{"unary", "podstat.PodStatIntf", "QueryEvents"}
+	started := testutil.ToFloat64(grpcStarted.WithLabelValues(labels...))
+	sent := testutil.ToFloat64(grpcMsgSent.WithLabelValues(labels...))
+
+	interceptor := UnaryServerInterceptor()
+	interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "invalid request")
+	})
+
+	for _, test := range []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"started", testutil.ToFloat64(grpcStarted.WithLabelValues(labels...)) - started, 2},
+		{"handled OK", testutil.ToFloat64(grpcHandled.WithLabelValues(append(labels, "OK")...)), 1},
+		{"handled InvalidArgument", testutil.ToFloat64(grpcHandled.WithLabelValues(append(labels, "InvalidArgument")...)), 1},
+		{"sent", testutil.ToFloat64(grpcMsgSent.WithLabelValues(labels...)) - sent, 1},
+	} {
+		if test.value != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, test.value)
+		}
+	}
+	if count := testutil.CollectAndCount(grpcHandlingSeconds, "grpc_server_handling_seconds"); count < 1 {
+		t.Errorf("expected the handling time to be collected, got %d series", count)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/podstat.PodStatIntf/ListenPodStatus", IsServerStream: true}
+	labels := []string{"server_stream", "podstat.PodStatIntf", "ListenPodStatus"}
+
+	err := StreamServerInterceptor()(nil, serverStream{}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		if err := ss.RecvMsg(nil); err != nil {
+			return err
+		}
+		for i := 0; i < 3; i++ {
+			if err := ss.SendMsg(nil); err != nil {
+				return err
+			}
+		}
+		return errors.New("stream failed")
+	})
+	if err == nil {
+		t.Fatal("expected the error of the handler")
+	}
+
+	for _, test := range []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"started", testutil.ToFloat64(grpcStarted.WithLabelValues(labels...)), 1},
+		{"received", testutil.ToFloat64(grpcMsgReceived.WithLabelValues(labels...)), 1},
+		{"sent", testutil.ToFloat64(grpcMsgSent.WithLabelValues(labels...)), 3},
+		{"handled Unknown", testutil.ToFloat64(grpcHandled.WithLabelValues(append(labels, "Unknown")...)), 1},
+	} {
+		if test.value != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, test.value)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var informers = &informerCollector{
+	desc: prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "informer", "synced"),
+		"Whether the informer has synced, by namespace and informer.",
+		[]string{"namespace", "informer"}, nil),
+}
+
+// SetInformerSource exports the sync state of the informers of manager.
+func SetInformerSource(manager *watcher.Manager) {
+	informers.lock.Lock()
+	defer informers.lock.Unlock()
+
+	informers.manager = manager
+}
+
+// informerCollector reports the sync state of the informers when scraped.
+type informerCollector struct {
+	desc *prometheus.Desc
+
+	lock    sync.Mutex
+	manager *watcher.Manager
+}
+
+// Describe ...
+func (c *informerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect ...
+func (c *informerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	manager := c.manager
+	c.lock.Unlock()
+
+	if manager == nil {
+		return
+	}
+
+	for _, informer := range manager.SyncState() {
+		synced := 0.0
+		if informer.Synced {
+			synced = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, synced, informer.Namespace, informer.Informer)
+	}
+}
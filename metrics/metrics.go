@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "dwserver"
+
+// Registry holds every dwserver metric.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ConfigReloads counts configuration reloads by result.
+	ConfigReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Number of configuration reloads by result.",
+	}, []string{"result"})
+
+	// ConfigLastReloadSuccess is the time of the last successful reload.
+	ConfigLastReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload.",
+	})
+
+	// TLSCertificateReloads counts server certificate reloads by result.
+	TLSCertificateReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tls_certificate_reloads_total",
+		Help:      "Number of server certificate reloads by result.",
+	}, []string{"result"})
+
+	// TLSCertificateExpiry is the expiry time of the served certificate.
+	TLSCertificateExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tls_certificate_expiry_timestamp_seconds",
+		Help:      "Expiry timestamp of the served server certificate.",
+	})
+
+	// EventsProcessed counts the events processed by the controllers by
+	// resource, event type and result.
+	EventsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_processed_total",
+		Help:      "Number of events processed by resource, event type and result.",
+	}, []string{"resource", "type", "result"})
+
+	// Subscribers is the number of open pod status streams.
+	Subscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "subscribers",
+		Help:      "Number of open pod status streams.",
+	})
+
+	// SubscriberSendDuration observes how long sending a message to a
+	// subscriber takes.
+	SubscriberSendDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "subscriber_send_duration_seconds",
+		Help:      "Time taken to send a message to a subscriber.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	})
+
+	// SubscriberDroppedMessages counts the messages dropped because a
+	// subscriber did not keep up.
+	SubscriberDroppedMessages = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subscriber_dropped_messages_total",
+		Help:      "Number of messages dropped because the subscriber buffer was full.",
+	})
+
+	// Anomalies counts the container anomalies detected by type.
+	Anomalies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "anomalies_total",
+		Help:      "Number of container anomalies detected by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		ConfigReloads,
+		ConfigLastReloadSuccess,
+		TLSCertificateReloads,
+		TLSCertificateExpiry,
+		EventsProcessed,
+		Subscribers,
+		SubscriberSendDuration,
+		SubscriberDroppedMessages,
+		Anomalies,
+		informers,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestWorkqueueMetrics(t *testing.T) {
+	queue := workqueue.NewNamed("test")
+	defer queue.ShutDown()
+
+	depth := workqueueDepth.WithLabelValues("test")
+	for _, key := range []string{"default/web-1", "default/web-2", "default/web-1"} {
+		queue.Add(key)
+	}
+	if value := testutil.ToFloat64(depth); value != 2 {
+		t.Errorf("expected a depth of 2, got %v", value)
+	}
+	if value := testutil.ToFloat64(workqueueAdds.WithLabelValues("test")); value != 2 {
+		t.Errorf("expected 2 adds, got %v", value)
+	}
+
+	key, _ := queue.Get()
+	if value := testutil.ToFloat64(depth); value != 1 {
+		t.Errorf("expected a depth of 1 once a key is taken, got %v", value)
+	}
+	queue.Done(key)
+
+	if count := testutil.CollectAndCount(workqueueDepth, "dwserver_workqueue_depth"); count < 1 {
+		t.Errorf("expected the depth of the test queue to be collected, got %d series", count)
+	}
+}
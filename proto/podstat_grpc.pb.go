@@ -25,6 +25,12 @@ type PodStatIntfClient interface {
 	GetPodStatusByName(ctx context.Context, in *PodStatRequest, opts ...grpc.CallOption) (*PodStatReply, error)
 	GetAllPodStatus(ctx context.Context, in *PodStatRequest, opts ...grpc.CallOption) (PodStatIntf_GetAllPodStatusClient, error)
 	ListenPodStatus(ctx context.Context, in *PodStatRequest, opts ...grpc.CallOption) (PodStatIntf_ListenPodStatusClient, error)
+	QueryEvents(ctx context.Context, in *QueryEventsRequest, opts ...grpc.CallOption) (*QueryEventsReply, error)
+	// ReplayEvents streams the events of the on-disk event log, which
+	// outlives restarts of the server. The stream ends after the last
+	// selected event.
+	ReplayEvents(ctx context.Context, in *QueryEventsRequest, opts ...grpc.CallOption) (PodStatIntf_ReplayEventsClient, error)
+	GetPodTimeline(ctx context.Context, in *PodTimelineRequest, opts ...grpc.CallOption) (*PodTimelineReply, error)
 }
 
 type podStatIntfClient struct {
@@ -108,6 +114,56 @@ func (x *podStatIntfListenPodStatusClient) Recv() (*PodStatReply, error) {
 	return m, nil
 }
 
+func (c *podStatIntfClient) QueryEvents(ctx context.Context, in *QueryEventsRequest, opts ...grpc.CallOption) (*QueryEventsReply, error) {
+	out := new(QueryEventsReply)
+	err := c.cc.Invoke(ctx, "/podstat.PodStatIntf/QueryEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *podStatIntfClient) ReplayEvents(ctx context.Context, in *QueryEventsRequest, opts ...grpc.CallOption) (PodStatIntf_ReplayEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PodStatIntf_ServiceDesc.Streams[2], "/podstat.PodStatIntf/ReplayEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &podStatIntfReplayEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PodStatIntf_ReplayEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type podStatIntfReplayEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *podStatIntfReplayEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *podStatIntfClient) GetPodTimeline(ctx context.Context, in *PodTimelineRequest, opts ...grpc.CallOption) (*PodTimelineReply, error) {
+	out := new(PodTimelineReply)
+	err := c.cc.Invoke(ctx, "/podstat.PodStatIntf/GetPodTimeline", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PodStatIntfServer is the server API for PodStatIntf service.
 // All implementations must embed UnimplementedPodStatIntfServer
 // for forward compatibility
@@ -115,6 +171,12 @@ type PodStatIntfServer interface {
 	GetPodStatusByName(context.Context, *PodStatRequest) (*PodStatReply, error)
 	GetAllPodStatus(*PodStatRequest, PodStatIntf_GetAllPodStatusServer) error
 	ListenPodStatus(*PodStatRequest, PodStatIntf_ListenPodStatusServer) error
+	QueryEvents(context.Context, *QueryEventsRequest) (*QueryEventsReply, error)
+	// ReplayEvents streams the events of the on-disk event log, which
+	// outlives restarts of the server. The stream ends after the last
+	// selected event.
+	ReplayEvents(*QueryEventsRequest, PodStatIntf_ReplayEventsServer) error
+	GetPodTimeline(context.Context, *PodTimelineRequest) (*PodTimelineReply, error)
 	mustEmbedUnimplementedPodStatIntfServer()
 }
 
@@ -131,6 +193,15 @@ func (UnimplementedPodStatIntfServer) GetAllPodStatus(*PodStatRequest, PodStatIn
 func (UnimplementedPodStatIntfServer) ListenPodStatus(*PodStatRequest, PodStatIntf_ListenPodStatusServer) error {
 	return status.Errorf(codes.Unimplemented, "method ListenPodStatus not implemented")
 }
+func (UnimplementedPodStatIntfServer) QueryEvents(context.Context, *QueryEventsRequest) (*QueryEventsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryEvents not implemented")
+}
+func (UnimplementedPodStatIntfServer) ReplayEvents(*QueryEventsRequest, PodStatIntf_ReplayEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReplayEvents not implemented")
+}
+func (UnimplementedPodStatIntfServer) GetPodTimeline(context.Context, *PodTimelineRequest) (*PodTimelineReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPodTimeline not implemented")
+}
 func (UnimplementedPodStatIntfServer) mustEmbedUnimplementedPodStatIntfServer() {}
 
 // UnsafePodStatIntfServer may be embedded to opt out of forward compatibility for this service.
@@ -204,6 +275,63 @@ func (x *podStatIntfListenPodStatusServer) Send(m *PodStatReply) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _PodStatIntf_QueryEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PodStatIntfServer).QueryEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/podstat.PodStatIntf/QueryEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PodStatIntfServer).QueryEvents(ctx, req.(*QueryEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PodStatIntf_ReplayEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PodStatIntfServer).ReplayEvents(m, &podStatIntfReplayEventsServer{stream})
+}
+
+type PodStatIntf_ReplayEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type podStatIntfReplayEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *podStatIntfReplayEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PodStatIntf_GetPodTimeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PodTimelineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PodStatIntfServer).GetPodTimeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/podstat.PodStatIntf/GetPodTimeline",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PodStatIntfServer).GetPodTimeline(ctx, req.(*PodTimelineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PodStatIntf_ServiceDesc is the grpc.ServiceDesc for PodStatIntf service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -215,6 +343,14 @@ var PodStatIntf_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetPodStatusByName",
 			Handler:    _PodStatIntf_GetPodStatusByName_Handler,
 		},
+		{
+			MethodName: "QueryEvents",
+			Handler:    _PodStatIntf_QueryEvents_Handler,
+		},
+		{
+			MethodName: "GetPodTimeline",
+			Handler:    _PodStatIntf_GetPodTimeline_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -227,6 +363,11 @@ var PodStatIntf_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _PodStatIntf_ListenPodStatus_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ReplayEvents",
+			Handler:       _PodStatIntf_ReplayEvents_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "podstat.proto",
 }
@@ -0,0 +1,80 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaOptions describes a Kafka publisher.
+type KafkaOptions struct {
+	// Brokers are the addresses of the brokers, host:port.
+	Brokers []string
+
+	Topic string
+}
+
+// kafkaPublisher publishes to a topic, the messages of a key to the same
+// partition.
+type kafkaPublisher struct {
+	opts   KafkaOptions
+	writer *kafka.Writer
+}
+
+// NewKafka publishes to the topic of opts. The brokers are only contacted by
+// Publish.
+func NewKafka(opts KafkaOptions) (Publisher, error) {
+	if len(opts.Brokers) == 0 || opts.Topic == "" {
+		return nil, fmt.Errorf("kafka publisher needs brokers and a topic")
+	}
+
+	return &kafkaPublisher{
+		opts: opts,
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(opts.Brokers...),
+			Topic:    opts.Topic,
+			Balancer: &kafka.Hash{},
+
+			// every in-sync replica stores the messages before they are
+			// acknowledged, and failed batches are retried by the caller
+			RequiredAcks: kafka.RequireAll,
+			MaxAttempts:  1,
+
+			// Publish passes whole batches, so waiting for more messages
+			// only delays them
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}, nil
+}
+
+func (p *kafkaPublisher) Name() string {
+	return "kafka:" + strings.Join(p.opts.Brokers, ",") + "/" + p.opts.Topic
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, msgs []Message) error {
+	return p.writer.WriteMessages(ctx, kafkaMessages(msgs)...)
+}
+
+// kafkaMessages returns the Kafka messages of msgs. The ID is sent as the id
+// header.
+func kafkaMessages(msgs []Message) []kafka.Message {
+	messages := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		message := kafka.Message{Key: []byte(msg.Key), Value: msg.Value}
+		for name, value := range msg.Headers {
+			message.Headers = append(message.Headers, kafka.Header{Key: name, Value: []byte(value)})
+		}
+		if msg.ID != "" {
+			message.Headers = append(message.Headers, kafka.Header{Key: "id", Value: []byte(msg.ID)})
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
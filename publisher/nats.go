@@ -0,0 +1,106 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSOptions describes a NATS publisher.
+type NATSOptions struct {
+	// URL is a comma separated list of servers.
+	URL string
+
+	// Subject prefixes the subjects of the messages, which add the tokens
+	// of their key: dw.events.default.web for the key default/web. A
+	// JetStream stream must capture the subjects.
+	Subject string
+}
+
+// natsPublisher publishes to JetStream, which acknowledges the messages once
+// they are stored and drops redeliveries of a message ID.
+type natsPublisher struct {
+	opts NATSOptions
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+// NewNATS connects to the NATS servers of opts. It keeps reconnecting while
+// they are unavailable, including when they are unavailable from the start.
+func NewNATS(opts NATSOptions) (Publisher, error) {
+	if opts.URL == "" || opts.Subject == "" {
+		return nil, fmt.Errorf("nats publisher needs a url and a subject")
+	}
+
+	conn, err := nats.Connect(opts.URL,
+		nats.Name("dwserver"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &natsPublisher{opts: opts, conn: conn, js: js}, nil
+}
+
+func (p *natsPublisher) Name() string { return "nats:" + p.opts.URL + "/" + p.opts.Subject }
+
+// subjectToken replaces the characters NATS subjects reserve in a token of
+// a key.
+var subjectToken = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_")
+
+// subject returns the subject of the messages of key.
+func (p *natsPublisher) subject(key string) string {
+	subject := p.opts.Subject
+	for _, token := range strings.Split(key, "/") {
+		if token != "" {
+			subject += "." + subjectToken.Replace(token)
+		}
+	}
+	return subject
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, msgs []Message) error {
+	// publish the whole batch before waiting for the acknowledgements, the
+	// connection keeps them in order
+	futures := make([]nats.PubAckFuture, 0, len(msgs))
+	for _, msg := range msgs {
+		m := nats.NewMsg(p.subject(msg.Key))
+		m.Data = msg.Value
+		for name, value := range msg.Headers {
+			m.Header.Set(name, value)
+		}
+		if msg.ID != "" {
+			m.Header.Set(nats.MsgIdHdr, msg.ID)
+		}
+
+		future, err := p.js.PublishMsgAsync(m)
+		if err != nil {
+			return err
+		}
+		futures = append(futures, future)
+	}
+
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,31 @@
+// Package publisher publishes messages to message brokers, NATS JetStream and
+// Kafka, acknowledging them only once the broker stored them.
+package publisher
+
+import "context"
+
+// Message is a message published to a broker.
+type Message struct {
+	// Key orders the messages: the messages of a key are stored in the
+	// order they were published.
+	Key string
+
+	// ID identifies the message, so that brokers can drop redeliveries.
+	ID string
+
+	Value   []byte
+	Headers map[string]string
+}
+
+// Publisher publishes batches of messages to a broker.
+type Publisher interface {
+	// Name identifies the publisher in logs.
+	Name() string
+
+	// Publish returns once the broker acknowledged every message of msgs.
+	// When it fails, some of them may still have been published.
+	Publish(ctx context.Context, msgs []Message) error
+
+	// Close releases the connection to the broker.
+	Close() error
+}
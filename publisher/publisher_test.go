@@ -0,0 +1,50 @@
+package publisher
+
+import "testing"
+
+func TestNATSSubject(t *testing.T) {
+	p := &natsPublisher{opts: NATSOptions{Subject: "dw.events"}}
+	for key, want := range map[string]string{
+		"default/web":        "dw.events.default.web",
+		"default/web.v2":     "dw.events.default.web_v2",
+		"cluster-admin":      "dw.events.cluster-admin",
+		"default/web-*-1 >2": "dw.events.default.web-_-1__2",
+	} {
+		if got := p.subject(key); got != want {
+			t.Errorf("subject(%q) = %q, expected %q", key, got, want)
+		}
+	}
+}
+
+func TestKafkaMessages(t *testing.T) {
+	messages := kafkaMessages([]Message{{
+		Key:     "default/web",
+		ID:      "6f1c/42/modified",
+		Value:   []byte(`{}`),
+		Headers: map[string]string{"type": "io.dw.pod.modified"},
+	}})
+	if len(messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(messages))
+	}
+
+	message := messages[0]
+	if string(message.Key) != "default/web" || string(message.Value) != `{}` {
+		t.Errorf("unexpected message %+v", message)
+	}
+	headers := map[string]string{}
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers["type"] != "io.dw.pod.modified" || headers["id"] != "6f1c/42/modified" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := NewNATS(NATSOptions{URL: "nats://localhost:4222"}); err == nil {
+		t.Error("expected an error for a nats publisher without subject")
+	}
+	if _, err := NewKafka(KafkaOptions{Topic: "dw-events"}); err == nil {
+		t.Error("expected an error for a kafka publisher without brokers")
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestQueryAnomalies(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	lis, stop, errc := startServer(t, clientset, func(cfg *config.Config) {
+		cfg.Anomalies.Detect = true
+	})
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	client := pb.NewPodStatIntfClient(conn)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", UID: "uid-web-1"},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name:  "app",
+				State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "not found"}},
+			}},
+		},
+	}
+	if _, err := clientset.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server may not be serving or watching yet
+	var reply *pb.QueryEventsReply
+	for {
+		var err error
+		reply, err = client.QueryEvents(ctx, &pb.QueryEventsRequest{Pod: "web-1"})
+		if err == nil && len(reply.GetEvents()) == 2 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for the events, last reply %v: %v", reply, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// events at the same time are ordered by resource
+	detected, added := reply.GetEvents()[0], reply.GetEvents()[1]
+	if added.GetType() != common.EventAdded || added.GetPodstat().GetReason() != "ErrImagePull" {
+		t.Errorf("expected the pod to be added waiting for its image, got %v", added)
+	}
+	if detected.GetResource() != common.ResourceAnomaly || detected.GetType() != anomaly.TypeImagePull {
+		t.Errorf("expected an image pull anomaly, got %v", detected)
+	}
+	if a := detected.GetAnomaly(); a.GetContainer() != "app" || a.GetReason() != "ErrImagePull" || a.GetMessage() != "not found" {
+		t.Errorf("unexpected anomaly %v", a)
+	}
+
+	reply, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{Resource: common.ResourceAnomaly, Types: []string{anomaly.TypeOOMKilled}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.GetEvents()) != 0 {
+		t.Errorf("expected no OOMKilled anomalies, got %v", reply.GetEvents())
+	}
+}
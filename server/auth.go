@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/grpc/server/auth"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// newInterceptors returns the authentication and authorization interceptors
+// configured by cfg.
+func newInterceptors(cfg *config.Config, clientset kubernetes.Interface) (*auth.Interceptors, error) {
+	a := cfg.Auth
+	interceptors := &auth.Interceptors{}
+
+	var authenticators []auth.Authenticator
+	if a.ClientCertificates {
+		authenticators = append(authenticators, auth.CertificateAuthenticator{})
+	}
+	if a.StaticTokenFile != "" {
+		static, err := auth.NewStaticTokenAuthenticator(a.StaticTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, static)
+	}
+	if a.TokenReview {
+		authenticators = append(authenticators, auth.NewTokenReviewAuthenticator(clientset, a.TokenAudiences))
+	}
+
+	if len(authenticators) > 0 {
+		interceptors.Authenticator = auth.Union(authenticators...)
+		if (a.TokenReview || a.StaticTokenFile != "") && !cfg.TLS.Enabled() {
+			klog.Warning("Bearer tokens are accepted without TLS, they are sent in clear text")
+		}
+	} else {
+		klog.Warning("Authentication is not configured, every client can read pod status")
+	}
+
+	if a.Authorization == config.AuthorizationSubjectAccessReview {
+		interceptors.Authorizer = auth.NewSubjectAccessReviewAuthorizer(clientset)
+	}
+
+	return interceptors, nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestNamespaceAuthorization(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokens := filepath.Join(dir, "tokens.csv")
+	if err := ioutil.WriteFile(tokens, []byte("secret,alice,uid-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// alice may list the pods of default only
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview).DeepCopy()
+		review.Status.Allowed = review.Spec.User == "alice" && review.Spec.ResourceAttributes.Namespace == "default"
+		return true, review, nil
+	})
+
+	lis, stop, errc := startServer(t, clientset, func(cfg *config.Config) {
+		cfg.Auth.StaticTokenFile = tokens
+		cfg.Auth.Authorization = config.AuthorizationSubjectAccessReview
+		cfg.EventLog.Dir = filepath.Join(dir, "eventlog")
+		cfg.Timelines.MaxAge = metav1.Duration{Duration: time.Hour}
+	})
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	client := pb.NewPodStatIntfClient(conn)
+
+	calls := map[string]func(namespace string) error{
+		"ListenPodStatus": func(namespace string) error {
+			// an authorized stream is served until the call ends
+			streamCtx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			stream, err := client.ListenPodStatus(streamCtx, &pb.PodStatRequest{Namespace: namespace})
+			if err != nil {
+				return err
+			}
+			if _, err = stream.Recv(); status.Code(err) == codes.DeadlineExceeded {
+				return nil
+			}
+			return err
+		},
+		"QueryEvents": func(namespace string) error {
+			_, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{Namespace: namespace})
+			return err
+		},
+		"ReplayEvents": func(namespace string) error {
+			stream, err := client.ReplayEvents(ctx, &pb.QueryEventsRequest{Namespace: namespace})
+			if err != nil {
+				return err
+			}
+			for {
+				if _, err := stream.Recv(); err != nil {
+					if err == io.EOF {
+						return nil
+					}
+					return err
+				}
+			}
+		},
+		"GetPodTimeline": func(namespace string) error {
+			_, err := client.GetPodTimeline(ctx, &pb.PodTimelineRequest{Namespace: namespace})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call("production"); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected production to be denied, got %v", name, err)
+		}
+		if err := call(""); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected all namespaces to be denied, got %v", name, err)
+		}
+		if err := call("default"); err != nil {
+			t.Errorf("%s: expected default to be allowed, got %v", name, err)
+		}
+	}
+}
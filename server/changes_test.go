@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/cloudevents"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestReplyChanges(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "1", Labels: map[string]string{"generation": "1"}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+
+	lis, stop, errc := startServer(t, clientset)
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stream, err := pb.NewPodStatIntfClient(conn).ListenPodStatus(ctx, &pb.PodStatRequest{Clientid: "changes", IncludeChanges: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies, recvErr := receive(stream)
+
+	// the stream is registered asynchronously, so keep relabeling the pod
+	// until an update is received
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for version := 2; ; version++ {
+		select {
+		case reply := <-replies:
+			changes := reply.GetChanges()
+			if len(changes) != 1 {
+				t.Fatalf("expected one change, got %v", changes)
+			}
+			change := changes[0]
+			if change.GetPath() != "metadata.labels.generation" {
+				t.Errorf("expected the label to change, got %q", change.GetPath())
+			}
+			if change.GetOp() != "replace" || change.GetNew() == "" {
+				t.Errorf("expected the new value of the label, got %v", change)
+			}
+			return
+		case err := <-recvErr:
+			t.Fatalf("stream failed: %v", err)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a pod update")
+		case <-ticker.C:
+			pod.ResourceVersion = strconv.Itoa(version)
+			pod.Labels = map[string]string{"generation": strconv.Itoa(version)}
+			if _, err := clientset.CoreV1().Pods(pod.Namespace).Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestReplyCloudEvent(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", UID: "6f1c", ResourceVersion: "1"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+
+	lis, stop, errc := startServer(t, clientset)
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stream, err := pb.NewPodStatIntfClient(conn).ListenPodStatus(ctx, &pb.PodStatRequest{Clientid: "cloudevents", IncludeChanges: true, Cloudevents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies, recvErr := receive(stream)
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for version := 2; ; version++ {
+		select {
+		case reply := <-replies:
+			var event cloudevents.Event
+			if err := json.Unmarshal(reply.GetCloudevent(), &event); err != nil {
+				t.Fatalf("invalid cloud event %q: %v", reply.GetCloudevent(), err)
+			}
+			if event.Type != "io.dw.pod.modified" || event.Subject != "web-1" || event.Source != "/namespaces/default/pods" {
+				t.Errorf("unexpected cloud event %s", reply.GetCloudevent())
+			}
+			if len(reply.GetChanges()) == 0 {
+				t.Error("expected the changes along with the cloud event")
+			}
+			return
+		case err := <-recvErr:
+			t.Fatalf("stream failed: %v", err)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a pod update")
+		case <-ticker.C:
+			pod.ResourceVersion = strconv.Itoa(version)
+			pod.Labels = map[string]string{"generation": strconv.Itoa(version)}
+			if _, err := clientset.CoreV1().Pods(pod.Namespace).Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
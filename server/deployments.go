@@ -0,0 +1,68 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// deploymentWatchers watch the deployments of the namespaces the pod
+// controller watches, for the sinks of deployment events. The informers
+// share the factories of the pod informers, so they are stopped with them.
+type deploymentWatchers struct {
+	manager *watcher.Manager
+	sinks   watcher.Sinks
+
+	// lock guards namespaces and stopper
+	lock       sync.Mutex
+	namespaces sets.String
+	stopper    chan struct{}
+}
+
+func newDeploymentWatchers(manager *watcher.Manager, sinks watcher.Sinks, namespaces []string) *deploymentWatchers {
+	d := &deploymentWatchers{manager: manager, sinks: sinks, namespaces: sets.NewString()}
+	d.setNamespaces(namespaces)
+	return d
+}
+
+// start records the stopper of the informers. The informers of the
+// namespaces watched so far are started with the pod informers.
+func (d *deploymentWatchers) start(stopper chan struct{}) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.stopper = stopper
+}
+
+// setNamespaces watches the deployments of namespaces, an empty list
+// watches all namespaces. The informers of new namespaces are started and
+// synced if the pod informers are running already.
+func (d *deploymentWatchers) setNamespaces(namespaces []string) error {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	wanted := sets.NewString(namespaces...)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var added []*watcher.DeploymentWatcher
+	for _, namespace := range wanted.List() {
+		if !d.namespaces.Has(namespace) {
+			added = append(added, watcher.NewDeploymentWatcher(d.manager, namespace, d.sinks...))
+		}
+	}
+	d.namespaces = wanted
+
+	if d.stopper == nil {
+		return nil
+	}
+	for _, dw := range added {
+		if err := dw.Run(d.stopper); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/klog/v2"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+// healthServicePrefix is the prefix of the methods of the grpc.health.v1
+// service.
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// setServing records whether the gRPC server accepts calls and reports it
+// through the grpc.health.v1 service.
+func (s *Server) setServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	value := int32(0)
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+		value = 1
+	}
+
+	atomic.StoreInt32(&s.serving, value)
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(pb.PodStatIntf_ServiceDesc.ServiceName, status)
+}
+
+// readinessChecks returns the failed readiness checks: dwserver is ready
+// once every informer has synced and the gRPC server is serving.
+func (s *Server) readinessChecks() map[string]error {
+	checks := map[string]error{
+		"informers": nil,
+		"grpc":      nil,
+	}
+
+	informers := s.manager.SyncState()
+	if len(informers) == 0 {
+		checks["informers"] = fmt.Errorf("no informers started")
+	}
+	for _, informer := range informers {
+		if !informer.Synced {
+			checks["informers"] = fmt.Errorf("%s informer in namespace %q has not synced", informer.Informer, informer.Namespace)
+			break
+		}
+	}
+
+	if atomic.LoadInt32(&s.serving) == 0 {
+		checks["grpc"] = fmt.Errorf("gRPC server is not serving")
+	}
+
+	return checks
+}
+
+// handleHealthz reports that the process is alive.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, "ok")
+}
+
+// handleReadyz reports whether dwserver is ready to serve clients. With the
+// verbose query parameter, every check is listed.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := s.readinessChecks()
+
+	var out strings.Builder
+	ready := true
+	for _, name := range []string{"informers", "grpc"} {
+		if err := checks[name]; err != nil {
+			ready = false
+			fmt.Fprintf(&out, "[-]%s failed: %v\n", name, err)
+		} else {
+			fmt.Fprintf(&out, "[+]%s ok\n", name)
+		}
+	}
+
+	if !ready {
+		klog.V(2).InfoS("Readiness check failed", "checks", out.String())
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, out.String())
+		return
+	}
+
+	if _, verbose := r.URL.Query()["verbose"]; verbose {
+		fmt.Fprint(w, out.String())
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// handleSubscribers lists the open pod status streams as JSON.
+func (s *Server) handleSubscribers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.podController.Subscriptions()); err != nil {
+		klog.ErrorS(err, "Failed to write subscribers")
+	}
+}
+
+// registerProfiling serves the net/http/pprof handlers under /debug/pprof/.
+func registerProfiling(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// unlessHealthUnary exempts the health service from interceptor, so that
+// probes need neither credentials nor quota.
+func unlessHealthUnary(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// unlessHealthStream is the stream counterpart of unlessHealthUnary.
+func unlessHealthStream(interceptor grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(srv, ss)
+		}
+		return interceptor(srv, ss, info, handler)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/kubernetes/fake"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestHealthExemptFromLimits(t *testing.T) {
+	lis, stop, errc := startServer(t, fake.NewSimpleClientset(), func(cfg *config.Config) {
+		cfg.Server.MaxStreamsPerClient = 1
+		cfg.Server.UnaryQPS, cfg.Server.UnaryBurst = 0.001, 1
+	})
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	client := pb.NewPodStatIntfClient(conn)
+	health := healthpb.NewHealthClient(conn)
+
+	// use up the quota of unary calls and streams
+	if _, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the unary quota to be used up, got %v", err)
+	}
+	stream, err := client.ListenPodStatus(ctx, &pb.PodStatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies, recvErr := receive(stream)
+
+	for i := 0; i < 3; i++ {
+		if _, err := health.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Errorf("expected health checks not to be rate limited, got %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		watch, err := health.Watch(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := watch.Recv(); err != nil {
+			t.Errorf("expected health watches not to count against the streams, got %v", err)
+		}
+	}
+
+	// the stream slot is still taken
+	second, err := client.ListenPodStatus(ctx, &pb.PodStatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected a second stream to be rejected, got %v", err)
+	}
+	select {
+	case err := <-recvErr:
+		t.Errorf("expected the first stream to be served, got %v", err)
+	case reply := <-replies:
+		t.Errorf("unexpected reply %v", reply)
+	default:
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestQueryEvents(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	lis, stop, errc := startServer(t, clientset)
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	client := pb.NewPodStatIntfClient(conn)
+
+	since := time.Now()
+	deployment := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if _, err := clientset.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.0.0.1"},
+	}
+	if _, err := clientset.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server may not be serving or watching yet
+	var reply *pb.QueryEventsReply
+	for {
+		var err error
+		reply, err = client.QueryEvents(ctx, &pb.QueryEventsRequest{Namespace: "default"})
+		if err == nil && len(reply.GetEvents()) == 2 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for the events, last reply %v: %v", reply, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	for _, e := range reply.GetEvents() {
+		if e.GetType() != common.EventAdded || e.GetTime().AsTime().Before(since.Add(-time.Second)) {
+			t.Errorf("unexpected event %v", e)
+		}
+		if e.GetResource() == common.ResourcePod && e.GetPodstat().GetPodip() != "10.0.0.1" {
+			t.Errorf("expected the state of the pod, got %v", e)
+		}
+	}
+
+	reply, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{Pod: "web-1", Types: []string{common.EventDeleted}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.GetEvents()) != 0 {
+		t.Errorf("expected no deleted events, got %v", reply.GetEvents())
+	}
+
+	reply, err = client.QueryEvents(ctx, &pb.QueryEventsRequest{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.GetEvents()) != 1 || !reply.GetTruncated() {
+		t.Errorf("expected a truncated reply of 1 event, got %v", reply)
+	}
+}
+
+func TestQueryEventsDisabled(t *testing.T) {
+	lis, stop, errc := startServer(t, fake.NewSimpleClientset(), func(cfg *config.Config) {
+		cfg.History.MaxEvents = 0
+	})
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := pb.NewPodStatIntfClient(conn).QueryEvents(ctx, &pb.QueryEventsRequest{}, grpc.WaitForReady(true))
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition, got %v", err)
+	}
+}
+
+func TestReplayEventsAfterRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	withEventLog := func(cfg *config.Config) {
+		cfg.EventLog.Dir = dir
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}, Status: v1.PodStatus{Phase: v1.PodRunning}}
+	lis, stop, errc := startServer(t, fake.NewSimpleClientset(pod), withEventLog)
+	conn := dial(t, lis)
+	client := pb.NewPodStatIntfClient(conn)
+	for {
+		reply, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{Pod: "web-1"})
+		if err == nil && len(reply.GetEvents()) > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the pod event")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	conn.Close()
+	close(stop)
+	if err := <-errc; err != nil {
+		t.Fatalf("Serve returned %v", err)
+	}
+
+	// the pod is gone by the time dwserver restarts
+	lis, stop, errc = startServer(t, fake.NewSimpleClientset(), withEventLog)
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+	conn = dial(t, lis)
+	defer conn.Close()
+	client = pb.NewPodStatIntfClient(conn)
+
+	stream, err := client.ReplayEvents(ctx, &pb.QueryEventsRequest{Namespace: "default", Since: timestamppb.New(time.Now().Add(-time.Hour))}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []*pb.Event
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 1 || events[0].GetName() != "web-1" || events[0].GetType() != common.EventAdded || events[0].GetPodstat().GetPodstate() != string(v1.PodRunning) {
+		t.Errorf("expected the event recorded before the restart, got %v", events)
+	}
+
+	// the history is loaded from the log
+	reply, err := client.QueryEvents(ctx, &pb.QueryEventsRequest{Pod: "web-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.GetEvents()) != 1 {
+		t.Errorf("expected the history to hold the recorded event, got %v", reply.GetEvents())
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+)
+
+// httpShutdownTimeout bounds the shutdown of the HTTP server.
+const httpShutdownTimeout = 5 * time.Second
+
+// serveHTTP serves /metrics, the health endpoints and the debug endpoints
+// on the configured HTTP address, if any. Serving errors are sent to errc.
+func (s *Server) serveHTTP(errc chan<- error) (*http.Server, error) {
+	addr := s.config().Server.HTTPAddress
+	if addr == "" {
+		return nil, nil
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: s.httpHandler()}
+	go func() {
+		klog.InfoS("HTTP server listening", "address", lis.Addr().String())
+		if err := srv.Serve(lis); err != http.ErrServerClosed {
+			errc <- err
+		}
+	}()
+
+	return srv, nil
+}
+
+// httpHandler routes the HTTP endpoints.
+func (s *Server) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	// the debug endpoints name the clients and expose the internals of the
+	// process, they are opt-in
+	if s.config().Server.Profiling {
+		mux.HandleFunc("/debug/subscribers", s.handleSubscribers)
+		registerProfiling(mux)
+	}
+	return mux
+}
+
+func stopHTTP(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		klog.ErrorS(err, "Failed to stop the HTTP server")
+	}
+}
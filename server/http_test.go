@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// get returns the status and the body of a GET of path on handler.
+func get(handler http.Handler, path string) (int, string) {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code, w.Body.String()
+}
+
+// waitForStatus polls path on handler until it answers code.
+func waitForStatus(t *testing.T, handler http.Handler, path string, code int) string {
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		status, body := get(handler, path)
+		if status == code {
+			return body
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to answer %d, last %d: %s", path, code, status, body)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	// the pods are listed once the test lets them
+	listed := make(chan struct{})
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		<-listed
+		return false, nil, nil
+	})
+
+	cfg := config.Default()
+	cfg.Server.ShutdownTimeout = metav1.Duration{Duration: 30 * time.Second}
+	srv, err := New(cfg, watcher.NewManager(clientset, watcher.DefaultOptions()), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := srv.httpHandler()
+
+	// a stream holding up the shutdown
+	started, release := make(chan struct{}), make(chan struct{})
+	srv.grpcServer.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Blocking",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Block",
+			ServerStreams: true,
+			Handler: func(interface{}, grpc.ServerStream) error {
+				close(started)
+				<-release
+				return nil
+			},
+		}},
+	}, struct{}{})
+
+	lis := bufconn.Listen(1 << 20)
+	stop := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(lis, stop)
+	}()
+
+	if status, body := get(handler, "/readyz"); status != http.StatusServiceUnavailable || !strings.Contains(body, "[-]informers failed") || !strings.Contains(body, "[-]grpc failed") {
+		t.Errorf("expected not to be ready before the informers sync, got %d: %s", status, body)
+	}
+
+	close(listed)
+	if body := waitForStatus(t, handler, "/readyz", http.StatusOK); body != "ok" {
+		t.Errorf("expected ok, got %s", body)
+	}
+	if _, body := get(handler, "/readyz?verbose"); body != "[+]informers ok\n[+]grpc ok\nok" {
+		t.Errorf("expected the checks to be listed, got %s", body)
+	}
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/test.Blocking/Block"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	close(stop)
+	body := waitForStatus(t, handler, "/readyz", http.StatusServiceUnavailable)
+	if !strings.Contains(body, "[-]grpc failed: gRPC server is not serving") {
+		t.Errorf("expected the gRPC check to fail during shutdown, got %s", body)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("expected the shutdown to wait for the open stream, Serve returned %v", err)
+	default:
+	}
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
+
+func TestDebugEndpointsOptIn(t *testing.T) {
+	for _, profiling := range []bool{false, true} {
+		cfg := config.Default()
+		cfg.Server.Profiling = profiling
+		clientset := fake.NewSimpleClientset()
+		srv, err := New(cfg, watcher.NewManager(clientset, watcher.DefaultOptions()), clientset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := http.StatusNotFound
+		if profiling {
+			expected = http.StatusOK
+		}
+		for _, path := range []string{"/debug/subscribers", "/debug/pprof/"} {
+			if status, _ := get(srv.httpHandler(), path); status != expected {
+				t.Errorf("profiling %v: expected %s to answer %d, got %d", profiling, path, expected, status)
+			}
+		}
+	}
+}
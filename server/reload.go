@@ -0,0 +1,200 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/logging"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// configChangeDelay coalesces the burst of events a single write of the
+// config file produces.
+const configChangeDelay = 500 * time.Millisecond
+
+// Reload loads the configuration with load and applies the settings that
+// can change while running: the watched namespaces, the filters, the
+// subscriber and client limits and the logging settings. Nothing is applied
+// unless the whole configuration is valid. Existing streams are kept.
+// Changes to other settings are logged and take effect on restart. The
+// result is logged and counted in the config reload metrics.
+func (s *Server) Reload(load func() (*config.Config, error)) error {
+	err := s.reload(load)
+	if err != nil {
+		klog.ErrorS(err, "Failed to reload configuration")
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		return err
+	}
+
+	klog.Info("Configuration reloaded")
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	metrics.ConfigLastReloadSuccess.SetToCurrentTime()
+	return nil
+}
+
+func (s *Server) reload(load func() (*config.Config, error)) error {
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	selector, err := labels.Parse(cfg.Filters.LabelSelector)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// once the namespaces change the whole configuration is applied and
+	// kept, even if the informers of new namespaces fail to sync, so that
+	// the server never runs a mix of the old and the new settings
+	var errs []error
+	if err := s.podController.SetNamespaces(cfg.Namespaces); err != nil {
+		errs = append(errs, fmt.Errorf("failed to watch namespaces %v: %v", cfg.Namespaces, err))
+	}
+	if s.deployments != nil {
+		if err := s.deployments.setNamespaces(cfg.Namespaces); err != nil {
+			errs = append(errs, fmt.Errorf("failed to watch the deployments of namespaces %v: %v", cfg.Namespaces, err))
+		}
+	}
+	s.podController.SetLabelSelector(selector)
+	s.podController.SetMaxSubscribers(cfg.Server.MaxSubscribers)
+	s.limiter.SetOptions(cfg.LimitOptions())
+	if err := logging.Configure(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, setting := range restartRequired(s.cfg, cfg) {
+		klog.InfoS("Setting changed, restart dwserver to apply it", "setting", setting)
+	}
+
+	s.cfg = cfg
+	return utilerrors.NewAggregate(errs)
+}
+
+// restartRequired returns the settings that differ between old and new and
+// cannot be applied while running.
+func restartRequired(old, new *config.Config) []string {
+	var changed []string
+
+	if old.Server.ListenAddress != new.Server.ListenAddress {
+		changed = append(changed, "server.listenAddress")
+	}
+	if old.Server.HTTPAddress != new.Server.HTTPAddress {
+		changed = append(changed, "server.httpAddress")
+	}
+	if old.Server.Profiling != new.Server.Profiling {
+		changed = append(changed, "server.profiling")
+	}
+	if !reflect.DeepEqual(old.TLS, new.TLS) {
+		changed = append(changed, "tls")
+	}
+	if !reflect.DeepEqual(old.Auth, new.Auth) {
+		changed = append(changed, "auth")
+	}
+	if !reflect.DeepEqual(old.Informer, new.Informer) {
+		changed = append(changed, "informer")
+	}
+	if !reflect.DeepEqual(old.Controller, new.Controller) {
+		changed = append(changed, "controller")
+	}
+	if old.Tracing != new.Tracing {
+		changed = append(changed, "tracing")
+	}
+	if !reflect.DeepEqual(old.Diff, new.Diff) {
+		changed = append(changed, "diff")
+	}
+	if !reflect.DeepEqual(old.Sinks, new.Sinks) {
+		changed = append(changed, "sinks")
+	}
+	if !reflect.DeepEqual(old.Webhooks, new.Webhooks) {
+		changed = append(changed, "webhooks")
+	}
+	if old.History != new.History {
+		changed = append(changed, "history")
+	}
+	if old.EventLog != new.EventLog {
+		changed = append(changed, "eventLog")
+	}
+	if old.Timelines != new.Timelines {
+		changed = append(changed, "timelines")
+	}
+	if !reflect.DeepEqual(old.Anomalies, new.Anomalies) {
+		changed = append(changed, "anomalies")
+	}
+
+	return changed
+}
+
+// WatchConfigFile notifies on the returned channel when the file at path
+// changes, until stopCh is closed.
+func WatchConfigFile(path string, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	return watchFiles(stopCh, path)
+}
+
+// watchFiles notifies on the returned channel when any of paths changes,
+// until stopCh is closed. The directories are watched rather than the
+// files, so that editors replacing a file and ConfigMap and Secret volumes
+// swapping their ..data symlink are noticed too.
+func watchFiles(stopCh <-chan struct{}, paths ...string) (<-chan struct{}, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	files := sets.NewString("..data")
+	dirs := sets.NewString()
+	for _, path := range paths {
+		dir, file := filepath.Split(filepath.Clean(path))
+		if dir == "" {
+			dir = "."
+		}
+		files.Insert(file)
+		dirs.Insert(dir)
+	}
+
+	for _, dir := range dirs.List() {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer w.Close()
+
+		var pending <-chan time.Time
+		for {
+			select {
+			case <-stopCh:
+				return
+			case event := <-w.Events:
+				if files.Has(filepath.Base(event.Name)) {
+					pending = time.After(configChangeDelay)
+				}
+			case err := <-w.Errors:
+				klog.ErrorS(err, "File watch error")
+			case <-pending:
+				pending = nil
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
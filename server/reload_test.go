@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/logging"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"google.golang.org/grpc/test/bufconn"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestReloadLogLevel(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	srv, err := New(config.Default(), watcher.NewManager(clientset, watcher.DefaultOptions()), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logging.SetLevel(0)
+
+	// a reload that fails leaves the verbosity alone
+	bad := config.Default()
+	bad.Logging.Level = 4
+	bad.Filters.LabelSelector = "app in ("
+	if err := srv.Reload(func() (*config.Config, error) { return bad, nil }); err == nil {
+		t.Fatal("expected the reload of an invalid label selector to fail")
+	}
+	if klog.V(4).Enabled() {
+		t.Error("expected the verbosity of a failed reload not to be applied")
+	}
+
+	good := config.Default()
+	good.Logging.Level = 4
+	if err := srv.Reload(func() (*config.Config, error) { return good, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !klog.V(4).Enabled() {
+		t.Error("expected the verbosity of a successful reload to be applied")
+	}
+}
+
+func TestReloadNamespaces(t *testing.T) {
+	web := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "1"}}
+	api := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "production", ResourceVersion: "1"}}
+	clientset := fake.NewSimpleClientset(web, api)
+
+	var lock sync.Mutex
+	listed := sets.NewString()
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		listed.Insert(action.GetNamespace())
+		return false, nil, nil
+	})
+	watches := make(chan *stopRecordingWatch, 10)
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		recorded := &stopRecordingWatch{Interface: w, namespace: action.GetNamespace(), stopped: make(chan struct{})}
+		watches <- recorded
+		return true, recorded, nil
+	})
+
+	cfg := config.Default()
+	cfg.Namespaces = []string{"default"}
+	srv, err := New(cfg, watcher.NewManager(clientset, watcher.DefaultOptions()), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	stop := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(lis, stop)
+	}()
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	stream, err := pb.NewPodStatIntfClient(conn).ListenPodStatus(ctx, &pb.PodStatRequest{Clientid: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies, recvErr := receive(stream)
+	waitForUpdate(ctx, t, clientset, web, replies, recvErr)
+
+	reload := func(namespaces ...string) {
+		cfg := config.Default()
+		cfg.Namespaces = namespaces
+		if err := srv.Reload(func() (*config.Config, error) { return cfg, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// nothing of an invalid configuration is applied, the informers of new
+	// namespaces would have listed their pods by the time Reload returns
+	bad := config.Default()
+	bad.Namespaces = []string{"default", "production"}
+	bad.Logging.Format = "xml"
+	if err := srv.Reload(func() (*config.Config, error) { return bad, nil }); err == nil {
+		t.Fatal("expected the reload of an invalid log format to fail")
+	}
+	lock.Lock()
+	if listed.Has("production") {
+		t.Error("expected the namespaces of a failed reload not to be watched")
+	}
+	lock.Unlock()
+
+	// the pods of production are watched and reach the open stream
+	reload("default", "production")
+	var production *stopRecordingWatch
+	for production == nil {
+		select {
+		case w := <-watches:
+			if w.namespace == "production" {
+				production = w
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the pods of production to be watched")
+		}
+	}
+	waitForUpdate(ctx, t, clientset, api, replies, recvErr)
+
+	// they are no longer once production is dropped, the stream is kept
+	reload("default")
+	select {
+	case <-production.stopped:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the pods of production to no longer be watched")
+	}
+	waitForUpdate(ctx, t, clientset, web, replies, recvErr)
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/anomaly"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/controller"
+	"github.com/bobbybho/k8s-deployment-watcher/eventlog"
+	"github.com/bobbybho/k8s-deployment-watcher/grpc/server/limit"
+	podserver "github.com/bobbybho/k8s-deployment-watcher/grpc/server/pod"
+	"github.com/bobbybho/k8s-deployment-watcher/history"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"github.com/bobbybho/k8s-deployment-watcher/timeline"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+// Server runs the pod controller and the PodStatIntf gRPC server of
+// dwserver.
+type Server struct {
+	manager       *watcher.Manager
+	podController *controller.PodController
+	grpcServer    *grpc.Server
+
+	limiter *limit.Limiter
+	health  *health.Server
+
+	// deployments is nil unless a webhook, the history or the event log
+	// takes deployment events
+	deployments *deploymentWatchers
+
+	// history is nil if the event history is disabled
+	history *history.Store
+
+	// eventLog is nil if the on-disk event log is disabled
+	eventLog *eventlog.Log
+
+	// timelines is nil if the pod timelines are disabled
+	timelines *timeline.Tracker
+
+	// sinks are closed on shutdown
+	sinks watcher.Sinks
+
+	// serving is set while the gRPC server accepts calls, atomically.
+	serving int32
+
+	// certs is nil unless TLS is enabled.
+	certs *certificates
+
+	// lock serializes reloads and guards cfg.
+	lock sync.Mutex
+	cfg  *config.Config
+}
+
+// New ...
+func New(cfg *config.Config, manager *watcher.Manager, clientset kubernetes.Interface) (s *Server, err error) {
+	controllerOpts, err := cfg.ControllerOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var sinks watcher.Sinks
+	defer func() {
+		if err != nil {
+			sinks.Close()
+		}
+	}()
+
+	podSinks, err := cfg.PodSinks()
+	if err != nil {
+		return nil, err
+	}
+	sinks = append(sinks, podSinks...)
+
+	webhooks, err := cfg.WebhookSinks("")
+	if err != nil {
+		return nil, err
+	}
+	sinks = append(sinks, webhooks...)
+	controllerOpts.Sinks = append(podSinks, webhooks.For(common.ResourcePod)...)
+
+	// the timelines and the anomaly detector forget the pods that are gone
+	// from the cache of the controller, which is created once they are
+	var podController *controller.PodController
+	podExists := func(namespace, name string, uid types.UID) bool {
+		return podController.HasPod(namespace, name, uid)
+	}
+
+	var store *history.Store
+	if cfg.History.Enabled() {
+		store = history.New(cfg.HistoryOptions())
+		sinks = append(sinks, store)
+		controllerOpts.Sinks = append(controllerOpts.Sinks, store)
+	}
+
+	var eventLog *eventlog.Log
+	if cfg.EventLog.Enabled() {
+		if eventLog, err = eventlog.Open(cfg.EventLogOptions()); err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, eventLog)
+		controllerOpts.Sinks = append(controllerOpts.Sinks, eventLog)
+
+		// the history starts with the events recorded before a restart
+		if store != nil {
+			since := time.Now().Add(-cfg.History.MaxAge.Duration)
+			if err := eventLog.Replay(history.Query{Since: since}, func(e history.Entry) error {
+				store.Add(e)
+				return nil
+			}); err != nil {
+				klog.ErrorS(err, "Failed to load the event history from the event log", "dir", cfg.EventLog.Dir)
+			}
+		}
+	}
+
+	var timelines *timeline.Tracker
+	if cfg.Timelines.Enabled() {
+		opts := cfg.TimelineOptions()
+		nodes := manager.NodeLister()
+		opts.Zone = func(name string) string {
+			node, err := nodes.Get(name)
+			if err != nil {
+				return ""
+			}
+			return timeline.NodeZone(node)
+		}
+		opts.Exists = podExists
+		timelines = timeline.NewTracker(opts)
+		sinks = append(sinks, timelines)
+		controllerOpts.Sinks = append(controllerOpts.Sinks, timelines)
+	}
+
+	// anomalies go where the pod events go, after the pod event they were
+	// detected in
+	if cfg.Anomalies.Enabled() {
+		anomalySinks := append(append(watcher.Sinks(nil), podSinks...), webhooks.For(common.ResourceAnomaly)...)
+		if store != nil {
+			anomalySinks = append(anomalySinks, store)
+		}
+		if eventLog != nil {
+			anomalySinks = append(anomalySinks, eventLog)
+		}
+		opts := cfg.AnomalyOptions()
+		opts.Exists = podExists
+		controllerOpts.Sinks = append(controllerOpts.Sinks, anomaly.NewDetector(opts, anomalySinks))
+	}
+
+	metrics.SetInformerSource(manager)
+
+	podController = controller.NewPodController(manager, clientset, cfg.Namespaces, controllerOpts)
+	s = &Server{
+		manager:       manager,
+		podController: podController,
+		limiter:       limit.New(cfg.LimitOptions()),
+		health:        health.NewServer(),
+		history:       store,
+		eventLog:      eventLog,
+		timelines:     timelines,
+		cfg:           cfg,
+	}
+
+	// the deployments are only watched for the webhooks, the history and
+	// the event log, the other sinks of deployment events are used by the
+	// deployment watch command
+	var deploymentSinks watcher.Sinks
+	if deploymentWebhooks := webhooks.For(common.ResourceDeployment); len(deploymentWebhooks) > 0 {
+		if deploymentSinks, err = cfg.DeploymentSinks(); err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, deploymentSinks...)
+		deploymentSinks = append(deploymentSinks, deploymentWebhooks...)
+	}
+	if store != nil {
+		deploymentSinks = append(deploymentSinks, store)
+	}
+	if eventLog != nil {
+		deploymentSinks = append(deploymentSinks, eventLog)
+	}
+	if len(deploymentSinks) > 0 {
+		s.deployments = newDeploymentWatchers(manager, deploymentSinks, cfg.Namespaces)
+	}
+	s.sinks = sinks
+
+	var opts []grpc.ServerOption
+	if cfg.TLS.Enabled() {
+		if s.certs, err = newCertificates(cfg.TLS, clientset); err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(s.certs.credentials()))
+	} else {
+		klog.Warning("TLS is not configured, serving gRPC without transport security")
+	}
+
+	interceptors, err := newInterceptors(cfg, clientset)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor(), metrics.UnaryServerInterceptor(),
+			unlessHealthUnary(interceptors.Unary()), unlessHealthUnary(s.limiter.Unary())),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor(), metrics.StreamServerInterceptor(),
+			unlessHealthStream(interceptors.Stream()), unlessHealthStream(s.limiter.Stream())))
+
+	s.grpcServer = grpc.NewServer(opts...)
+
+	pb.RegisterPodStatIntfServer(s.grpcServer, &podserver.PodServer{PodController: s.podController, History: s.history, EventLog: s.eventLog, Timelines: s.timelines})
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+	s.setServing(false)
+
+	return s, nil
+}
+
+// Run listens on the configured address and serves until stopCh is closed.
+// See Serve.
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	lis, err := net.Listen("tcp", s.config().Server.ListenAddress)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(lis, stopCh)
+}
+
+// Serve serves /metrics, starts the informers, waits for their caches to
+// sync and serves gRPC on lis. When stopCh is closed, new streams are
+// refused, open streams get a final shutdown message and the gRPC server
+// stops gracefully within the configured shutdown timeout before the HTTP
+// server and the informers are stopped. Serve returns once everything has
+// stopped, or when serving fails.
+func (s *Server) Serve(lis net.Listener, stopCh <-chan struct{}) error {
+	// closed last, once the informers are told to stop
+	defer func() {
+		if err := s.sinks.Close(); err != nil {
+			klog.ErrorS(err, "Failed to close sinks")
+		}
+	}()
+
+	informerStop := make(chan struct{})
+	var once sync.Once
+	stopInformers := func() {
+		once.Do(func() { close(informerStop) })
+	}
+	defer stopInformers()
+
+	errc := make(chan error, 2)
+	httpServer, err := s.serveHTTP(errc)
+	if err != nil {
+		lis.Close()
+		return err
+	}
+	if httpServer != nil {
+		defer stopHTTP(httpServer)
+	}
+
+	// stop waiting for the caches to sync on shutdown
+	synced := make(chan struct{})
+	go func() {
+		select {
+		case <-stopCh:
+			stopInformers()
+		case <-synced:
+		}
+	}()
+
+	if s.deployments != nil {
+		s.deployments.start(informerStop)
+	}
+	err = s.podController.Start(informerStop)
+	close(synced)
+	if err != nil {
+		lis.Close()
+		select {
+		case <-stopCh:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	if s.certs != nil {
+		certStop := make(chan struct{})
+		defer close(certStop)
+		if err := s.certs.Run(certStop); err != nil {
+			lis.Close()
+			return err
+		}
+	}
+
+	go func() {
+		klog.InfoS("gRPC server listening", "address", lis.Addr().String())
+		errc <- s.grpcServer.Serve(lis)
+	}()
+	s.setServing(true)
+
+	select {
+	case <-stopCh:
+	case err := <-errc:
+		s.grpcServer.Stop()
+		return err
+	}
+
+	s.shutdown()
+	return nil
+}
+
+// shutdown ends the open streams and stops the gRPC server, giving in-flight
+// calls the shutdown timeout to finish.
+func (s *Server) shutdown() {
+	timeout := s.config().Server.ShutdownTimeout.Duration
+	klog.InfoS("Shutting down, waiting for open streams", "timeout", timeout.String())
+
+	// fail readiness and health checks first, so that no new clients are
+	// sent to this instance
+	s.setServing(false)
+	s.health.Shutdown()
+	s.podController.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		klog.Info("GRPC server stopped")
+	case <-time.After(timeout):
+		klog.Warning("Timed out waiting for open streams, cancelling them")
+		s.grpcServer.Stop()
+		<-stopped
+	}
+}
+
+func (s *Server) config() *config.Config {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.cfg
+}
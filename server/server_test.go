@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	podserver "github.com/bobbybho/k8s-deployment-watcher/grpc/server/pod"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+// stopRecordingWatch closes stopped when the informer stops watching.
+type stopRecordingWatch struct {
+	watch.Interface
+	namespace string
+	once      sync.Once
+	stopped   chan struct{}
+}
+
+func (w *stopRecordingWatch) Stop() {
+	w.Interface.Stop()
+	w.once.Do(func() { close(w.stopped) })
+}
+
+// startServer serves a dwserver watching clientset on the returned
+// listener, until stop is closed. Serve returns its error on errc. configure
+// adjusts the default test configuration.
+func startServer(t *testing.T, clientset *fake.Clientset, configure ...func(*config.Config)) (lis *bufconn.Listener, stop chan struct{}, errc chan error) {
+	cfg := config.Default()
+	cfg.Server.HTTPAddress = "127.0.0.1:0"
+	cfg.Server.ShutdownTimeout = metav1.Duration{Duration: 5 * time.Second}
+	for _, f := range configure {
+		f(cfg)
+	}
+
+	watcherOpts, err := cfg.WatcherOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := New(cfg, watcher.NewManager(clientset, watcherOpts), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis = bufconn.Listen(1 << 20)
+	stop = make(chan struct{})
+	errc = make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(lis, stop)
+	}()
+
+	return lis, stop, errc
+}
+
+// dial connects to the server listening on lis.
+func dial(t *testing.T, lis *bufconn.Listener) *grpc.ClientConn {
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// receive forwards the replies of stream to replies until it fails.
+func receive(stream pb.PodStatIntf_ListenPodStatusClient) (replies chan *pb.PodStatReply, recvErr chan error) {
+	replies = make(chan *pb.PodStatReply)
+	recvErr = make(chan error, 1)
+	go func() {
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			replies <- reply
+		}
+	}()
+	return replies, recvErr
+}
+
+// waitForUpdate keeps changing pod until an update of it is received. The
+// stream is registered asynchronously, so earlier changes may be missed.
+// Updates of other pods are skipped.
+func waitForUpdate(ctx context.Context, t *testing.T, clientset *fake.Clientset, pod *v1.Pod, replies chan *pb.PodStatReply, recvErr chan error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for version := 2; ; version++ {
+		select {
+		case reply := <-replies:
+			if reply.GetPodstat().GetPodname() == pod.Name {
+				return
+			}
+		case err := <-recvErr:
+			t.Fatalf("stream failed: %v", err)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a pod update")
+		case <-ticker.C:
+			pod.ResourceVersion = strconv.Itoa(version)
+			if _, err := clientset.CoreV1().Pods(pod.Namespace).Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "1"},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+
+	watches := make(chan *stopRecordingWatch, 10)
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		recorded := &stopRecordingWatch{Interface: w, stopped: make(chan struct{})}
+		watches <- recorded
+		return true, recorded, nil
+	})
+
+	lis, stop, errc := startServer(t, clientset)
+
+	conn := dial(t, lis)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stream, err := pb.NewPodStatIntfClient(conn).ListenPodStatus(ctx, &pb.PodStatRequest{Clientid: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replies, recvErr := receive(stream)
+	waitForUpdate(ctx, t, clientset, pod, replies, recvErr)
+
+	var podWatch *stopRecordingWatch
+	select {
+	case podWatch = <-watches:
+	default:
+		t.Fatal("the pod informer did not watch pods")
+	}
+
+	close(stop)
+
+	// updates buffered before the shutdown may still arrive first
+	for shutdown := false; !shutdown; {
+		select {
+		case reply := <-replies:
+			shutdown = reply.GetMessage() == podserver.ShutdownMessage
+		case err := <-recvErr:
+			t.Fatalf("stream ended without the shutdown message: %v", err)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the shutdown message")
+		}
+	}
+
+	select {
+	case err := <-recvErr:
+		if err != io.EOF {
+			t.Fatalf("expected the stream to end cleanly, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the stream to end")
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("Serve returned %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for Serve to return")
+	}
+
+	select {
+	case <-podWatch.stopped:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the pod informer to stop")
+	}
+
+	// the server no longer accepts streams
+	newConn := dial(t, lis)
+	defer newConn.Close()
+
+	newCtx, newCancel := context.WithTimeout(ctx, time.Second)
+	defer newCancel()
+
+	newStream, err := pb.NewPodStatIntfClient(newConn).ListenPodStatus(newCtx, &pb.PodStatRequest{Clientid: "late"})
+	if err == nil {
+		_, err = newStream.Recv()
+	}
+	if err == nil {
+		t.Fatal("expected a stream opened after shutdown to fail")
+	}
+}
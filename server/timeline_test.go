@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestGetPodTimeline(t *testing.T) {
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	at := func(seconds int) metav1.Time {
+		return metav1.NewTime(created.Add(time.Duration(seconds) * time.Second))
+	}
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{v1.LabelTopologyZone: "zone-a"}}}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", CreationTimestamp: at(0)},
+		Spec:       v1.PodSpec{NodeName: "node-a"},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue, LastTransitionTime: at(1)},
+				{Type: v1.PodInitialized, Status: v1.ConditionTrue, LastTransitionTime: at(2)},
+				{Type: v1.ContainersReady, Status: v1.ConditionTrue, LastTransitionTime: at(9)},
+				{Type: v1.PodReady, Status: v1.ConditionTrue, LastTransitionTime: at(9)},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "web", State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: at(7)}}},
+			},
+		},
+	}
+
+	lis, stop, errc := startServer(t, fake.NewSimpleClientset(node, pod), func(cfg *config.Config) {
+		cfg.Timelines.MaxAge = metav1.Duration{Duration: time.Hour}
+	})
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	conn := dial(t, lis)
+	defer conn.Close()
+	client := pb.NewPodStatIntfClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var reply *pb.PodTimelineReply
+	for {
+		var err error
+		reply, err = client.GetPodTimeline(ctx, &pb.PodTimelineRequest{Namespace: "default", Pod: "web-1"})
+		if err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for the timeline: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if len(reply.GetTimelines()) != 1 {
+		t.Fatalf("expected the timeline of web-1, got %v", reply)
+	}
+	timeline := reply.GetTimelines()[0]
+	if timeline.GetZone() != "zone-a" {
+		t.Errorf("expected the zone of the node, got %q", timeline.GetZone())
+	}
+	for _, test := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"scheduling", timeline.GetSchedulingLatency().AsDuration(), time.Second},
+		{"startup", timeline.GetStartupLatency().AsDuration(), 5 * time.Second},
+		{"time to ready", timeline.GetTimeToReady().AsDuration(), 9 * time.Second},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s latency: expected %v, got %v", test.name, test.want, test.got)
+		}
+	}
+	if len(reply.GetZones()) != 1 || reply.GetZones()[0].GetTimeToReady().GetCount() != 1 {
+		t.Errorf("expected the latencies of zone-a, got %v", reply.GetZones())
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/metrics"
+	"google.golang.org/grpc/credentials"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// secretTimeout bounds the initial read of the certificate Secret.
+const secretTimeout = 30 * time.Second
+
+// certificates holds the server certificate and the client CA bundle and
+// reloads them when their files or Secret change. Connections made after a
+// reload use the new certificate, established ones are kept.
+type certificates struct {
+	cfg       config.TLSConfig
+	clientset kubernetes.Interface
+
+	lock      sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// newCertificates loads the certificates selected by cfg.
+func newCertificates(cfg config.TLSConfig, clientset kubernetes.Interface) (*certificates, error) {
+	c := &certificates{cfg: cfg, clientset: clientset}
+
+	if cfg.Secret != "" {
+		namespace, name, err := cfg.SecretKey()
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+		defer cancel()
+
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TLS secret %s: %v", cfg.Secret, err)
+		}
+		if err := c.loadSecret(secret); err != nil {
+			return nil, err
+		}
+	} else if err := c.loadFiles(); err != nil {
+		return nil, err
+	}
+
+	if err := c.loadClientCAs(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// credentials returns server credentials that always present the current
+// certificate.
+func (c *certificates) credentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		GetConfigForClient: c.configForClient,
+	})
+}
+
+func (c *certificates) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*c.cert},
+		NextProtos:   []string{"h2"},
+	}
+	if c.clientCAs != nil {
+		cfg.ClientCAs = c.clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// Run reloads the certificates when they change, until stopCh is closed.
+func (c *certificates) Run(stopCh <-chan struct{}) error {
+	var files []string
+	if c.cfg.Secret == "" {
+		files = append(files, c.cfg.CertFile, c.cfg.KeyFile)
+	} else if err := c.watchSecret(stopCh); err != nil {
+		return err
+	}
+	if c.cfg.ClientCAFile != "" {
+		files = append(files, c.cfg.ClientCAFile)
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	changes, err := watchFiles(stopCh, files...)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-changes:
+				err := c.loadClientCAs()
+				if err == nil && c.cfg.Secret == "" {
+					err = c.loadFiles()
+				}
+				c.reloaded(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// watchSecret reloads the certificate whenever the Secret is updated.
+func (c *certificates) watchSecret(stopCh <-chan struct{}) error {
+	namespace, name, err := c.cfg.SecretKey()
+	if err != nil {
+		return err
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+
+	informer := factory.Core().V1().Secrets().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		// the Secret may have changed since it was read by newCertificates
+		AddFunc: func(obj interface{}) {
+			if err := c.loadSecret(obj.(*v1.Secret)); err != nil {
+				klog.ErrorS(err, "Failed to load TLS certificates, keeping the current ones")
+			}
+		},
+		UpdateFunc: func(old, new interface{}) {
+			oldSecret, newSecret := old.(*v1.Secret), new.(*v1.Secret)
+			if oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				return
+			}
+			c.reloaded(c.loadSecret(newSecret))
+		},
+		DeleteFunc: func(obj interface{}) {
+			klog.InfoS("TLS secret was deleted, keeping the current certificate", "secret", c.cfg.Secret)
+		},
+	})
+
+	factory.Start(stopCh)
+	return nil
+}
+
+func (c *certificates) reloaded(err error) {
+	if err != nil {
+		klog.ErrorS(err, "Failed to reload TLS certificates, keeping the current ones")
+		metrics.TLSCertificateReloads.WithLabelValues("failure").Inc()
+		return
+	}
+
+	klog.Info("TLS certificates reloaded")
+	metrics.TLSCertificateReloads.WithLabelValues("success").Inc()
+}
+
+func (c *certificates) loadFiles() error {
+	cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %v", err)
+	}
+
+	return c.setCertificate(cert)
+}
+
+func (c *certificates) loadSecret(secret *v1.Secret) error {
+	cert, err := tls.X509KeyPair(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate from secret %s/%s: %v", secret.Namespace, secret.Name, err)
+	}
+
+	return c.setCertificate(cert)
+}
+
+func (c *certificates) setCertificate(cert tls.Certificate) error {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse server certificate: %v", err)
+	}
+	cert.Leaf = leaf
+
+	c.lock.Lock()
+	c.cert = &cert
+	c.lock.Unlock()
+
+	metrics.TLSCertificateExpiry.Set(float64(leaf.NotAfter.Unix()))
+	return nil
+}
+
+func (c *certificates) loadClientCAs() error {
+	if c.cfg.ClientCAFile == "" {
+		return nil
+	}
+
+	pool, err := common.LoadCertPool(c.cfg.ClientCAFile)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.clientCAs = pool
+	c.lock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testCA issues the certificates of the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dwserver-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM encoded certificate and key for localhost with
+// serial, for clients or servers.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// certDir returns a temporary directory for the certificates of a test.
+func certDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// writeFile replaces the file at path with data, the way a Secret volume
+// does.
+func writeFile(t *testing.T, path string, data []byte) {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tlsFiles writes a server certificate issued by ca with serial to dir and
+// returns the TLS configuration using it.
+func tlsFiles(t *testing.T, dir string, ca *testCA, serial int64) config.TLSConfig {
+	cfg := config.TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	cert, key := ca.issue(t, serial, x509.ExtKeyUsageServerAuth)
+	writeFile(t, cfg.KeyFile, key)
+	writeFile(t, cfg.CertFile, cert)
+	return cfg
+}
+
+// serveTLS serves the health service with the credentials of certs until
+// the test ends.
+func serveTLS(t *testing.T, certs *certificates) *bufconn.Listener {
+	srv := grpc.NewServer(grpc.Creds(certs.credentials()))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+// checkHealth calls the health service on lis over TLS with tlsConfig.
+func checkHealth(lis *bufconn.Listener, tlsConfig *tls.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "localhost",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+// servedSerial returns the serial number of the certificate served on lis.
+func servedSerial(t *testing.T, lis *bufconn.Listener, roots *x509.CertPool) int64 {
+	conn, err := lis.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := tls.Client(conn, &tls.Config{ServerName: "localhost", RootCAs: roots, NextProtos: []string{"h2"}})
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	return client.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCA(t)
+	certs, err := newCertificates(tlsFiles(t, certDir(t), ca, 2), fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := serveTLS(t, certs)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if err := checkHealth(lis, &tls.Config{RootCAs: roots}); err != nil {
+		t.Errorf("expected a TLS call to succeed, got %v", err)
+	}
+	if err := checkHealth(lis, &tls.Config{RootCAs: x509.NewCertPool()}); err == nil {
+		t.Error("expected a call not trusting the server certificate to fail")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := certDir(t)
+	cfg := tlsFiles(t, dir, ca, 2)
+	cfg.ClientCAFile = filepath.Join(dir, "ca.crt")
+	writeFile(t, cfg.ClientCAFile, ca.pem)
+
+	certs, err := newCertificates(cfg, fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := serveTLS(t, certs)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if err := checkHealth(lis, &tls.Config{RootCAs: roots}); err == nil {
+		t.Error("expected a client without a certificate to be rejected")
+	}
+
+	certPEM, keyPEM := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkHealth(lis, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}); err != nil {
+		t.Errorf("expected a client with a certificate to be accepted, got %v", err)
+	}
+}
+
+func TestCertificateRotation(t *testing.T) {
+	ca := newTestCA(t)
+	dir := certDir(t)
+	certs, err := newCertificates(tlsFiles(t, dir, ca, 2), fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := certs.Run(stop); err != nil {
+		t.Fatal(err)
+	}
+	lis := serveTLS(t, certs)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if serial := servedSerial(t, lis, roots); serial != 2 {
+		t.Fatalf("expected certificate 2 to be served, got %d", serial)
+	}
+
+	tlsFiles(t, dir, ca, 3)
+	deadline := time.Now().Add(10 * time.Second)
+	for servedSerial(t, lis, roots) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the rotated certificate to be served")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
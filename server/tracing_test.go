@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
+)
+
+func TestEventTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "1"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+
+	lis, stop, errc := startServer(t, clientset)
+
+	conn := dial(t, lis)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stream, err := pb.NewPodStatIntfClient(conn).ListenPodStatus(ctx, &pb.PodStatRequest{Clientid: "traced"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replies, recvErr := receive(stream)
+	waitForUpdate(ctx, t, clientset, pod, replies, recvErr)
+
+	close(stop)
+	if err := <-errc; err != nil {
+		t.Fatalf("Serve returned %v", err)
+	}
+
+	spans := make(map[trace.SpanID]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.SpanContext.SpanID()] = span
+	}
+
+	// follow a delivered update back to the informer event
+	for _, send := range spans {
+		if send.Name != tracing.SpanSend {
+			continue
+		}
+
+		process, ok := spans[send.Parent.SpanID()]
+		if !ok || process.Name != tracing.SpanProcess {
+			t.Fatalf("parent of the send span is %q, expected %q", process.Name, tracing.SpanProcess)
+		}
+		event, ok := spans[process.Parent.SpanID()]
+		if !ok || event.Name != tracing.SpanEvent {
+			t.Fatalf("parent of the process span is %q, expected %q", event.Name, tracing.SpanEvent)
+		}
+		if event.Parent.IsValid() {
+			t.Errorf("expected the event span to be a root span")
+		}
+		if send.SpanContext.TraceID() != event.SpanContext.TraceID() {
+			t.Errorf("expected the send span in the trace of the event")
+		}
+
+		attrs := make(map[string]string)
+		for _, attr := range event.Attributes {
+			attrs[string(attr.Key)] = attr.Value.Emit()
+		}
+		if attrs[string(tracing.KeyEventKey)] != "default/web-1" || attrs[string(tracing.KeyResourceVersion)] == "" {
+			t.Errorf("unexpected event span attributes %v", attrs)
+		}
+
+		// the send is linked to the span of the gRPC stream
+		if len(send.Links) != 1 {
+			t.Fatalf("expected the send span to link the stream span, got %d links", len(send.Links))
+		}
+		streamSpan, ok := spans[send.Links[0].SpanContext.SpanID()]
+		if !ok || streamSpan.SpanKind != trace.SpanKindServer {
+			t.Errorf("expected the send span to link a gRPC server span, got %q", streamSpan.Name)
+		}
+		return
+	}
+
+	t.Fatal("no send span recorded")
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/config"
+	"github.com/bobbybho/k8s-deployment-watcher/watcher"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeploymentWebhook(t *testing.T) {
+	records := make(chan watcher.Record, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record watcher.Record
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			t.Errorf("invalid record: %v", err)
+		}
+		records <- record
+	}))
+	defer hook.Close()
+
+	clientset := fake.NewSimpleClientset()
+	_, stop, errc := startServer(t, clientset, func(cfg *config.Config) {
+		cfg.Webhooks = []config.WebhookConfig{{URL: hook.URL, Resources: []string{common.ResourceDeployment}}}
+	})
+	defer func() {
+		close(stop)
+		if err := <-errc; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// the informers may not be watching yet, the deployment is listed then
+	deployment := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if _, err := clientset.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case record := <-records:
+		if record.Resource != common.ResourceDeployment || record.Key != "default/web" || record.Type != common.EventAdded {
+			t.Errorf("unexpected record %+v", record)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the webhook")
+	}
+}
@@ -3,15 +3,27 @@ package podbot
 import (
 	"context"
 	"io"
-	"log"
+	"os"
 
+	podserver "github.com/bobbybho/k8s-deployment-watcher/grpc/server/pod"
 	pb "github.com/bobbybho/k8s-deployment-watcher/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"k8s.io/klog/v2"
 )
 
 type PodBot struct {
 	Name string
+
+	// Namespace restricts the pods the bot listens to, empty for all.
+	Namespace string
+
+	// IncludeChanges asks for the changed fields of updated pods.
+	IncludeChanges bool
+
+	// CloudEvents asks for the events as CloudEvents and prints them to
+	// stdout, one per line.
+	CloudEvents bool
 }
 
 func NewPodBot(name string) *PodBot {
@@ -36,7 +48,7 @@ func (p *PodBot) Run(ctx context.Context, remoteAddr string, opt ...grpc.DialOpt
 	var err error
 
 	if conn, err = GRPCConnect(remoteAddr, opt...); err != nil {
-		log.Printf("Failed to connect to remote addr %v error: %v\n", remoteAddr, err.Error())
+		klog.ErrorS(err, "Failed to connect", "podbot", p.Name, "address", remoteAddr)
 	}
 
 	go p.RunPodStat(ctx, conn)
@@ -51,18 +63,26 @@ func (p *PodBot) RunPodStat(ctx context.Context, conn *grpc.ClientConn) {
 
 	listenRequest := pb.PodStatRequest{}
 	listenRequest.Clientid = p.Name
+	listenRequest.Namespace = p.Namespace
+	listenRequest.IncludeChanges = p.IncludeChanges
+	listenRequest.Cloudevents = p.CloudEvents
 
 	stream, err := client.ListenPodStatus(ctx, &listenRequest)
 	if err != nil {
-		log.Fatalf("Failed to listen to podstatus stream. podbot=%v error=%v\n", p.Name, err.Error())
+		klog.ErrorS(err, "Failed to listen to the pod status stream", "podbot", p.Name)
+		klog.Flush()
+		os.Exit(1)
+	}
 
+	if header, err := stream.Header(); err == nil {
+		klog.InfoS("Subscribed", "podbot", p.Name, "subscriber", header.Get(podserver.SubscriptionIDHeader))
 	}
 
 listenLoop:
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("[%v] received done signal\n", p.Name)
+			klog.InfoS("Received done signal", "podbot", p.Name)
 			break listenLoop
 		default:
 			podStatusReply, err := stream.Recv()
@@ -71,12 +91,23 @@ listenLoop:
 			}
 
 			if err != nil {
-				log.Printf("Failed to receive podstat stream: %v\n", err.Error())
+				klog.ErrorS(err, "Failed to receive pod status", "podbot", p.Name)
 				return
 			}
 
-			log.Printf("Receive PodStatusReply %+v\n", podStatusReply)
+			klog.InfoS("Received pod status", "podbot", p.Name, "event", podStatusReply.GetMessage(),
+				"pod", podStatusReply.GetPodstat().GetPodname(), "phase", podStatusReply.GetPodstat().GetPodstate())
 
+			for _, change := range podStatusReply.GetChanges() {
+				klog.InfoS("Changed field", "podbot", p.Name, "pod", podStatusReply.GetPodstat().GetPodname(),
+					"op", change.GetOp(), "path", change.GetPath(), "old", change.GetOld(), "new", change.GetNew())
+			}
+
+			if event := podStatusReply.GetCloudevent(); len(event) > 0 {
+				if _, err := os.Stdout.Write(append(event, '\n')); err != nil {
+					klog.ErrorS(err, "Failed to print CloudEvent", "podbot", p.Name)
+				}
+			}
 		}
 	}
 }
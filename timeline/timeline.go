@@ -0,0 +1,421 @@
+// Package timeline derives the lifecycle of pods from their events, from
+// their creation until they are ready, and the startup latencies of
+// deployments and zones.
+package timeline
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultMaxAge is how long the timelines of deleted pods are kept.
+const DefaultMaxAge = 24 * time.Hour
+
+// pruneInterval is how often the timelines of deleted pods are expired.
+const pruneInterval = time.Minute
+
+// Steps of the lifecycle of a pod, in order.
+const (
+	StepCreated         = "Created"
+	StepScheduled       = "Scheduled"
+	StepInitialized     = "Initialized"
+	StepStarted         = "Started"
+	StepContainersReady = "ContainersReady"
+	StepReady           = "Ready"
+)
+
+// Steps lists the steps of the lifecycle of a pod, in order.
+var Steps = []string{StepCreated, StepScheduled, StepInitialized, StepStarted, StepContainersReady, StepReady}
+
+// NodeZone returns the zone of node from its topology labels, falling back
+// to the deprecated failure domain label.
+func NodeZone(node *v1.Node) string {
+	if zone := node.Labels[v1.LabelTopologyZone]; zone != "" {
+		return zone
+	}
+	return node.Labels[v1.LabelFailureDomainBetaZone]
+}
+
+// Timeline is the lifecycle of a pod. The time of a step is zero until the
+// pod reaches it, and is the first time it was reached: a pod that becomes
+// ready again after a failed probe keeps its first Ready.
+type Timeline struct {
+	Namespace  string
+	Name       string
+	UID        string
+	Deployment string
+	Node       string
+	Zone       string
+
+	Created         time.Time
+	Scheduled       time.Time
+	Initialized     time.Time
+	Started         time.Time
+	ContainersReady time.Time
+	Ready           time.Time
+
+	// Deleted is when the deletion of the pod was seen, or when the pod was
+	// found to no longer exist.
+	Deleted time.Time
+}
+
+// FromPod returns the timeline of pod as of its current status.
+func FromPod(pod *v1.Pod) Timeline {
+	t := Timeline{
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		UID:        string(pod.UID),
+		Deployment: common.DeploymentName(pod),
+		Node:       pod.Spec.NodeName,
+		Created:    pod.CreationTimestamp.Time,
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Status != v1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case v1.PodScheduled:
+			t.Scheduled = c.LastTransitionTime.Time
+		case v1.PodInitialized:
+			t.Initialized = c.LastTransitionTime.Time
+		case v1.ContainersReady:
+			t.ContainersReady = c.LastTransitionTime.Time
+		case v1.PodReady:
+			t.Ready = c.LastTransitionTime.Time
+		}
+	}
+
+	// the containers have started, images pulled, once the last of them
+	// runs
+	for _, s := range pod.Status.ContainerStatuses {
+		running := s.State.Running
+		if running == nil {
+			t.Started = time.Time{}
+			break
+		}
+		if running.StartedAt.After(t.Started) {
+			t.Started = running.StartedAt.Time
+		}
+	}
+
+	return t
+}
+
+// Step returns the time of step, one of Steps.
+func (t Timeline) Step(step string) time.Time {
+	switch step {
+	case StepCreated:
+		return t.Created
+	case StepScheduled:
+		return t.Scheduled
+	case StepInitialized:
+		return t.Initialized
+	case StepStarted:
+		return t.Started
+	case StepContainersReady:
+		return t.ContainersReady
+	case StepReady:
+		return t.Ready
+	}
+	return time.Time{}
+}
+
+// merge fills the steps of t not reached yet from the ones of other, a later
+// observation of the same pod.
+func (t *Timeline) merge(other Timeline) {
+	for _, s := range []struct {
+		field *time.Time
+		value time.Time
+	}{
+		{&t.Created, other.Created},
+		{&t.Scheduled, other.Scheduled},
+		{&t.Initialized, other.Initialized},
+		{&t.Started, other.Started},
+		{&t.ContainersReady, other.ContainersReady},
+		{&t.Ready, other.Ready},
+	} {
+		if s.field.IsZero() {
+			*s.field = s.value
+		}
+	}
+	if other.Node != "" {
+		t.Node = other.Node
+	}
+	if other.Deployment != "" {
+		t.Deployment = other.Deployment
+	}
+}
+
+// between returns the time from a to b, zero unless both are set.
+func between(a, b time.Time) time.Duration {
+	if a.IsZero() || b.IsZero() || b.Before(a) {
+		return 0
+	}
+	return b.Sub(a)
+}
+
+// SchedulingLatency is the time from the creation of the pod until it was
+// scheduled, zero until it is.
+func (t Timeline) SchedulingLatency() time.Duration {
+	return between(t.Created, t.Scheduled)
+}
+
+// StartupLatency is the time from the initialization of the pod, or its
+// scheduling without init containers, until its containers were running:
+// the pull of the images and the start of the containers.
+func (t Timeline) StartupLatency() time.Duration {
+	from := t.Initialized
+	if from.IsZero() {
+		from = t.Scheduled
+	}
+	return between(from, t.Started)
+}
+
+// TimeToReady is the time from the creation of the pod until it was ready.
+func (t Timeline) TimeToReady() time.Duration {
+	return between(t.Created, t.Ready)
+}
+
+// Options describes a Tracker.
+type Options struct {
+	// MaxAge is how long the timelines of deleted pods are kept. Zero uses
+	// DefaultMaxAge.
+	MaxAge time.Duration
+
+	// Zone returns the zone of a node, empty if unknown. Nil leaves the
+	// zones of the timelines empty.
+	Zone func(node string) string
+
+	// Exists reports whether the pod namespace/name with uid still exists.
+	// The pods that no longer exist are taken as deleted, even if their
+	// deletion was not seen. Nil only takes the pods seen deleted as such.
+	Exists func(namespace, name string, uid types.UID) bool
+}
+
+// Tracker is an EventSink deriving the timelines of the pods of the events
+// it is sent.
+type Tracker struct {
+	opts Options
+
+	lock      sync.RWMutex
+	timelines map[string]*Timeline
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+// NewTracker returns a Tracker without timelines.
+func NewTracker(opts Options) *Tracker {
+	if opts.MaxAge == 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	return &Tracker{opts: opts, timelines: map[string]*Timeline{}, now: time.Now}
+}
+
+// Name implements watcher.EventSink.
+func (t *Tracker) Name() string { return "timeline" }
+
+// Close implements watcher.EventSink, the timelines stay queryable.
+func (t *Tracker) Close() error { return nil }
+
+// Send updates the timeline of the pod of e.
+func (t *Tracker) Send(e common.Event) error {
+	pod, ok := e.Object.(*v1.Pod)
+	if !ok || e.ResourceType != common.ResourcePod {
+		return nil
+	}
+	observed := FromPod(pod)
+	key := pod.Namespace + "/" + pod.Name
+	now := t.now()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if now.Sub(t.lastPrune) >= pruneInterval {
+		t.prune(now)
+	}
+
+	timeline, ok := t.timelines[key]
+	if !ok || timeline.UID != observed.UID {
+		timeline = &observed
+		t.timelines[key] = timeline
+	} else {
+		timeline.merge(observed)
+	}
+
+	if timeline.Zone == "" && timeline.Node != "" && t.opts.Zone != nil {
+		timeline.Zone = t.opts.Zone(timeline.Node)
+	}
+	if e.EventType == common.EventDeleted && timeline.Deleted.IsZero() {
+		timeline.Deleted = now
+	}
+	return nil
+}
+
+// prune drops the timelines of the pods deleted more than MaxAge ago, and
+// takes the pods that no longer exist as deleted now.
+func (t *Tracker) prune(now time.Time) {
+	cutoff := now.Add(-t.opts.MaxAge)
+	for key, timeline := range t.timelines {
+		if timeline.Deleted.IsZero() && t.opts.Exists != nil && !t.opts.Exists(timeline.Namespace, timeline.Name, types.UID(timeline.UID)) {
+			timeline.Deleted = now
+		}
+		if !timeline.Deleted.IsZero() && timeline.Deleted.Before(cutoff) {
+			delete(t.timelines, key)
+		}
+	}
+	t.lastPrune = now
+}
+
+// Query selects timelines. Empty fields select every timeline.
+type Query struct {
+	Namespace  string
+	Deployment string
+	Pod        string
+}
+
+func (q Query) matches(t *Timeline) bool {
+	return (q.Namespace == "" || t.Namespace == q.Namespace) &&
+		(q.Deployment == "" || t.Deployment == q.Deployment) &&
+		(q.Pod == "" || t.Name == q.Pod)
+}
+
+// Timelines returns the timelines selected by q, by creation time.
+func (t *Tracker) Timelines(q Query) []Timeline {
+	cutoff := t.now().Add(-t.opts.MaxAge)
+
+	var timelines []Timeline
+	t.lock.RLock()
+	for _, timeline := range t.timelines {
+		if q.matches(timeline) && (timeline.Deleted.IsZero() || !timeline.Deleted.Before(cutoff)) {
+			timelines = append(timelines, *timeline)
+		}
+	}
+	t.lock.RUnlock()
+
+	sort.Slice(timelines, func(i, j int) bool {
+		a, b := timelines[i], timelines[j]
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+	return timelines
+}
+
+// Stats are the percentiles of a latency over the pods that reached the
+// end of it.
+type Stats struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// NewStats returns the stats of samples, which it sorts.
+func NewStats(samples []time.Duration) Stats {
+	if len(samples) == 0 {
+		return Stats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	// nearest rank
+	percentile := func(p int) time.Duration {
+		rank := (p*len(samples) + 99) / 100
+		if rank < 1 {
+			rank = 1
+		}
+		return samples[rank-1]
+	}
+	return Stats{
+		Count: len(samples),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   samples[len(samples)-1],
+	}
+}
+
+// Summary are the latencies of a group of pods, a deployment or a zone.
+type Summary struct {
+	// Namespace and Deployment are set for the summary of a deployment,
+	// Zone for the summary of a zone.
+	Namespace  string
+	Deployment string
+	Zone       string
+
+	Scheduling  Stats
+	Startup     Stats
+	TimeToReady Stats
+}
+
+// Summarize returns the latencies of timelines per deployment and per zone,
+// sorted by name. Pods outside of deployments and of unknown zones are left
+// out of the respective summaries.
+func Summarize(timelines []Timeline) (deployments, zones []Summary) {
+	type samples struct {
+		scheduling, startup, ready []time.Duration
+	}
+	add := func(s *samples, t Timeline) {
+		if !t.Scheduled.IsZero() {
+			s.scheduling = append(s.scheduling, t.SchedulingLatency())
+		}
+		if !t.Started.IsZero() {
+			s.startup = append(s.startup, t.StartupLatency())
+		}
+		if !t.Ready.IsZero() {
+			s.ready = append(s.ready, t.TimeToReady())
+		}
+	}
+
+	byDeployment := map[[2]string]*samples{}
+	byZone := map[string]*samples{}
+	for _, t := range timelines {
+		if t.Deployment != "" {
+			key := [2]string{t.Namespace, t.Deployment}
+			if byDeployment[key] == nil {
+				byDeployment[key] = &samples{}
+			}
+			add(byDeployment[key], t)
+		}
+		if t.Zone != "" {
+			if byZone[t.Zone] == nil {
+				byZone[t.Zone] = &samples{}
+			}
+			add(byZone[t.Zone], t)
+		}
+	}
+
+	for key, s := range byDeployment {
+		deployments = append(deployments, Summary{
+			Namespace:   key[0],
+			Deployment:  key[1],
+			Scheduling:  NewStats(s.scheduling),
+			Startup:     NewStats(s.startup),
+			TimeToReady: NewStats(s.ready),
+		})
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		a, b := deployments[i], deployments[j]
+		return a.Namespace+"/"+a.Deployment < b.Namespace+"/"+b.Deployment
+	})
+
+	for zone, s := range byZone {
+		zones = append(zones, Summary{
+			Zone:        zone,
+			Scheduling:  NewStats(s.scheduling),
+			Startup:     NewStats(s.startup),
+			TimeToReady: NewStats(s.ready),
+		})
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Zone < zones[j].Zone })
+
+	return deployments, zones
+}
@@ -0,0 +1,164 @@
+package timeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var start = time.Date(2022, 3, 1, 2, 0, 0, 0, time.UTC)
+
+func at(seconds int) metav1.Time {
+	return metav1.NewTime(start.Add(time.Duration(seconds) * time.Second))
+}
+
+var controller = true
+
+// web is a pod of the web deployment created at start.
+var web = &v1.Pod{
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace:         "default",
+		CreationTimestamp: at(0),
+		Labels:            map[string]string{appv1.DefaultDeploymentUniqueLabelKey: "5d4f8c"},
+		OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f8c", Controller: &controller}},
+	},
+}
+
+// startup returns the pod name of web on node, with the conditions that are
+// true at the given seconds past start and its container started at started,
+// if not negative.
+func startup(name, node string, conditions map[v1.PodConditionType]int, started int) *v1.Pod {
+	p := web.DeepCopy()
+	p.Name, p.UID = name, types.UID("uid-"+name)
+	p.Spec.NodeName = node
+	for _, c := range []v1.PodConditionType{v1.PodScheduled, v1.PodInitialized, v1.ContainersReady, v1.PodReady} {
+		seconds, ok := conditions[c]
+		status := v1.ConditionFalse
+		if ok {
+			status = v1.ConditionTrue
+		}
+		p.Status.Conditions = append(p.Status.Conditions, v1.PodCondition{Type: c, Status: status, LastTransitionTime: at(seconds)})
+	}
+	if started >= 0 {
+		p.Status.ContainerStatuses = []v1.ContainerStatus{{State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: at(started)}}}}
+	}
+	return p
+}
+
+func TestTracker(t *testing.T) {
+	zones := map[string]string{"node-a": "zone-a", "node-b": "zone-b"}
+	tracker := NewTracker(Options{Zone: func(node string) string { return zones[node] }})
+
+	for _, test := range []struct {
+		eventType string
+		pod       *v1.Pod
+	}{
+		{common.EventAdded, startup("web-1", "", nil, -1)},
+		{common.EventModified, startup("web-1", "node-a", map[v1.PodConditionType]int{v1.PodScheduled: 2}, -1)},
+		{common.EventModified, startup("web-1", "node-a", map[v1.PodConditionType]int{v1.PodScheduled: 2, v1.PodInitialized: 3}, 10)},
+		{common.EventModified, startup("web-1", "node-a", map[v1.PodConditionType]int{v1.PodScheduled: 2, v1.PodInitialized: 3, v1.ContainersReady: 12, v1.PodReady: 12}, 10)},
+		// a failed readiness probe, then ready again
+		{common.EventModified, startup("web-1", "node-a", map[v1.PodConditionType]int{v1.PodScheduled: 2, v1.PodInitialized: 3}, 10)},
+		{common.EventModified, startup("web-1", "node-a", map[v1.PodConditionType]int{v1.PodScheduled: 2, v1.PodInitialized: 3, v1.ContainersReady: 60, v1.PodReady: 60}, 10)},
+		{common.EventAdded, startup("web-2", "node-b", map[v1.PodConditionType]int{v1.PodScheduled: 4, v1.PodInitialized: 4, v1.ContainersReady: 30, v1.PodReady: 30}, 25)},
+	} {
+		e := common.Event{Key: "default/" + test.pod.Name, EventType: test.eventType, ResourceType: common.ResourcePod, Object: test.pod}
+		if err := tracker.Send(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timelines := tracker.Timelines(Query{Pod: "web-1"})
+	if len(timelines) != 1 {
+		t.Fatalf("expected the timeline of web-1, got %v", timelines)
+	}
+	web1 := timelines[0]
+	if web1.Zone != "zone-a" || web1.Deployment != "web" {
+		t.Errorf("unexpected zone or deployment %+v", web1)
+	}
+	for _, test := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"scheduling", web1.SchedulingLatency(), 2 * time.Second},
+		{"startup", web1.StartupLatency(), 7 * time.Second},
+		{"time to ready", web1.TimeToReady(), 12 * time.Second},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s latency: expected %v, got %v", test.name, test.want, test.got)
+		}
+	}
+
+	deployments, zoneSummaries := Summarize(tracker.Timelines(Query{Namespace: "default"}))
+	if len(deployments) != 1 || deployments[0].Deployment != "web" {
+		t.Fatalf("expected the summary of web, got %+v", deployments)
+	}
+	if ready := deployments[0].TimeToReady; ready.Count != 2 || ready.P50 != 12*time.Second || ready.Max != 30*time.Second {
+		t.Errorf("unexpected time to ready %+v", ready)
+	}
+	if len(zoneSummaries) != 2 || zoneSummaries[0].Zone != "zone-a" || zoneSummaries[1].Startup.P99 != 21*time.Second {
+		t.Errorf("unexpected zones %+v", zoneSummaries)
+	}
+}
+
+func TestTrackerExpiresDeletedPods(t *testing.T) {
+	now := start
+	tracker := NewTracker(Options{MaxAge: time.Hour})
+	tracker.now = func() time.Time { return now }
+
+	p := startup("web-1", "node-a", nil, -1)
+	tracker.Send(common.Event{Key: "default/web-1", EventType: common.EventAdded, ResourceType: common.ResourcePod, Object: p})
+	tracker.Send(common.Event{Key: "default/web-1", EventType: common.EventDeleted, ResourceType: common.ResourcePod, Object: p})
+	if len(tracker.Timelines(Query{})) != 1 {
+		t.Error("expected the timeline of the deleted pod to be kept")
+	}
+
+	now = now.Add(2 * time.Hour)
+	tracker.Send(common.Event{Key: "default/web-2", EventType: common.EventAdded, ResourceType: common.ResourcePod, Object: startup("web-2", "node-a", nil, -1)})
+	if timelines := tracker.Timelines(Query{}); len(timelines) != 1 || timelines[0].Name != "web-2" {
+		t.Errorf("expected the timeline of the deleted pod to expire, got %v", timelines)
+	}
+}
+
+func TestNewStats(t *testing.T) {
+	var samples []time.Duration
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, time.Duration(101-i)*time.Second)
+	}
+	stats := NewStats(samples)
+	if stats.Count != 100 || stats.P50 != 50*time.Second || stats.P90 != 90*time.Second || stats.P99 != 99*time.Second || stats.Max != 100*time.Second {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestTrackerExpiresVanishedPods(t *testing.T) {
+	existing := map[types.UID]bool{"uid-web-1": true, "uid-web-2": true}
+	now := start
+	tracker := NewTracker(Options{MaxAge: time.Hour, Exists: func(namespace, name string, uid types.UID) bool { return existing[uid] }})
+	tracker.now = func() time.Time { return now }
+
+	for _, name := range []string{"web-1", "web-2"} {
+		tracker.Send(common.Event{Key: "default/" + name, EventType: common.EventAdded, ResourceType: common.ResourcePod, Object: startup(name, "node-a", nil, -1)})
+	}
+
+	// web-1 is gone without its deletion being seen
+	delete(existing, "uid-web-1")
+	now = now.Add(pruneInterval)
+	tracker.Send(common.Event{Key: "default/web-2", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: startup("web-2", "node-a", nil, -1)})
+	for _, timeline := range tracker.Timelines(Query{}) {
+		if deleted := !timeline.Deleted.IsZero(); deleted != (timeline.Name == "web-1") {
+			t.Errorf("%s: expected deleted %v", timeline.Name, !deleted)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	tracker.Send(common.Event{Key: "default/web-2", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: startup("web-2", "node-a", nil, -1)})
+	if timelines := tracker.Timelines(Query{}); len(timelines) != 1 || timelines[0].Name != "web-2" {
+		t.Errorf("expected the timeline of the vanished pod to expire, got %v", timelines)
+	}
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// Span names
+const (
+	// SpanEvent spans an event from the informer until it has been
+	// processed. Its children are SpanProcess and SpanSend.
+	SpanEvent = "event"
+
+	// SpanProcess spans one attempt of the controller to process an event.
+	SpanProcess = "processItem"
+
+	// SpanSend spans a reply from the moment it is queued for a subscriber
+	// until it has been sent on its stream.
+	SpanSend = "send"
+)
+
+// Attribute keys
+const (
+	KeyEventKey        = attribute.Key("dwserver.event.key")
+	KeyEventType       = attribute.Key("dwserver.event.type")
+	KeyResource        = attribute.Key("dwserver.event.resource")
+	KeyResourceVersion = attribute.Key("dwserver.event.resource_version")
+	KeySubscriber      = attribute.Key("dwserver.subscriber")
+	KeyRetries         = attribute.Key("dwserver.event.retries")
+)
+
+// EventAttributes returns the attributes identifying e.
+func EventAttributes(e common.Event) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		KeyEventKey.String(e.Key),
+		KeyEventType.String(e.EventType),
+		KeyResource.String(e.ResourceType),
+	}
+	if obj, err := meta.Accessor(e.Object); err == nil {
+		attrs = append(attrs, KeyResourceVersion.String(obj.GetResourceVersion()))
+	}
+	return attrs
+}
+
+// StartEvent starts the span of e and returns e carrying it. The span is
+// ended by EndEvent once e has been processed.
+func StartEvent(e common.Event) common.Event {
+	e.Context, _ = Tracer().Start(context.Background(), SpanEvent,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(EventAttributes(e)...))
+	return e
+}
+
+// EndEvent ends the span of e, recording err if e was dropped because of it.
+func EndEvent(e common.Event, err error) {
+	EndSpan(trace.SpanFromContext(EventContext(e)), err)
+}
+
+// EventContext returns the context carrying the span of e.
+func EventContext(e common.Event) context.Context {
+	if e.Context == nil {
+		return context.Background()
+	}
+	return e.Context
+}
+
+// EndSpan ends span, recording err if it is not nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,79 @@
+// Package tracing traces pod events with OpenTelemetry, from the informer
+// through the workqueue to the streams of the subscribers.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
+)
+
+// instrumentationName names the tracer of dwserver.
+const instrumentationName = "github.com/bobbybho/k8s-deployment-watcher"
+
+// Options ...
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC receiver. Empty disables
+	// tracing.
+	Endpoint string
+
+	// Insecure exports without TLS.
+	Insecure bool
+
+	// SampleRatio is the fraction of traces sampled. Traces started by a
+	// client follow the sampling decision of the client.
+	SampleRatio float64
+
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+}
+
+// Setup installs the global tracer provider, exporting spans to the OTLP
+// endpoint of opts, and the W3C trace context propagator. The returned
+// function flushes the spans and stops the exporter. Without an endpoint
+// spans are not recorded.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	klog.InfoS("Exporting traces", "endpoint", opts.Endpoint, "sampleRatio", opts.SampleRatio)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of dwserver. It follows the global tracer
+// provider, so tests can install one with an in-memory exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
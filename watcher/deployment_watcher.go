@@ -4,36 +4,37 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/google/go-cmp/cmp"
-	appv1 "k8s.io/api/apps/v1"
-	"k8s.io/client-go/informers"
-	appinformers "k8s.io/client-go/informers/apps/v1"
-	"k8s.io/client-go/kubernetes"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 // DeploymentWatcher ...
 type DeploymentWatcher struct {
-	informerFactory    informers.SharedInformerFactory
-	deploymentInformer appinformers.DeploymentInformer
+	manager            *Manager
+	deploymentInformer cache.SharedIndexInformer
+	sinks              Sinks
 }
 
-// NewDeploymentWatcher ...
-func NewDeploymentWatcher(clientset kubernetes.Interface, namespace string) *DeploymentWatcher {
-	dw := &DeploymentWatcher{}
+// NewDeploymentWatcher watches the deployments of namespace and sends their
+// events to sinks.
+func NewDeploymentWatcher(manager *Manager, namespace string, sinks ...EventSink) *DeploymentWatcher {
+	dw := &DeploymentWatcher{manager: manager, sinks: sinks}
 
-	dw.informerFactory = informers.NewSharedInformerFactory(clientset, time.Second*30)
-	dw.informerFactory = informers.NewSharedInformerFactoryWithOptions(clientset, time.Second*30, informers.WithNamespace(namespace))
-	dw.deploymentInformer = dw.informerFactory.Apps().V1().Deployments()
+	if manager.MetadataOnlyDeployments() {
+		dw.deploymentInformer = manager.DeploymentMetadataInformer(namespace)
+	} else {
+		dw.deploymentInformer = manager.DeploymentInformer(namespace).Informer()
+	}
 
-	dw.deploymentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+	dw.deploymentInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    dw.deploymentAdd,
 		UpdateFunc: dw.deploymentUpdate,
 		DeleteFunc: dw.deploymentDelete,
 	})
 
-	klog.Infof("New Deployment Watcher in namespace %v", namespace)
+	klog.InfoS("Watching deployments", "namespace", namespace)
 
 	return dw
 }
@@ -41,33 +42,59 @@ func NewDeploymentWatcher(clientset kubernetes.Interface, namespace string) *Dep
 // WatchDeploymentEndpoints ...
 func (n *DeploymentWatcher) Run(stopCh chan struct{}) error {
 
-	// Starts all the shared informers that have been created by the factory so
-	// far.
-	n.informerFactory.Start(stopCh)
+	// Starts all the shared informers that have been registered with the
+	// manager so far.
+	n.manager.Start(stopCh)
 	// wait for the initial synchronization of the local cache.
-	if !cache.WaitForCacheSync(stopCh, n.deploymentInformer.Informer().HasSynced) {
+	if !cache.WaitForCacheSync(stopCh, n.deploymentInformer.HasSynced) {
 		return fmt.Errorf("Failed to sync")
 	}
 	return nil
 }
 
 func (n *DeploymentWatcher) deploymentAdd(obj interface{}) {
-	deployment := obj.(*appv1.Deployment)
-	klog.Infof("DEPLOYMENT CREATED: %s/%s %v", deployment.Namespace, deployment.Name, deployment.Status.Replicas)
+	n.sinks.send(deploymentEvent(obj.(metav1.Object), common.EventAdded))
 }
 
 func (n *DeploymentWatcher) deploymentUpdate(old, new interface{}) {
-	oldDeployment := old.(*appv1.Deployment)
-	newDeployment := new.(*appv1.Deployment)
-	klog.Infof(
-		"DEPLOYMENT UPDATED. %s/%s (%v)%v",
-		oldDeployment.Namespace, oldDeployment.Name, oldDeployment.Status.AvailableReplicas, newDeployment.Status.Replicas,
-	)
-
-	klog.Infof("%s", cmp.Diff(oldDeployment, newDeployment))
+	oldMeta, newMeta := old.(metav1.Object), new.(metav1.Object)
+
+	// periodic resyncs redeliver the cached object unchanged
+	if oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		klog.V(4).InfoS("Deployment resynced", "deployment", klog.KObj(newMeta), "resourceVersion", newMeta.GetResourceVersion())
+		return
+	}
+
+	event := deploymentEvent(newMeta, common.EventModified)
+	event.Old = old
+	n.sinks.send(event)
+
+	if eventType, ok := rolloutEventType(old, new); ok {
+		rollout := deploymentEvent(newMeta, eventType)
+		rollout.Old = old
+		n.sinks.send(rollout)
+	}
+
+	n.manager.reportUpdate(event)
 }
 
 func (n *DeploymentWatcher) deploymentDelete(obj interface{}) {
-	deployment := obj.(*appv1.Deployment)
-	klog.Infof("DEPLOYMENT DELETED: %s/%s", deployment.Namespace, deployment.Name)
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	if deployment, ok := obj.(metav1.Object); ok {
+		n.sinks.send(deploymentEvent(deployment, common.EventDeleted))
+	}
+}
+
+// deploymentEvent describes an event of deployment observed now.
+func deploymentEvent(deployment metav1.Object, eventType string) common.Event {
+	return common.Event{
+		Key:          deployment.GetNamespace() + "/" + deployment.GetName(),
+		EventType:    eventType,
+		ResourceType: common.ResourceDeployment,
+		Object:       deployment,
+		Time:         time.Now(),
+	}
 }
@@ -0,0 +1,79 @@
+package watcher
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeploymentUpdateSkipsResyncs(t *testing.T) {
+	sink := &recordingSink{}
+	dw := NewDeploymentWatcher(NewManager(fake.NewSimpleClientset(), DefaultOptions()), "default", sink)
+
+	old := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", ResourceVersion: "3"}}
+	dw.deploymentUpdate(old, old.DeepCopy())
+	if len(sink.events) != 0 {
+		t.Fatalf("expected the resync to be skipped, got %v", sink.events)
+	}
+
+	deployment := old.DeepCopy()
+	deployment.ResourceVersion = "4"
+	dw.deploymentUpdate(old, deployment)
+	if len(sink.events) != 1 {
+		t.Fatalf("expected the update of web, got %v", sink.events)
+	}
+	if e := sink.events[0]; e.Key != "default/web" || e.EventType != common.EventModified || e.Object != deployment || e.Old != old {
+		t.Errorf("expected the update of web with its old state, got %+v", e)
+	}
+}
+
+func TestRolloutEvents(t *testing.T) {
+	sink := &recordingSink{}
+	dw := NewDeploymentWatcher(NewManager(fake.NewSimpleClientset(), DefaultOptions()), "default", sink)
+
+	old := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", ResourceVersion: "1"}}
+	for i, test := range []struct {
+		reason   string
+		expected string
+	}{
+		{"NewReplicaSetCreated", common.EventRolloutStarted},
+		{"ReplicaSetUpdated", ""},
+		{"NewReplicaSetAvailable", common.EventRolloutCompleted},
+		{"NewReplicaSetAvailable", ""},
+		{"ReplicaSetUpdated", common.EventRolloutStarted},
+		{"ProgressDeadlineExceeded", common.EventRolloutFailed},
+	} {
+		deployment := old.DeepCopy()
+		deployment.ResourceVersion = fmt.Sprint(i + 2)
+		deployment.Status.Conditions = []appv1.DeploymentCondition{{Type: appv1.DeploymentProgressing, Status: v1.ConditionTrue, Reason: test.reason}}
+		sink.events = nil
+		dw.deploymentUpdate(old, deployment)
+
+		expected := []string{common.EventModified}
+		if test.expected != "" {
+			expected = append(expected, test.expected)
+		}
+		var eventTypes []string
+		for _, e := range sink.events {
+			eventTypes = append(eventTypes, e.EventType)
+			if e.Object != deployment {
+				t.Errorf("%d: expected the %s event to carry the deployment", i, e.EventType)
+			}
+		}
+		if !reflect.DeepEqual(eventTypes, expected) {
+			t.Errorf("%d: %s: expected %v, got %v", i, test.reason, expected, eventTypes)
+		}
+		old = deployment
+	}
+
+	// metadata-only deployments have no rollouts
+	if eventType, ok := rolloutEventType(&metav1.PartialObjectMetadata{}, &metav1.PartialObjectMetadata{}); ok {
+		t.Errorf("expected no rollout of metadata-only deployments, got %s", eventType)
+	}
+}
@@ -0,0 +1,54 @@
+package watcher
+
+import (
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+)
+
+// EventFilter selects events. Every non-empty list must contain the
+// respective value of an event for the filter to match it.
+type EventFilter struct {
+	// Resources are resource types, such as common.ResourcePod.
+	Resources []string
+
+	Namespaces []string
+
+	// Deployments are the names of deployments, matching their events and
+	// the events of their pods.
+	Deployments []string
+
+	// EventTypes are event types, such as common.EventModified.
+	EventTypes []string
+}
+
+// Matches reports whether f selects e.
+func (f EventFilter) Matches(e common.Event) bool {
+	if !matches(f.Resources, e.ResourceType) || !matches(f.EventTypes, e.EventType) {
+		return false
+	}
+	if len(f.Namespaces) > 0 {
+		namespace, _, _ := cache.SplitMetaNamespaceKey(e.Key)
+		if !matches(f.Namespaces, namespace) {
+			return false
+		}
+	}
+	return len(f.Deployments) == 0 || matches(f.Deployments, e.Deployment())
+}
+
+// MatchesResource reports whether f can select events of resource.
+func (f EventFilter) MatchesResource(resource string) bool {
+	return matches(f.Resources, resource)
+}
+
+func matches(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
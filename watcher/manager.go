@@ -0,0 +1,425 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	appinformers "k8s.io/client-go/informers/apps/v1"
+	corev1 "k8s.io/client-go/informers/core/v1"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
+	"k8s.io/client-go/kubernetes"
+	applisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/bobbybho/k8s-deployment-watcher/diff"
+)
+
+// DefaultResyncPeriod ...
+const DefaultResyncPeriod = time.Second * 30
+
+// clusterScope keys the factory of cluster scoped resources. It is not a
+// valid namespace name, so it never collides with a namespace scope.
+const clusterScope = "<cluster>"
+
+// Options ...
+type Options struct {
+	// Resync is the resync period of every informer. Zero disables resyncs.
+	Resync time.Duration
+
+	// ListPageSize is the number of objects requested per page when an
+	// informer lists. Zero leaves the default paging of the reflector.
+	ListPageSize int64
+
+	// TrimObjects strips managedFields, annotations and unused spec fields
+	// from pods and deployments before they are cached.
+	TrimObjects bool
+
+	// MetadataOnlyDeployments caches deployments as PartialObjectMetadata
+	// instead of full objects. MetadataClient must be set.
+	MetadataOnlyDeployments bool
+	MetadataClient          metadata.Interface
+
+	// Differ computes the changes of updated pods and deployments. Nil
+	// ignores diff.DefaultIgnorePaths.
+	Differ *diff.Differ
+
+	// OnUpdate, if set, is called with the changes of every update of a
+	// pod or deployment, from the informer goroutines.
+	OnUpdate func(Update)
+}
+
+// DefaultOptions ...
+func DefaultOptions() Options {
+	return Options{
+		Resync: DefaultResyncPeriod,
+	}
+}
+
+// Manager owns one SharedInformerFactory per namespace scope so that every
+// watcher and controller in the process shares a single watch per resource
+// against the API server.
+type Manager struct {
+	clientset kubernetes.Interface
+	opts      Options
+
+	lock      sync.Mutex
+	factories map[string]informers.SharedInformerFactory
+	scopes    map[string]*scope
+
+	// metadata-only informers are not tracked by any factory, so the manager
+	// starts them itself.
+	metadataInformers map[string]cache.SharedIndexInformer
+	metadataStarted   map[string]bool
+}
+
+// scope stops the informers of one namespace, either when the manager is
+// stopped or when the namespace is no longer watched.
+type scope struct {
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func (s *scope) stop() {
+	s.once.Do(func() { close(s.stopCh) })
+}
+
+// NewManager ...
+func NewManager(clientset kubernetes.Interface, opts Options) *Manager {
+	if opts.Differ == nil {
+		opts.Differ = defaultDiffer
+	}
+
+	return &Manager{
+		clientset:         clientset,
+		opts:              opts,
+		factories:         make(map[string]informers.SharedInformerFactory),
+		scopes:            make(map[string]*scope),
+		metadataInformers: make(map[string]cache.SharedIndexInformer),
+		metadataStarted:   make(map[string]bool),
+	}
+}
+
+// Differ returns the Differ of the changes of updates.
+func (m *Manager) Differ() *diff.Differ {
+	return m.opts.Differ
+}
+
+// MetadataOnlyDeployments reports whether deployments are cached as
+// PartialObjectMetadata.
+func (m *Manager) MetadataOnlyDeployments() bool {
+	return m.opts.MetadataOnlyDeployments
+}
+
+// Factory returns the informer factory for namespace, creating it on first
+// use. metav1.NamespaceAll selects all namespaces.
+func (m *Manager) Factory(namespace string) informers.SharedInformerFactory {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.factory(namespace)
+}
+
+func (m *Manager) factory(key string) informers.SharedInformerFactory {
+	if f, ok := m.factories[key]; ok {
+		return f
+	}
+
+	namespace := key
+	if key == clusterScope {
+		namespace = metav1.NamespaceAll
+	}
+
+	f := informers.NewSharedInformerFactoryWithOptions(m.clientset, m.opts.Resync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(m.tweakListOptions),
+	)
+	m.factories[key] = f
+
+	klog.InfoS("New informer factory", "namespace", namespace)
+
+	return f
+}
+
+// StopNamespace stops the informers of namespace and forgets them. Asking
+// for an informer of namespace afterwards creates a new one.
+func (m *Manager) StopNamespace(namespace string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if sc, ok := m.scopes[namespace]; ok {
+		sc.stop()
+		delete(m.scopes, namespace)
+	}
+
+	delete(m.factories, namespace)
+	delete(m.metadataInformers, namespace)
+	delete(m.metadataStarted, namespace)
+
+	klog.InfoS("Stopped informers", "namespace", namespace)
+}
+
+// PodInformer registers the pod informer for namespace. With TrimObjects set
+// the informer caches trimmed pods, see TrimPod.
+func (m *Manager) PodInformer(namespace string) corev1.PodInformer {
+	f := m.Factory(namespace)
+	if m.opts.TrimObjects {
+		// the factory keeps the first informer registered for a type, so
+		// this has to happen before the typed accessor below.
+		f.InformerFor(&v1.Pod{}, m.newTrimmedInformer(&v1.Pod{}, TrimPod, &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				m.tweakListOptions(&options)
+				return m.clientset.CoreV1().Pods(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				m.tweakListOptions(&options)
+				return m.clientset.CoreV1().Pods(namespace).Watch(context.TODO(), options)
+			},
+		}))
+	}
+
+	pods := f.Core().V1().Pods()
+	pods.Informer()
+	return pods
+}
+
+// DeploymentInformer registers the deployment informer for namespace. With
+// TrimObjects set the informer caches trimmed deployments, see
+// TrimDeployment.
+func (m *Manager) DeploymentInformer(namespace string) appinformers.DeploymentInformer {
+	f := m.Factory(namespace)
+	if m.opts.TrimObjects {
+		f.InformerFor(&appv1.Deployment{}, m.newTrimmedInformer(&appv1.Deployment{}, TrimDeployment, &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				m.tweakListOptions(&options)
+				return m.clientset.AppsV1().Deployments(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				m.tweakListOptions(&options)
+				return m.clientset.AppsV1().Deployments(namespace).Watch(context.TODO(), options)
+			},
+		}))
+	}
+
+	deployments := f.Apps().V1().Deployments()
+	deployments.Informer()
+	return deployments
+}
+
+// DeploymentMetadataInformer registers an informer that caches only the
+// metadata of the deployments in namespace.
+func (m *Manager) DeploymentMetadataInformer(namespace string) cache.SharedIndexInformer {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if informer, ok := m.metadataInformers[namespace]; ok {
+		return informer
+	}
+
+	gvr := appv1.SchemeGroupVersion.WithResource("deployments")
+	var lw cache.ListerWatcher = &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			m.tweakListOptions(&options)
+			return m.opts.MetadataClient.Resource(gvr).Namespace(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			m.tweakListOptions(&options)
+			return m.opts.MetadataClient.Resource(gvr).Namespace(namespace).Watch(context.TODO(), options)
+		},
+	}
+	if m.opts.TrimObjects {
+		lw = newTransformingListWatch(lw, TrimObjectMetadata)
+	}
+
+	informer := cache.NewSharedIndexInformer(lw, &metav1.PartialObjectMetadata{}, m.opts.Resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	m.metadataInformers[namespace] = informer
+
+	return informer
+}
+
+func (m *Manager) tweakListOptions(options *metav1.ListOptions) {
+	if m.opts.ListPageSize > 0 {
+		options.Limit = m.opts.ListPageSize
+	}
+}
+
+func (m *Manager) newTrimmedInformer(obj runtime.Object, transform cache.TransformFunc, lw cache.ListerWatcher) func(kubernetes.Interface, time.Duration) cache.SharedIndexInformer {
+	return func(_ kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(newTransformingListWatch(lw, transform), obj, resync,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	}
+}
+
+// ReplicaSetInformer registers the replicaset informer for namespace.
+func (m *Manager) ReplicaSetInformer(namespace string) appinformers.ReplicaSetInformer {
+	replicaSets := m.Factory(namespace).Apps().V1().ReplicaSets()
+	replicaSets.Informer()
+	return replicaSets
+}
+
+// NodeInformer registers the node informer. Nodes are cluster scoped, so the
+// informer always lives in the cluster scope factory.
+func (m *Manager) NodeInformer() corev1.NodeInformer {
+	m.lock.Lock()
+	f := m.factory(clusterScope)
+	m.lock.Unlock()
+
+	nodes := f.Core().V1().Nodes()
+	nodes.Informer()
+	return nodes
+}
+
+// EndpointSliceInformer registers the endpointslice informer for namespace.
+func (m *Manager) EndpointSliceInformer(namespace string) discoveryinformers.EndpointSliceInformer {
+	endpointSlices := m.Factory(namespace).Discovery().V1().EndpointSlices()
+	endpointSlices.Informer()
+	return endpointSlices
+}
+
+// PodLister ...
+func (m *Manager) PodLister(namespace string) corelisters.PodLister {
+	return m.PodInformer(namespace).Lister()
+}
+
+// DeploymentLister ...
+func (m *Manager) DeploymentLister(namespace string) applisters.DeploymentLister {
+	return m.DeploymentInformer(namespace).Lister()
+}
+
+// ReplicaSetLister ...
+func (m *Manager) ReplicaSetLister(namespace string) applisters.ReplicaSetLister {
+	return m.ReplicaSetInformer(namespace).Lister()
+}
+
+// NodeLister ...
+func (m *Manager) NodeLister() corelisters.NodeLister {
+	return m.NodeInformer().Lister()
+}
+
+// EndpointSliceLister ...
+func (m *Manager) EndpointSliceLister(namespace string) discoverylisters.EndpointSliceLister {
+	return m.EndpointSliceInformer(namespace).Lister()
+}
+
+// Start starts every informer registered so far. It is safe to call Start
+// again after registering more informers; running ones are left alone.
+func (m *Manager) Start(stopCh <-chan struct{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for key, f := range m.factories {
+		f.Start(m.scope(key, stopCh).stopCh)
+	}
+
+	for namespace, informer := range m.metadataInformers {
+		if !m.metadataStarted[namespace] {
+			go informer.Run(m.scope(namespace, stopCh).stopCh)
+			m.metadataStarted[namespace] = true
+		}
+	}
+}
+
+// scope returns the stop scope of key, tying a new one to stopCh.
+func (m *Manager) scope(key string, stopCh <-chan struct{}) *scope {
+	if sc, ok := m.scopes[key]; ok {
+		return sc
+	}
+
+	sc := &scope{stopCh: make(chan struct{})}
+	go func() {
+		select {
+		case <-stopCh:
+			sc.stop()
+		case <-sc.stopCh:
+		}
+	}()
+	m.scopes[key] = sc
+
+	return sc
+}
+
+// WaitForCacheSync waits for the initial synchronization of every started
+// informer.
+func (m *Manager) WaitForCacheSync(stopCh <-chan struct{}) error {
+	for namespace, f := range m.snapshot() {
+		for informerType, synced := range f.WaitForCacheSync(stopCh) {
+			if !synced {
+				return fmt.Errorf("failed to sync %v informer in namespace %q", informerType, namespace)
+			}
+		}
+	}
+
+	m.lock.Lock()
+	var synced []cache.InformerSynced
+	for namespace, informer := range m.metadataInformers {
+		if m.metadataStarted[namespace] {
+			synced = append(synced, informer.HasSynced)
+		}
+	}
+	m.lock.Unlock()
+
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		return fmt.Errorf("failed to sync deployment metadata informers")
+	}
+	return nil
+}
+
+// InformerSync is the synchronization state of an informer.
+type InformerSync struct {
+	// Namespace is empty for informers of all namespaces.
+	Namespace string
+	Informer  string
+	Synced    bool
+}
+
+// SyncState reports whether each started informer has synced, without
+// waiting.
+func (m *Manager) SyncState() []InformerSync {
+	// a closed channel makes WaitForCacheSync check the informers once
+	closed := make(chan struct{})
+	close(closed)
+
+	var state []InformerSync
+	for key, f := range m.snapshot() {
+		namespace := key
+		if key == clusterScope {
+			namespace = metav1.NamespaceAll
+		}
+		for informerType, synced := range f.WaitForCacheSync(closed) {
+			state = append(state, InformerSync{Namespace: namespace, Informer: informerType.String(), Synced: synced})
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for namespace, informer := range m.metadataInformers {
+		if m.metadataStarted[namespace] {
+			state = append(state, InformerSync{Namespace: namespace, Informer: "deployment metadata", Synced: informer.HasSynced()})
+		}
+	}
+	return state
+}
+
+func (m *Manager) snapshot() map[string]informers.SharedInformerFactory {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	factories := make(map[string]informers.SharedInformerFactory, len(m.factories))
+	for namespace, f := range m.factories {
+		factories[namespace] = f
+	}
+	return factories
+}
@@ -5,31 +5,26 @@ import (
 	"time"
 
 	"github.com/bobbybho/k8s-deployment-watcher/common"
-	"github.com/google/go-cmp/cmp"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/informers"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	corev1 "k8s.io/client-go/informers/core/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/util/workqueue"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
 // PodWatcher ...
 type PodWatcher struct {
-	informerFactory informers.SharedInformerFactory
-	podInformer     corev1.PodInformer
-	queue           workqueue.RateLimitingInterface
+	manager     *Manager
+	podInformer corev1.PodInformer
+	sinks       Sinks
 }
 
-// NewDeploymentWatcher ...
-func NewPodWatcher(clientset kubernetes.Interface, namespace string, queue workqueue.RateLimitingInterface) *PodWatcher {
-	pw := &PodWatcher{}
+// NewPodWatcher watches the pods of namespace and sends their events to
+// sinks.
+func NewPodWatcher(manager *Manager, namespace string, sinks ...EventSink) *PodWatcher {
+	pw := &PodWatcher{manager: manager, sinks: sinks}
 
-	pw.informerFactory = informers.NewSharedInformerFactory(clientset, time.Second*30)
-	pw.informerFactory = informers.NewSharedInformerFactoryWithOptions(clientset, time.Second*30, informers.WithNamespace(namespace))
-	pw.podInformer = pw.informerFactory.Core().V1().Pods()
-	pw.queue = queue
+	pw.podInformer = manager.PodInformer(namespace)
 
 	pw.podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    pw.podAdd,
@@ -37,7 +32,7 @@ func NewPodWatcher(clientset kubernetes.Interface, namespace string, queue workq
 		DeleteFunc: pw.podDelete,
 	})
 
-	klog.Infof("New POD Watcher in namespace %v", namespace)
+	klog.InfoS("Watching pods", "namespace", namespace)
 
 	return pw
 }
@@ -50,9 +45,9 @@ func (n *PodWatcher) GetShareIndexInformer() cache.SharedIndexInformer {
 // Run ...
 func (n *PodWatcher) Run(stopCh chan struct{}) error {
 
-	// Starts all the shared informers that have been created by the factory so
-	// far.
-	n.informerFactory.Start(stopCh)
+	// Starts all the shared informers that have been registered with the
+	// manager so far.
+	n.manager.Start(stopCh)
 	// wait for the initial synchronization of the local cache.
 	if !cache.WaitForCacheSync(stopCh, n.podInformer.Informer().HasSynced) {
 		return fmt.Errorf("failed to sync")
@@ -62,45 +57,74 @@ func (n *PodWatcher) Run(stopCh chan struct{}) error {
 
 func (n *PodWatcher) podAdd(obj interface{}) {
 	pod := obj.(*v1.Pod)
-	klog.Infof("POD CREATED: %s/%s %v", pod.Name, pod.Namespace, pod.Labels)
 
 	var event common.Event
 	var err error
 	event.Key, err = cache.MetaNamespaceKeyFunc(obj)
-	event.EventType = "create"
+	event.EventType = common.EventAdded
+	event.ResourceType = common.ResourcePod
+	event.Object = pod
+	event.Time = time.Now()
 	if err != nil {
-		n.queue.Add(event)
+		utilruntime.HandleError(err)
+		return
 	}
+	n.sinks.send(event)
 }
 
 func (n *PodWatcher) podUpdate(old, new interface{}) {
 	oldPod := old.(*v1.Pod)
 	newPod := new.(*v1.Pod)
-	klog.Infof(
-		"POD UPDATED. %s/%s",
-		oldPod.Namespace, oldPod.Name,
-	)
+
+	// periodic resyncs redeliver the cached object unchanged
+	if oldPod.ResourceVersion == newPod.ResourceVersion {
+		klog.V(4).InfoS("Pod resynced", "pod", klog.KObj(newPod), "resourceVersion", newPod.ResourceVersion)
+		return
+	}
 
 	var event common.Event
 	var err error
-	event.Key, err = cache.MetaNamespaceKeyFunc(old)
-	event.EventType = "update"
+	event.Key, err = cache.MetaNamespaceKeyFunc(new)
+	event.EventType = common.EventModified
+	event.ResourceType = common.ResourcePod
+	event.Object = newPod
+	event.Old = oldPod
+	event.Time = time.Now()
 	if err != nil {
-		n.queue.Add(event)
+		utilruntime.HandleError(err)
+		return
 	}
+	n.sinks.send(event)
 
-	klog.Infof("%s", cmp.Diff(oldPod, newPod))
+	n.manager.reportUpdate(event)
 }
 
 func (n *PodWatcher) podDelete(obj interface{}) {
-	pod := obj.(*v1.Pod)
-	klog.Infof("POD DELETED: %s/%s", pod.Name, pod.Namespace)
+	// the informer hands us a tombstone instead of the pod when the delete
+	// happened while its watch was disconnected.
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("unexpected object in pod delete: %T", obj))
+			return
+		}
+		if pod, ok = tombstone.Obj.(*v1.Pod); !ok {
+			utilruntime.HandleError(fmt.Errorf("unexpected object in pod tombstone: %T", tombstone.Obj))
+			return
+		}
+	}
 
 	var event common.Event
 	var err error
-	event.Key, err = cache.MetaNamespaceKeyFunc(obj)
-	event.EventType = "update"
+	event.Key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	event.EventType = common.EventDeleted
+	event.ResourceType = common.ResourcePod
+	event.Object = pod
+	event.Time = time.Now()
 	if err != nil {
-		n.queue.Add(event)
+		utilruntime.HandleError(err)
+		return
 	}
+	n.sinks.send(event)
 }
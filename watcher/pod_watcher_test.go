@@ -0,0 +1,142 @@
+package watcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// recordingSink records the events it is sent.
+type recordingSink struct {
+	lock   sync.Mutex
+	events []common.Event
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(e common.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestPodDeleteTombstone(t *testing.T) {
+	sink := &recordingSink{}
+	pw := NewPodWatcher(NewManager(fake.NewSimpleClientset(), DefaultOptions()), "default", sink)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "7"},
+		Spec:       v1.PodSpec{NodeName: "node-a"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	pw.podDelete(cache.DeletedFinalStateUnknown{Key: "default/web-1", Obj: pod})
+	// not a pod, dropped
+	pw.podDelete(cache.DeletedFinalStateUnknown{Key: "default/web-2", Obj: "web-2"})
+
+	if len(sink.events) != 1 {
+		t.Fatalf("expected the delete of web-1, got %v", sink.events)
+	}
+	e := sink.events[0]
+	if e.Key != "default/web-1" || e.EventType != common.EventDeleted || e.Object != pod {
+		t.Errorf("expected the delete of web-1 with its last state, got %+v", e)
+	}
+}
+
+func TestPodUpdateSkipsResyncs(t *testing.T) {
+	sink := &recordingSink{}
+	pw := NewPodWatcher(NewManager(fake.NewSimpleClientset(), DefaultOptions()), "default", sink)
+
+	old := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "7"}}
+	pw.podUpdate(old, old.DeepCopy())
+	if len(sink.events) != 0 {
+		t.Fatalf("expected the resync to be skipped, got %v", sink.events)
+	}
+
+	pod := old.DeepCopy()
+	pod.ResourceVersion = "8"
+	pw.podUpdate(old, pod)
+	if len(sink.events) != 1 {
+		t.Fatalf("expected the update of web-1, got %v", sink.events)
+	}
+	if e := sink.events[0]; e.Key != "default/web-1" || e.EventType != common.EventModified || e.Object != pod || e.Old != old {
+		t.Errorf("expected the update of web-1 with its old state, got %+v", e)
+	}
+}
+
+func TestResyncPeriod(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: "7"}}
+
+	for _, test := range []struct {
+		resync   time.Duration
+		resynced bool
+	}{
+		{time.Second, true},
+		{0, false},
+	} {
+		opts := DefaultOptions()
+		opts.Resync = test.resync
+		sink := &recordingSink{}
+		pw := NewPodWatcher(NewManager(fake.NewSimpleClientset(pod), opts), "default", sink)
+
+		resynced := make(chan struct{}, 1)
+		pw.GetShareIndexInformer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(interface{}, interface{}) {
+				select {
+				case resynced <- struct{}{}:
+				default:
+				}
+			},
+		})
+
+		stopCh := make(chan struct{})
+		if err := pw.Run(stopCh); err != nil {
+			close(stopCh)
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		select {
+		case <-resynced:
+			if !test.resynced {
+				t.Errorf("resync %v: expected no resync", test.resync)
+			}
+		case <-ctx.Done():
+			if test.resynced {
+				t.Errorf("resync %v: timed out waiting for a resync", test.resync)
+			}
+		}
+		cancel()
+		close(stopCh)
+
+		// only the add of web-1 reaches the sinks
+		sink.lock.Lock()
+		for _, e := range sink.events {
+			if e.EventType != common.EventAdded {
+				t.Errorf("resync %v: unexpected event %+v", test.resync, e)
+			}
+		}
+		sink.lock.Unlock()
+	}
+}
+
+func TestListPageSize(t *testing.T) {
+	for _, size := range []int64{0, 500} {
+		opts := DefaultOptions()
+		opts.ListPageSize = size
+		var options metav1.ListOptions
+		NewManager(fake.NewSimpleClientset(), opts).tweakListOptions(&options)
+		if options.Limit != size {
+			t.Errorf("expected a limit of %d, got %d", size, options.Limit)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package watcher
+
+import (
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	appv1 "k8s.io/api/apps/v1"
+)
+
+// Reasons of the Progressing condition of deployments, set by the deployment
+// controller.
+const (
+	reasonNewReplicaSetCreated     = "NewReplicaSetCreated"
+	reasonFoundNewReplicaSet       = "FoundNewReplicaSet"
+	reasonReplicaSetUpdated        = "ReplicaSetUpdated"
+	reasonNewReplicaSetAvailable   = "NewReplicaSetAvailable"
+	reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+)
+
+// rolloutEventType returns the rollout event type of the update of a
+// deployment from old to new, derived from the reason of its Progressing
+// condition: a rollout starts when the deployment starts progressing, and
+// completes or fails when its new replica set becomes available or misses
+// the progress deadline. Metadata-only deployments have no rollouts.
+func rolloutEventType(old, new interface{}) (string, bool) {
+	oldDeployment, ok := old.(*appv1.Deployment)
+	if !ok {
+		return "", false
+	}
+	newDeployment, ok := new.(*appv1.Deployment)
+	if !ok {
+		return "", false
+	}
+
+	oldReason, newReason := progressingReason(oldDeployment), progressingReason(newDeployment)
+	switch {
+	case oldReason == newReason:
+		return "", false
+	case progressing(newReason) && !progressing(oldReason):
+		return common.EventRolloutStarted, true
+	case newReason == reasonNewReplicaSetAvailable:
+		return common.EventRolloutCompleted, true
+	case newReason == reasonProgressDeadlineExceeded:
+		return common.EventRolloutFailed, true
+	}
+	return "", false
+}
+
+// progressing reports whether reason is the one of a rollout in progress.
+func progressing(reason string) bool {
+	return reason == reasonNewReplicaSetCreated || reason == reasonFoundNewReplicaSet || reason == reasonReplicaSetUpdated
+}
+
+// progressingReason returns the reason of the Progressing condition of
+// deployment, empty if it has none.
+func progressingReason(deployment *appv1.Deployment) string {
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appv1.DeploymentProgressing {
+			return c.Reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,498 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/cloudevents"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/publisher"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// Sink types
+const (
+	SinkLog     = "log"
+	SinkStdout  = "stdout"
+	SinkFile    = "file"
+	SinkWebhook = "webhook"
+	SinkNATS    = "nats"
+	SinkKafka   = "kafka"
+)
+
+// SinkTypes are the sink types NewSink builds.
+var SinkTypes = []string{SinkLog, SinkStdout, SinkFile, SinkWebhook, SinkNATS, SinkKafka}
+
+// Sink formats, the encodings of the events written by stdout, file,
+// webhook and publisher sinks
+const (
+	SinkFormatJSON        = "json"
+	SinkFormatCloudEvents = "cloudevents"
+)
+
+// SinkFormats are the formats of stdout, file, webhook and publisher sinks.
+var SinkFormats = []string{SinkFormatJSON, SinkFormatCloudEvents}
+
+// ErrSinkClosed is returned by Send once a sink has been closed.
+var ErrSinkClosed = fmt.Errorf("sink closed")
+
+// EventSink receives the events of a watcher. Send is called from the
+// informer goroutines, so it must not block for long; sinks calling remote
+// endpoints buffer the events.
+type EventSink interface {
+	// Name identifies the sink in logs.
+	Name() string
+
+	Send(e common.Event) error
+
+	// Close flushes the buffered events and releases the sink.
+	Close() error
+}
+
+// Sinks sends every event to all of its sinks.
+type Sinks []EventSink
+
+// Send sends e to every sink, a failing sink does not keep e from the
+// others.
+func (s Sinks) Send(e common.Event) error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.Send(e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", sink.Name(), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Close closes every sink.
+func (s Sinks) Close() error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", sink.Name(), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// For returns the sinks that may take events of resource. Sinks filtering
+// events by resource implement MatchesResource(resource string) bool.
+func (s Sinks) For(resource string) Sinks {
+	var sinks Sinks
+	for _, sink := range s {
+		if f, ok := sink.(interface{ MatchesResource(string) bool }); ok && !f.MatchesResource(resource) {
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+// send sends e to the sinks, logging the failures.
+func (s Sinks) send(e common.Event) {
+	if err := s.Send(e); err != nil {
+		klog.ErrorS(err, "Failed to send event", e.LogValues()...)
+	}
+}
+
+// SinkOptions describes a sink built by NewSink.
+type SinkOptions struct {
+	// Type is one of SinkTypes.
+	Type string
+
+	// URL is the endpoint webhook sinks POST to, the comma separated
+	// servers of nats sinks or brokers of kafka sinks.
+	URL string
+
+	// Topic is the subject prefix of nats sinks or the topic of kafka
+	// sinks.
+	Topic string
+
+	// Timeout bounds each webhook request or publish. Zero uses
+	// DefaultWebhookTimeout or DefaultPublishTimeout.
+	Timeout time.Duration
+
+	// Path is the file of file sinks.
+	Path string
+
+	// MaxSize is the size in bytes a file sink rotates at. Zero uses
+	// DefaultMaxFileSize.
+	MaxSize int64
+
+	// MaxBackups is the number of rotated files kept. Zero uses
+	// DefaultMaxBackups.
+	MaxBackups int
+
+	// Format is one of SinkFormats, SinkFormatJSON if empty. Log sinks take
+	// no format.
+	Format string
+
+	// BatchSize and FlushInterval batch the events of nats and kafka sinks,
+	// see PublisherOptions.
+	BatchSize     int
+	FlushInterval time.Duration
+
+	// DeadLetterPath is the file nats and kafka sinks append the events
+	// they could not publish to.
+	DeadLetterPath string
+}
+
+// Validate checks that o describes a sink without building it.
+func (o SinkOptions) Validate() error {
+	switch o.Type {
+	case SinkLog, SinkStdout:
+	case SinkFile:
+		if o.Path == "" {
+			return fmt.Errorf("file sink needs a path")
+		}
+	case SinkWebhook:
+		if o.URL == "" {
+			return fmt.Errorf("webhook sink needs a url")
+		}
+	case SinkNATS, SinkKafka:
+		if o.URL == "" || o.Topic == "" {
+			return fmt.Errorf("%s sink needs a url and a topic", o.Type)
+		}
+	default:
+		return fmt.Errorf("unsupported sink type %q, expected one of %s", o.Type, strings.Join(SinkTypes, ", "))
+	}
+
+	if o.Format != "" {
+		if o.Type == SinkLog {
+			return fmt.Errorf("log sinks take no format")
+		}
+		if !matches(SinkFormats, o.Format) {
+			return fmt.Errorf("unsupported sink format %q, expected one of %s", o.Format, strings.Join(SinkFormats, ", "))
+		}
+	}
+
+	if o.Timeout < 0 || o.MaxSize < 0 || o.MaxBackups < 0 || o.BatchSize < 0 || o.FlushInterval < 0 {
+		return fmt.Errorf("%s sink: timeout, maxSize, maxBackups, batchSize and flushInterval must not be negative", o.Type)
+	}
+	return nil
+}
+
+// ParseSink parses the short form of a sink: its type, followed by =PATH for
+// file sinks, =URL for webhook sinks, =FORMAT for stdout sinks or =URL/TOPIC
+// for nats and kafka sinks.
+func ParseSink(spec string) (SinkOptions, error) {
+	opts := SinkOptions{Type: spec}
+	if i := strings.Index(spec, "="); i >= 0 {
+		opts.Type = spec[:i]
+		switch target := spec[i+1:]; opts.Type {
+		case SinkStdout:
+			opts.Format = target
+		case SinkFile:
+			opts.Path = target
+		case SinkWebhook:
+			opts.URL = target
+		case SinkNATS, SinkKafka:
+			// the topic follows the last slash, after the one of the scheme
+			if j := strings.LastIndex(target, "/"); j > 0 && target[j-1] != '/' {
+				opts.URL, opts.Topic = target[:j], target[j+1:]
+			} else {
+				opts.URL = target
+			}
+		default:
+			return opts, fmt.Errorf("%s sinks take no target", opts.Type)
+		}
+	}
+	return opts, opts.Validate()
+}
+
+// String returns the short form of o, see ParseSink.
+func (o SinkOptions) String() string {
+	switch o.Type {
+	case SinkStdout:
+		if o.Format != "" {
+			return o.Type + "=" + o.Format
+		}
+		return o.Type
+	case SinkFile:
+		return o.Type + "=" + o.Path
+	case SinkWebhook:
+		return o.Type + "=" + o.URL
+	case SinkNATS, SinkKafka:
+		return o.Type + "=" + o.URL + "/" + o.Topic
+	default:
+		return o.Type
+	}
+}
+
+// NewSink builds the sink described by opts.
+func NewSink(opts SinkOptions) (EventSink, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch opts.Type {
+	case SinkLog:
+		return LogSink(), nil
+	case SinkStdout:
+		if opts.Format == SinkFormatCloudEvents {
+			return CloudEventsSink(SinkStdout, stdout), nil
+		}
+		return JSONSink(SinkStdout, stdout), nil
+	case SinkFile:
+		return NewFileSink(opts.Path, opts.MaxSize, opts.MaxBackups, opts.Format)
+	case SinkWebhook:
+		return NewWebhookSink(WebhookOptions{URL: opts.URL, Timeout: opts.Timeout, Format: opts.Format, MaxRetries: DefaultWebhookRetries})
+	case SinkNATS:
+		p, err := publisher.NewNATS(publisher.NATSOptions{URL: opts.URL, Subject: opts.Topic})
+		if err != nil {
+			return nil, err
+		}
+		return NewPublisherSink(p, opts.publisherOptions()), nil
+	default:
+		p, err := publisher.NewKafka(publisher.KafkaOptions{Brokers: strings.Split(opts.URL, ","), Topic: opts.Topic})
+		if err != nil {
+			return nil, err
+		}
+		return NewPublisherSink(p, opts.publisherOptions()), nil
+	}
+}
+
+func (o SinkOptions) publisherOptions() PublisherOptions {
+	return PublisherOptions{
+		Format:         o.Format,
+		BatchSize:      o.BatchSize,
+		FlushInterval:  o.FlushInterval,
+		Timeout:        o.Timeout,
+		DeadLetterPath: o.DeadLetterPath,
+	}
+}
+
+// NewSinks builds the sinks described by opts. The sinks built before a
+// failure are closed.
+func NewSinks(opts []SinkOptions) (Sinks, error) {
+	sinks := make(Sinks, 0, len(opts))
+	for _, o := range opts {
+		sink, err := NewSink(o)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// Record is the JSON representation of an event written by the stdout, file
+// and webhook sinks.
+type Record struct {
+	Time            time.Time   `json:"time"`
+	Type            string      `json:"type"`
+	Resource        string      `json:"resource"`
+	Key             string      `json:"key"`
+	ResourceVersion string      `json:"resourceVersion,omitempty"`
+	Object          interface{} `json:"object,omitempty"`
+}
+
+// NewRecord returns the record of e.
+func NewRecord(e common.Event) Record {
+	record := Record{
+		Time:     e.Time,
+		Type:     e.EventType,
+		Resource: e.ResourceType,
+		Key:      e.Key,
+		Object:   e.Object,
+	}
+	if obj, err := meta.Accessor(e.Object); err == nil {
+		record.ResourceVersion = obj.GetResourceVersion()
+	}
+	return record
+}
+
+// logSink logs the events.
+type logSink struct{}
+
+// LogSink logs every event at the default level.
+func LogSink() EventSink {
+	return logSink{}
+}
+
+func (logSink) Name() string { return SinkLog }
+
+func (logSink) Send(e common.Event) error {
+	klog.InfoS(logMessage(e), logValues(e)...)
+	return nil
+}
+
+func (logSink) Close() error { return nil }
+
+var eventVerbs = map[string]string{
+	common.EventAdded:    "added",
+	common.EventModified: "updated",
+	common.EventDeleted:  "deleted",
+
+	common.EventRolloutStarted:   "rollout started",
+	common.EventRolloutCompleted: "rollout completed",
+	common.EventRolloutFailed:    "rollout failed",
+}
+
+// logMessage returns the log message of e, such as "Pod added".
+func logMessage(e common.Event) string {
+	resource := e.ResourceType
+	if resource != "" {
+		resource = strings.ToUpper(resource[:1]) + resource[1:]
+	}
+	verb, ok := eventVerbs[e.EventType]
+	if !ok {
+		// anomalies are named after their type
+		verb = e.EventType
+	}
+	return resource + " " + verb
+}
+
+// logValues returns the log values of e and the state of its object.
+func logValues(e common.Event) []interface{} {
+	values := e.LogValues()
+	if old, err := meta.Accessor(e.Old); err == nil {
+		values = append(values, "oldResourceVersion", old.GetResourceVersion())
+	}
+
+	switch obj := e.Object.(type) {
+	case *v1.Pod:
+		if e.EventType == common.EventAdded {
+			values = append(values, "labels", obj.Labels)
+		}
+	case *appv1.Deployment:
+		if old, ok := e.Old.(*appv1.Deployment); ok {
+			values = append(values, "availableReplicas", old.Status.AvailableReplicas)
+		}
+		if e.EventType != common.EventDeleted {
+			values = append(values, "replicas", obj.Status.Replicas)
+		}
+	}
+	return values
+}
+
+// stdout is the writer of stdout sinks, shared so that their lines do not
+// interleave.
+var stdout = &lockedWriter{w: os.Stdout}
+
+// jsonSink writes the records of the events as JSON lines.
+type jsonSink struct {
+	name   string
+	w      io.Writer
+	encode func(common.Event) ([]byte, error)
+}
+
+// JSONSink writes the record of every event to w as a line of JSON. w must be
+// safe for concurrent use, it is not closed with the sink.
+func JSONSink(name string, w io.Writer) EventSink {
+	return &jsonSink{name: name, w: w, encode: recordLine}
+}
+
+// CloudEventsSink writes every event to w as a CloudEvent in the structured
+// mode, one per line. w must be safe for concurrent use, it is not closed
+// with the sink.
+func CloudEventsSink(name string, w io.Writer) EventSink {
+	return &jsonSink{name: name, w: w, encode: cloudEventLine}
+}
+
+func (s *jsonSink) Name() string { return s.name }
+
+func (s *jsonSink) Send(e common.Event) error {
+	line, err := s.encode(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(line)
+	return err
+}
+
+func (s *jsonSink) Close() error { return nil }
+
+// recordLine returns the record of e as a line of JSON.
+func recordLine(e common.Event) ([]byte, error) {
+	line, err := json.Marshal(NewRecord(e))
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// cloudEventLine returns the structured CloudEvent of e as a line of JSON.
+func cloudEventLine(e common.Event) ([]byte, error) {
+	line, err := cloudevents.Structured(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// lineEncoder returns the encoding of the events of format, one of
+// SinkFormats.
+func lineEncoder(format string) func(common.Event) ([]byte, error) {
+	if format == SinkFormatCloudEvents {
+		return cloudEventLine
+	}
+	return recordLine
+}
+
+// lockedWriter serializes the writes to w.
+type lockedWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.w.Write(p)
+}
+
+// deadLetter is an event a sink could not deliver, as appended to a
+// dead-letter file.
+type deadLetter struct {
+	Time     time.Time `json:"time"`
+	URL      string    `json:"url,omitempty"`
+	Sink     string    `json:"sink,omitempty"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Event    Record    `json:"event"`
+	Body     string    `json:"body,omitempty"`
+}
+
+// writeDeadLetter appends letter to the file at path as a line of JSON. An
+// empty path drops it.
+func writeDeadLetter(path string, letter deadLetter) {
+	if path == "" {
+		return
+	}
+
+	line, err := json.Marshal(letter)
+	if err != nil {
+		klog.ErrorS(err, "Failed to encode dead letter", "path", path)
+		return
+	}
+
+	// sinks drop events from Send while their goroutines write too, and may
+	// share a file
+	deadLetterLock.Lock()
+	defer deadLetterLock.Unlock()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err == nil {
+		_, err = file.Write(append(line, '\n'))
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to write dead letter", "path", path)
+	}
+}
+
+// deadLetterLock serializes the writes of dead letters.
+var deadLetterLock sync.Mutex
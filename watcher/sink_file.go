@@ -0,0 +1,128 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+)
+
+const (
+	// DefaultMaxFileSize is the size file sinks rotate at.
+	DefaultMaxFileSize = 100 << 20
+
+	// DefaultMaxBackups is the number of rotated files file sinks keep.
+	DefaultMaxBackups = 3
+)
+
+// fileSink appends the events to a file as JSON lines. When
+// the file would grow past maxSize it is renamed to path.1, path.1 to path.2
+// and so on, and the oldest backup is removed.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	encode     func(common.Event) ([]byte, error)
+
+	// lock guards the file and its size
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink appends the events to the file at path in format, one of
+// SinkFormats, rotating it at maxSize bytes and keeping maxBackups rotated
+// files.
+func NewFileSink(path string, maxSize int64, maxBackups int, format string) (EventSink, error) {
+	if maxSize == 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	if maxBackups == 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	s := &fileSink{path: path, maxSize: maxSize, maxBackups: maxBackups, encode: lineEncoder(format)}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) Name() string { return SinkFile + ":" + s.path }
+
+func (s *fileSink) Send(e common.Event) error {
+	line, err := s.encode(e)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return ErrSinkClosed
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	if err := os.Remove(s.backup(s.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := s.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(s.backup(i), s.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.backup(1)); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *fileSink) backup(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
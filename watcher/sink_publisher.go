@@ -0,0 +1,278 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/cloudevents"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/publisher"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultBatchSize is the number of events publisher sinks publish at
+	// once.
+	DefaultBatchSize = 100
+
+	// DefaultFlushInterval is the longest publisher sinks hold an event to
+	// fill a batch.
+	DefaultFlushInterval = time.Second
+
+	// DefaultPublishTimeout bounds each publish of a batch.
+	DefaultPublishTimeout = 10 * time.Second
+)
+
+// publisherBuffer is the number of events a publisher sink holds while its
+// broker is unavailable, further events are dropped.
+const publisherBuffer = 10000
+
+// PublisherOptions describes a publisher sink.
+type PublisherOptions struct {
+	// Format is one of SinkFormats, SinkFormatJSON if empty.
+	Format string
+
+	// BatchSize is the number of events published at once. Zero uses
+	// DefaultBatchSize.
+	BatchSize int
+
+	// FlushInterval is the longest an event waits for its batch to fill.
+	// Zero uses DefaultFlushInterval.
+	FlushInterval time.Duration
+
+	// Timeout bounds each publish. Zero uses DefaultPublishTimeout.
+	Timeout time.Duration
+
+	// Backoff is the delay before retrying a failed batch, doubled up to
+	// MaxBackoff for every further retry. Zero uses DefaultWebhookBackoff
+	// and DefaultWebhookMaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// DeadLetterPath is the file the events that could not be published
+	// are appended to, as JSON lines. Empty only logs them.
+	DeadLetterPath string
+}
+
+// publisherSink publishes the events in batches from its own goroutine, in
+// the order they were sent. A failed batch is retried until the broker
+// acknowledges it, so the events are delivered at least once.
+type publisherSink struct {
+	publisher publisher.Publisher
+	opts      PublisherOptions
+	encode    func(common.Event) ([]byte, error)
+
+	// lock guards closed, so that Send never sends on the closed events
+	lock   sync.RWMutex
+	closed bool
+	events chan common.Event
+	done   chan struct{}
+
+	// stop cancels the backoff of retries once the sink is closed
+	ctx  context.Context
+	stop context.CancelFunc
+}
+
+// NewPublisherSink publishes the events with p, keyed by the namespace and
+// the deployment of their objects. The sink closes p.
+func NewPublisherSink(p publisher.Publisher, opts PublisherOptions) EventSink {
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultPublishTimeout
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = DefaultWebhookBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultWebhookMaxBackoff
+	}
+
+	s := &publisherSink{
+		publisher: p,
+		opts:      opts,
+		encode:    messageEncoder(opts.Format),
+		events:    make(chan common.Event, publisherBuffer),
+		done:      make(chan struct{}),
+	}
+	s.ctx, s.stop = context.WithCancel(context.Background())
+
+	go s.run()
+	return s
+}
+
+func (s *publisherSink) Name() string { return s.publisher.Name() }
+
+func (s *publisherSink) Send(e common.Event) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	// an unavailable broker must not hold up the informer
+	select {
+	case s.events <- e:
+		return nil
+	default:
+		s.deadLetter(e, 0, fmt.Errorf("broker is not keeping up"))
+		return fmt.Errorf("broker is not keeping up, dropped event")
+	}
+}
+
+// Close publishes the buffered events, without waiting to retry failed
+// batches, and closes the publisher.
+func (s *publisherSink) Close() error {
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.lock.Unlock()
+
+	s.stop()
+	<-s.done
+	return s.publisher.Close()
+}
+
+func (s *publisherSink) run() {
+	defer close(s.done)
+
+	var (
+		batch = make([]common.Event, 0, s.opts.BatchSize)
+		flush <-chan time.Time
+	)
+	for {
+		select {
+		case e, ok := <-s.events:
+			if !ok {
+				s.publish(batch)
+				return
+			}
+			if batch = append(batch, e); len(batch) == 1 {
+				flush = time.After(s.opts.FlushInterval)
+			}
+			if len(batch) < s.opts.BatchSize {
+				continue
+			}
+		case <-flush:
+		}
+
+		s.publish(batch)
+		batch, flush = batch[:0], nil
+	}
+}
+
+// publish publishes batch until the broker acknowledges it or the sink is
+// closed.
+func (s *publisherSink) publish(batch []common.Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	events := make([]common.Event, 0, len(batch))
+	msgs := make([]publisher.Message, 0, len(batch))
+	for _, e := range batch {
+		msg, err := s.message(e)
+		if err != nil {
+			s.deadLetter(e, 0, err)
+			continue
+		}
+		events = append(events, e)
+		msgs = append(msgs, msg)
+	}
+
+	backoff := s.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+		err := s.publisher.Publish(ctx, msgs)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		klog.V(2).InfoS("Publishing events failed, retrying", "sink", s.Name(), "events", len(msgs), "attempt", attempt, "backoff", backoff, "err", err)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			for _, e := range events {
+				s.deadLetter(e, attempt, err)
+			}
+			return
+		}
+		if backoff *= 2; backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+	}
+}
+
+// message returns the message of e.
+func (s *publisherSink) message(e common.Event) (publisher.Message, error) {
+	value, err := s.encode(e)
+	if err != nil {
+		return publisher.Message{}, err
+	}
+
+	contentType := "application/json"
+	if s.opts.Format == SinkFormatCloudEvents {
+		contentType = cloudevents.ContentType
+	}
+
+	return publisher.Message{
+		Key:   PublishKey(e),
+		ID:    cloudevents.ID(e),
+		Value: value,
+		Headers: map[string]string{
+			"content-type": contentType,
+			"type":         cloudevents.Type(e.ResourceType, e.EventType),
+		},
+	}, nil
+}
+
+// PublishKey returns the key events are published with, namespace/deployment
+// for deployments and their pods, so that the events of a pod stay in order.
+// Pods outside of deployments are keyed by namespace/name.
+func PublishKey(e common.Event) string {
+	deployment := e.Deployment()
+	if deployment == "" {
+		return e.Key
+	}
+
+	namespace, _, err := cache.SplitMetaNamespaceKey(e.Key)
+	if err != nil || namespace == "" {
+		return deployment
+	}
+	return namespace + "/" + deployment
+}
+
+// messageEncoder returns the encoding of the messages of format, one of
+// SinkFormats.
+func messageEncoder(format string) func(common.Event) ([]byte, error) {
+	if format == SinkFormatCloudEvents {
+		return cloudevents.Structured
+	}
+	return func(e common.Event) ([]byte, error) {
+		return json.Marshal(NewRecord(e))
+	}
+}
+
+// deadLetter records an event that could not be published.
+func (s *publisherSink) deadLetter(e common.Event, attempts int, err error) {
+	klog.ErrorS(err, "Failed to publish event", append(e.LogValues(), "sink", s.Name(), "attempts", attempts)...)
+	writeDeadLetter(s.opts.DeadLetterPath, deadLetter{
+		Time:     time.Now(),
+		Sink:     s.Name(),
+		Attempts: attempts,
+		Error:    err.Error(),
+		Event:    NewRecord(e),
+	})
+}
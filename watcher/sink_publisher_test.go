@@ -0,0 +1,294 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/publisher"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePublisher records the batches it is passed, failing the first
+// failures of them.
+type fakePublisher struct {
+	lock     sync.Mutex
+	failures int
+	attempts int
+	batches  [][]publisher.Message
+	closed   bool
+
+	// published receives the number of attempts after every attempt
+	published chan int
+}
+
+func newFakePublisher(failures int) *fakePublisher {
+	return &fakePublisher{failures: failures, published: make(chan int, 100)}
+}
+
+func (p *fakePublisher) Name() string { return "fake" }
+
+func (p *fakePublisher) Publish(ctx context.Context, msgs []publisher.Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.attempts++
+	p.published <- p.attempts
+	if p.attempts <= p.failures {
+		return fmt.Errorf("broker unavailable")
+	}
+	p.batches = append(p.batches, append([]publisher.Message(nil), msgs...))
+	return nil
+}
+
+func (p *fakePublisher) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	return nil
+}
+
+func podEvent(name string) common.Event {
+	return common.Event{
+		Key:          "default/" + name,
+		EventType:    common.EventModified,
+		ResourceType: common.ResourcePod,
+		Object:       deploymentPod(name),
+		Time:         time.Date(2022, 3, 1, 2, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestPublisherSinkBatches(t *testing.T) {
+	p := newFakePublisher(0)
+	sink := NewPublisherSink(p, PublisherOptions{BatchSize: 2, FlushInterval: time.Hour})
+
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		if err := sink.Send(podEvent(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.closed {
+		t.Error("expected the publisher to be closed with the sink")
+	}
+	if len(p.batches) != 2 || len(p.batches[0]) != 2 || len(p.batches[1]) != 1 {
+		t.Fatalf("expected batches of 2 and 1 messages, got %v", p.batches)
+	}
+
+	msg := p.batches[1][0]
+	if msg.Key != "default/web" {
+		t.Errorf("expected the key of the deployment, got %q", msg.Key)
+	}
+	if msg.ID == "" || msg.Headers["type"] != "io.dw.pod.modified" || msg.Headers["content-type"] != "application/json" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if err := sink.Send(podEvent("web-4")); err != ErrSinkClosed {
+		t.Errorf("expected ErrSinkClosed after Close, got %v", err)
+	}
+}
+
+func TestPublisherSinkFlushInterval(t *testing.T) {
+	p := newFakePublisher(0)
+	sink := NewPublisherSink(p, PublisherOptions{FlushInterval: 10 * time.Millisecond})
+	defer sink.Close()
+
+	if err := sink.Send(podEvent("web-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-p.published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the partial batch was not flushed")
+	}
+}
+
+func TestPublisherSinkRetries(t *testing.T) {
+	p := newFakePublisher(2)
+	sink := NewPublisherSink(p, PublisherOptions{FlushInterval: time.Millisecond, Backoff: time.Millisecond})
+
+	if err := sink.Send(podEvent("web-1")); err != nil {
+		t.Fatal(err)
+	}
+	for attempts := 0; attempts < 3; {
+		select {
+		case attempts = <-p.published:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected 3 attempts, got %d", attempts)
+		}
+	}
+	sink.Close()
+
+	if len(p.batches) != 1 || p.batches[0][0].Key != "default/web" {
+		t.Errorf("expected the event to be published once it succeeded, got %v", p.batches)
+	}
+}
+
+func TestPublisherSinkDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "publisher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/dead-letters.jsonl"
+	p := newFakePublisher(1000)
+	sink := NewPublisherSink(p, PublisherOptions{FlushInterval: time.Millisecond, Backoff: time.Hour, DeadLetterPath: path})
+
+	if err := sink.Send(podEvent("web-1")); err != nil {
+		t.Fatal(err)
+	}
+	<-p.published
+	sink.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 {
+		t.Error("expected the event that could not be published in the dead letters")
+	}
+}
+
+func TestPublishKey(t *testing.T) {
+	standalone := podEvent("debug")
+	standalone.Object = deploymentPod("debug")
+	standalone.Object.(metav1.Object).SetOwnerReferences(nil)
+
+	deployment := common.Event{
+		Key:          "default/web",
+		EventType:    common.EventModified,
+		ResourceType: common.ResourceDeployment,
+		Object:       &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+	}
+
+	for _, test := range []struct {
+		e    common.Event
+		want string
+	}{
+		{podEvent("web-1"), "default/web"},
+		{deployment, "default/web"},
+		{standalone, "default/debug"},
+	} {
+		if got := PublishKey(test.e); got != test.want {
+			t.Errorf("PublishKey(%s) = %q, expected %q", test.e.Key, got, test.want)
+		}
+	}
+}
+
+// runNATSServer starts a NATS server with JetStream and a stream capturing
+// the subjects of subject.
+func runNATSServer(t *testing.T, subject string) (*server.Server, func()) {
+	dir, err := ioutil.TempDir("", "nats")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		t.Fatal("the NATS server did not start")
+	}
+	cleanup := func() {
+		s.Shutdown()
+		os.RemoveAll(dir)
+	}
+
+	conn, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	js, err := conn.JetStream()
+	if err == nil {
+		_, err = js.AddStream(&nats.StreamConfig{Name: "DW", Subjects: []string{subject + ".>"}})
+	}
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return s, cleanup
+}
+
+func TestNATSSink(t *testing.T) {
+	s, cleanup := runNATSServer(t, "dw")
+	defer cleanup()
+
+	opts, err := ParseSink("nats=" + s.ClientURL() + "/dw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.FlushInterval = 10 * time.Millisecond
+	sink, err := NewSink(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []common.Event{podEvent("web-1"), podEvent("web-2"), podEvent("web-1")}
+	events[2].Object.(metav1.Object).SetResourceVersion("8")
+	for _, e := range events {
+		if err := sink.Send(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a redelivery of a published event is dropped by JetStream
+	if err := sink.Send(podEvent("web-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := js.StreamInfo("DW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != uint64(len(events)) {
+		t.Fatalf("expected %d messages in the stream, got %d", len(events), info.State.Msgs)
+	}
+
+	sub, err := js.SubscribeSync("dw.default.web", nats.DeliverAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range events {
+		msg, err := sub.NextMsg(5 * time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Subject != "dw.default.web" {
+			t.Errorf("message %d: unexpected subject %q", i, msg.Subject)
+		}
+		var record Record
+		if err := json.Unmarshal(msg.Data, &record); err != nil || record.Key != e.Key || record.ResourceVersion != e.Object.(metav1.Object).GetResourceVersion() {
+			t.Errorf("message %d: expected %s, got %s", i, e.Key, msg.Data)
+		}
+	}
+}
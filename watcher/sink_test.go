@@ -0,0 +1,179 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func sinkEvent(name string) common.Event {
+	return common.Event{
+		Key:          "default/" + name,
+		EventType:    common.EventAdded,
+		ResourceType: common.ResourcePod,
+		Object:       &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: "7"}},
+		Time:         time.Date(2022, 3, 1, 2, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestParseSink(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    SinkOptions
+		wantErr bool
+	}{
+		{spec: "log", want: SinkOptions{Type: SinkLog}},
+		{spec: "stdout", want: SinkOptions{Type: SinkStdout}},
+		{spec: "stdout=cloudevents", want: SinkOptions{Type: SinkStdout, Format: SinkFormatCloudEvents}},
+		{spec: "stdout=xml", wantErr: true},
+		{spec: "file=/var/log/pods.jsonl", want: SinkOptions{Type: SinkFile, Path: "/var/log/pods.jsonl"}},
+		{spec: "webhook=https://ci.example.com/hook?a=b", want: SinkOptions{Type: SinkWebhook, URL: "https://ci.example.com/hook?a=b"}},
+		{spec: "nats=nats://nats-1:4222,nats://nats-2:4222/dw.events", want: SinkOptions{Type: SinkNATS, URL: "nats://nats-1:4222,nats://nats-2:4222", Topic: "dw.events"}},
+		{spec: "kafka=kafka-0:9092/dw-events", want: SinkOptions{Type: SinkKafka, URL: "kafka-0:9092", Topic: "dw-events"}},
+		{spec: "nats=nats://nats-1:4222", wantErr: true},
+		{spec: "file", wantErr: true},
+		{spec: "log=x", wantErr: true},
+		{spec: "kafka", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSink(test.spec)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseSink(%q) returned error %v", test.spec, err)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseSink(%q) = %+v, expected %+v", test.spec, got, test.want)
+		}
+		if got.String() != test.spec {
+			t.Errorf("String() = %q, expected %q", got.String(), test.spec)
+		}
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := JSONSink("buffer", &out)
+	if err := sink.Send(sinkEvent("web-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	var record struct {
+		Time            time.Time `json:"time"`
+		Type            string    `json:"type"`
+		Resource        string    `json:"resource"`
+		Key             string    `json:"key"`
+		ResourceVersion string    `json:"resourceVersion"`
+		Object          v1.Pod    `json:"object"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("invalid record %q: %v", out.String(), err)
+	}
+	if record.Type != common.EventAdded || record.Resource != common.ResourcePod || record.Key != "default/web-1" ||
+		record.ResourceVersion != "7" || record.Object.Name != "web-1" || !record.Time.Equal(sinkEvent("web-1").Time) {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if !strings.HasSuffix(out.String(), "}\n") {
+		t.Errorf("expected one line, got %q", out.String())
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	line, err := recordLine(sinkEvent("web-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two records per file
+	path := filepath.Join(dir, "pods.jsonl")
+	sink, err := NewFileSink(path, int64(2*len(line)), 2, SinkFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := sink.Send(sinkEvent("web-1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(sinkEvent("web-1")); err != ErrSinkClosed {
+		t.Errorf("Send after Close returned %v, expected %v", err, ErrSinkClosed)
+	}
+
+	for file, records := range map[string]int{path: 1, path + ".1": 2, path + ".2": 2} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "\n"); n != records {
+			t.Errorf("%s has %d records, expected %d", filepath.Base(file), n, records)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups, stat of a third returned %v", err)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan Record, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var record Record
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			t.Errorf("invalid record: %v", err)
+		}
+		received <- record
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"web-1", "web-2"} {
+		if err := sink.Send(sinkEvent(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close sends the buffered events
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(received)
+
+	var keys []string
+	for record := range received {
+		keys = append(keys, record.Key)
+	}
+	if strings.Join(keys, ",") != "default/web-1,default/web-2" {
+		t.Errorf("received %v", keys)
+	}
+	if err := sink.Send(sinkEvent("web-3")); err != ErrSinkClosed {
+		t.Errorf("Send after Close returned %v, expected %v", err, ErrSinkClosed)
+	}
+}
@@ -0,0 +1,358 @@
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/cloudevents"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultWebhookTimeout bounds the requests of webhook sinks.
+	DefaultWebhookTimeout = 10 * time.Second
+
+	// DefaultWebhookRetries is the number of times webhook sinks built from
+	// a URL retry a failed request.
+	DefaultWebhookRetries = 3
+
+	// DefaultWebhookBackoff is the delay before the first retry, doubled
+	// for every further retry.
+	DefaultWebhookBackoff = time.Second
+
+	// DefaultWebhookMaxBackoff caps the delay between retries.
+	DefaultWebhookMaxBackoff = 30 * time.Second
+)
+
+// Webhook payload formats
+const (
+	WebhookFormatJSON        = "json"
+	WebhookFormatSlack       = "slack"
+	WebhookFormatCloudEvents = "cloudevents"
+
+	// WebhookFormatCloudEventsBinary sends the data of the CloudEvent as the
+	// body and its attributes as ce- headers.
+	WebhookFormatCloudEventsBinary = "cloudevents-binary"
+)
+
+// WebhookFormats are the payload formats of webhook sinks.
+var WebhookFormats = []string{WebhookFormatJSON, WebhookFormatSlack, WebhookFormatCloudEvents, WebhookFormatCloudEventsBinary}
+
+// SignatureHeader holds the HMAC-SHA256 of the body of signed webhook
+// requests, as sha256=HEX.
+const SignatureHeader = "X-Dw-Signature-256"
+
+// webhookBuffer is the number of events a webhook sink holds while its
+// endpoint is slow, further events are dropped.
+const webhookBuffer = 1000
+
+// WebhookOptions describes a webhook sink.
+type WebhookOptions struct {
+	URL string
+
+	// Timeout bounds each request. Zero uses DefaultWebhookTimeout.
+	Timeout time.Duration
+
+	// Format is one of WebhookFormats, WebhookFormatJSON if empty.
+	Format string
+
+	// Template is a text/template rendering the body from the Record of the
+	// event, in place of Format. It can use the json function.
+	Template string
+
+	// ContentType of templated bodies, application/json if empty.
+	ContentType string
+
+	// Secret signs the requests in SignatureHeader. Empty does not sign.
+	Secret []byte
+
+	// Filter selects the events sent.
+	Filter EventFilter
+
+	// MaxRetries is the number of times a failed request is retried.
+	// Requests failing with a 4xx status other than 429 are not retried.
+	MaxRetries int
+
+	// Backoff is the delay before the first retry, doubled up to
+	// MaxBackoff for every further retry. Zero uses DefaultWebhookBackoff
+	// and DefaultWebhookMaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// DeadLetterPath is the file the events that could not be delivered
+	// are appended to, as JSON lines. Empty only logs them.
+	DeadLetterPath string
+}
+
+// Validate checks that o describes a webhook sink without building it.
+func (o WebhookOptions) Validate() error {
+	if o.URL == "" {
+		return fmt.Errorf("webhook needs a url")
+	}
+	if o.Template == "" && o.Format != "" && !matches(WebhookFormats, o.Format) {
+		return fmt.Errorf("unsupported webhook format %q, expected one of %s", o.Format, strings.Join(WebhookFormats, ", "))
+	}
+	if o.Template != "" {
+		if _, err := parseWebhookTemplate(o.Template); err != nil {
+			return err
+		}
+	}
+	if o.Timeout < 0 || o.MaxRetries < 0 || o.Backoff < 0 || o.MaxBackoff < 0 {
+		return fmt.Errorf("webhook timeout, maxRetries and backoff must not be negative")
+	}
+	return nil
+}
+
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// webhookSink POSTs the events to a URL, one request per event, from its own
+// goroutine and in the order they were sent.
+type webhookSink struct {
+	opts     WebhookOptions
+	client   *http.Client
+	template *template.Template
+
+	// lock guards closed, so that Send never sends on the closed events
+	lock   sync.RWMutex
+	closed bool
+	events chan common.Event
+	done   chan struct{}
+
+	// stop cancels the backoff of retries once the sink is closed
+	ctx  context.Context
+	stop context.CancelFunc
+}
+
+// NewWebhookSink POSTs the events selected by opts.Filter to opts.URL.
+func NewWebhookSink(opts WebhookOptions) (EventSink, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultWebhookTimeout
+	}
+	if opts.Format == "" {
+		opts.Format = WebhookFormatJSON
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = "application/json"
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = DefaultWebhookBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultWebhookMaxBackoff
+	}
+
+	s := &webhookSink{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		events: make(chan common.Event, webhookBuffer),
+		done:   make(chan struct{}),
+	}
+	if opts.Template != "" {
+		s.template, _ = parseWebhookTemplate(opts.Template)
+	}
+	s.ctx, s.stop = context.WithCancel(context.Background())
+
+	go s.run()
+	return s, nil
+}
+
+func (s *webhookSink) Name() string { return SinkWebhook + ":" + s.opts.URL }
+
+// MatchesResource reports whether the filter of the sink can select events
+// of resource.
+func (s *webhookSink) MatchesResource(resource string) bool {
+	return s.opts.Filter.MatchesResource(resource)
+}
+
+func (s *webhookSink) Send(e common.Event) error {
+	if !s.opts.Filter.Matches(e) {
+		return nil
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	// a slow endpoint must not hold up the informer
+	select {
+	case s.events <- e:
+		return nil
+	default:
+		s.deadLetter(e, nil, 0, fmt.Errorf("endpoint is not keeping up"))
+		return fmt.Errorf("endpoint is not keeping up, dropped event")
+	}
+}
+
+// Close sends the buffered events, without waiting to retry failed requests,
+// and stops the sink.
+func (s *webhookSink) Close() error {
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.lock.Unlock()
+
+	s.stop()
+	<-s.done
+	return nil
+}
+
+func (s *webhookSink) run() {
+	defer close(s.done)
+
+	for e := range s.events {
+		body, header, err := s.payload(e)
+		if err != nil {
+			s.deadLetter(e, nil, 0, err)
+			continue
+		}
+
+		attempts, err := s.deliver(body, header)
+		if err != nil {
+			s.deadLetter(e, body, attempts, err)
+		}
+	}
+}
+
+// deliver posts body until it is accepted or the retries are exhausted, and
+// returns the number of attempts.
+func (s *webhookSink) deliver(body []byte, header http.Header) (int, error) {
+	backoff := s.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(body, header)
+		if err == nil || !retry || attempt > s.opts.MaxRetries {
+			return attempt, err
+		}
+
+		klog.V(2).InfoS("Webhook request failed, retrying", "url", s.opts.URL, "attempt", attempt, "backoff", backoff, "err", err)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			return attempt, err
+		}
+		if backoff *= 2; backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+	}
+}
+
+// post sends one request, and reports whether a failed request may succeed
+// when retried.
+func (s *webhookSink) post(body []byte, header http.Header) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header = header.Clone()
+	if len(s.opts.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.opts.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// Sign returns the signature of body with secret, as sent in
+// SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// payload returns the body and the headers of the request of e.
+func (s *webhookSink) payload(e common.Event) ([]byte, http.Header, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	if s.template != nil {
+		var body bytes.Buffer
+		if err := s.template.Execute(&body, NewRecord(e)); err != nil {
+			return nil, nil, err
+		}
+		header.Set("Content-Type", s.opts.ContentType)
+		return body.Bytes(), header, nil
+	}
+
+	switch s.opts.Format {
+	case WebhookFormatSlack:
+		body, err := json.Marshal(map[string]string{"text": slackText(e)})
+		return body, header, err
+	case WebhookFormatCloudEvents:
+		header.Set("Content-Type", cloudevents.ContentType)
+		body, err := cloudevents.Structured(e)
+		return body, header, err
+	case WebhookFormatCloudEventsBinary:
+		event, err := cloudevents.New(e)
+		if err != nil {
+			return nil, nil, err
+		}
+		return event.Binary(header), header, nil
+	default:
+		body, err := json.Marshal(NewRecord(e))
+		return body, header, err
+	}
+}
+
+// slackText describes e in Slack markup, such as
+// "*Pod updated* `default/web-1` (resourceVersion 42)".
+func slackText(e common.Event) string {
+	text := fmt.Sprintf("*%s* `%s`", logMessage(e), e.Key)
+	if record := NewRecord(e); record.ResourceVersion != "" {
+		text += fmt.Sprintf(" (resourceVersion %s)", record.ResourceVersion)
+	}
+	return text
+}
+
+// deadLetter records an event that could not be delivered.
+func (s *webhookSink) deadLetter(e common.Event, body []byte, attempts int, err error) {
+	klog.ErrorS(err, "Failed to deliver event to webhook", append(e.LogValues(), "url", s.opts.URL, "attempts", attempts)...)
+	writeDeadLetter(s.opts.DeadLetterPath, deadLetter{
+		Time:     time.Now(),
+		URL:      s.opts.URL,
+		Attempts: attempts,
+		Error:    err.Error(),
+		Event:    NewRecord(e),
+		Body:     string(body),
+	})
+}
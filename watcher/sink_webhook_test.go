@@ -0,0 +1,277 @@
+package watcher
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bobbybho/k8s-deployment-watcher/cloudevents"
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type webhookRequest struct {
+	contentType string
+	signature   string
+	header      http.Header
+	body        string
+}
+
+// webhookServer records the requests it receives, answering with the
+// statuses in order and 200 once they are used up.
+func webhookServer(t *testing.T, statuses ...int) (*httptest.Server, chan webhookRequest) {
+	requests := make(chan webhookRequest, 10)
+	var n int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		requests <- webhookRequest{r.Header.Get("Content-Type"), r.Header.Get(SignatureHeader), r.Header, string(body)}
+
+		if i := int(atomic.AddInt32(&n, 1)) - 1; i < len(statuses) {
+			w.WriteHeader(statuses[i])
+		}
+	}))
+	return server, requests
+}
+
+// deploymentPod is a pod of the deployment web.
+func deploymentPod(name string) *v1.Pod {
+	controller := true
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		ResourceVersion: "7",
+		Labels:          map[string]string{"pod-template-hash": "5d8f9c7b6"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f9c7b6", Controller: &controller}},
+	}}
+}
+
+func TestWebhookFormats(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            WebhookOptions
+		wantContentType string
+		want            func(t *testing.T, req webhookRequest)
+	}{
+		{
+			name:            "json",
+			wantContentType: "application/json",
+			want: func(t *testing.T, req webhookRequest) {
+				var record Record
+				if err := json.Unmarshal([]byte(req.body), &record); err != nil || record.Key != "default/web-1" {
+					t.Errorf("unexpected record %s: %v", req.body, err)
+				}
+			},
+		},
+		{
+			name:            "slack",
+			opts:            WebhookOptions{Format: WebhookFormatSlack},
+			wantContentType: "application/json",
+			want: func(t *testing.T, req webhookRequest) {
+				if req.body != `{"text":"*Pod added* `+"`default/web-1`"+` (resourceVersion 7)"}` {
+					t.Errorf("unexpected slack message %s", req.body)
+				}
+			},
+		},
+		{
+			name:            "cloudevents",
+			opts:            WebhookOptions{Format: WebhookFormatCloudEvents},
+			wantContentType: "application/cloudevents+json",
+			want: func(t *testing.T, req webhookRequest) {
+				var event cloudevents.Event
+				if err := json.Unmarshal([]byte(req.body), &event); err != nil {
+					t.Fatal(err)
+				}
+				if event.SpecVersion != "1.0" || event.Type != "io.dw.pod.added" || event.Source != "/namespaces/default/pods" || event.Subject != "web-1" || event.ID == "" {
+					t.Errorf("unexpected cloud event %s", req.body)
+				}
+			},
+		},
+		{
+			name:            "cloudevents-binary",
+			opts:            WebhookOptions{Format: WebhookFormatCloudEventsBinary},
+			wantContentType: "application/json",
+			want: func(t *testing.T, req webhookRequest) {
+				event, err := cloudevents.FromBinary(req.header, []byte(req.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if event.Type != "io.dw.pod.added" || event.Source != "/namespaces/default/pods" || event.Subject != "web-1" || event.ID == "" {
+					t.Errorf("unexpected cloud event %+v", event)
+				}
+				var pod v1.Pod
+				if err := json.Unmarshal(event.Data, &pod); err != nil || pod.Name != "web-1" {
+					t.Errorf("unexpected data %s: %v", event.Data, err)
+				}
+			},
+		},
+		{
+			name:            "template",
+			opts:            WebhookOptions{Template: `{{.Resource}} {{.Key}} {{json .Type}}`, ContentType: "text/plain"},
+			wantContentType: "text/plain",
+			want: func(t *testing.T, req webhookRequest) {
+				if req.body != `pod default/web-1 "ADDED"` {
+					t.Errorf("unexpected body %q", req.body)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := webhookServer(t)
+			defer server.Close()
+
+			opts := test.opts
+			opts.URL = server.URL
+			opts.Secret = []byte("s3cret")
+			sink, err := NewWebhookSink(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Send(sinkEvent("web-1")); err != nil {
+				t.Fatal(err)
+			}
+			sink.Close()
+
+			req := <-requests
+			if req.contentType != test.wantContentType {
+				t.Errorf("content type %q, expected %q", req.contentType, test.wantContentType)
+			}
+			if want := Sign([]byte("s3cret"), []byte(req.body)); req.signature != want {
+				t.Errorf("signature %q, expected %q", req.signature, want)
+			}
+			test.want(t, req)
+		})
+	}
+}
+
+func TestWebhookFilter(t *testing.T) {
+	server, requests := webhookServer(t)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{
+		URL: server.URL,
+		Filter: EventFilter{
+			Namespaces:  []string{"default"},
+			Deployments: []string{"web"},
+			EventTypes:  []string{common.EventModified},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []common.Event{
+		{Key: "default/web-5d8f9c7b6-abcde", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: deploymentPod("web-5d8f9c7b6-abcde")},
+		{Key: "default/web-5d8f9c7b6-abcde", EventType: common.EventAdded, ResourceType: common.ResourcePod, Object: deploymentPod("web-5d8f9c7b6-abcde")},
+		{Key: "kube-system/web-5d8f9c7b6-abcde", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: deploymentPod("web-5d8f9c7b6-abcde")},
+		{Key: "default/db-0", EventType: common.EventModified, ResourceType: common.ResourcePod, Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default"}}},
+	}
+	for _, e := range events {
+		if err := sink.Send(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+	close(requests)
+
+	var keys []string
+	for req := range requests {
+		var record Record
+		json.Unmarshal([]byte(req.body), &record)
+		keys = append(keys, record.Type+" "+record.Key)
+	}
+	if strings.Join(keys, ",") != "MODIFIED default/web-5d8f9c7b6-abcde" {
+		t.Errorf("sent %v", keys)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	server, requests := webhookServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, MaxRetries: 2, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(sinkEvent("web-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-requests:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("got %d requests, expected 3", i)
+		}
+	}
+	sink.Close()
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	deadLetters := filepath.Join(dir, "dead-letters.jsonl")
+
+	// client errors are not retried, server errors are until the retries
+	// are used up
+	server, requests := webhookServer(t, http.StatusBadRequest, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, MaxRetries: 1, Backoff: time.Millisecond, DeadLetterPath: deadLetters})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		if err := sink.Send(sinkEvent(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// closing cancels the retries
+	for i := 0; i < 4; i++ {
+		select {
+		case <-requests:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("got %d requests, expected 4", i)
+		}
+	}
+	sink.Close()
+
+	data, err := ioutil.ReadFile(deadLetters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var letter struct {
+			Attempts int    `json:"attempts"`
+			Error    string `json:"error"`
+			Event    Record `json:"event"`
+			Body     string `json:"body"`
+		}
+		if err := json.Unmarshal([]byte(line), &letter); err != nil {
+			t.Fatal(err)
+		}
+		if letter.Body == "" || !strings.Contains(letter.Error, "unexpected status") {
+			t.Errorf("unexpected dead letter %s", line)
+		}
+		got = append(got, letter.Event.Key+" "+string(rune('0'+letter.Attempts)))
+	}
+	if strings.Join(got, ",") != "default/web-1 1,default/web-2 2" {
+		t.Errorf("dead letters %v", got)
+	}
+}
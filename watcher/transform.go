@@ -0,0 +1,152 @@
+package watcher
+
+import (
+	"fmt"
+
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// transformingListWatch applies a transform to every object returned by the
+// wrapped ListerWatcher before it reaches the informer cache. Shared
+// informers in client-go v0.23 have no transform hook of their own.
+type transformingListWatch struct {
+	lw        cache.ListerWatcher
+	transform cache.TransformFunc
+}
+
+func newTransformingListWatch(lw cache.ListerWatcher, transform cache.TransformFunc) cache.ListerWatcher {
+	return &transformingListWatch{lw: lw, transform: transform}
+}
+
+// List ...
+func (t *transformingListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	list, err := t.lw.List(options)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i], err = t.transformObject(items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := meta.SetList(list, items); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Watch ...
+func (t *transformingListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	w, err := t.lw.Watch(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		if in.Type == watch.Error || in.Type == watch.Bookmark {
+			return in, true
+		}
+
+		obj, err := t.transformObject(in.Object)
+		if err != nil {
+			// keep the untrimmed object rather than losing the event
+			utilruntime.HandleError(err)
+			return in, true
+		}
+		in.Object = obj
+		return in, true
+	}), nil
+}
+
+func (t *transformingListWatch) transformObject(in runtime.Object) (runtime.Object, error) {
+	out, err := t.transform(in)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := out.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("transform returned %T, which is not a runtime.Object", out)
+	}
+	return obj, nil
+}
+
+// TrimPod keeps only the pod fields dwserver exposes: identity, labels,
+// owners, the node name and the status. managedFields, annotations and the
+// rest of the spec are dropped.
+func TrimPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return obj, nil
+	}
+
+	return &v1.Pod{
+		TypeMeta:   pod.TypeMeta,
+		ObjectMeta: trimObjectMeta(pod.ObjectMeta),
+		Spec: v1.PodSpec{
+			NodeName: pod.Spec.NodeName,
+		},
+		Status: pod.Status,
+	}, nil
+}
+
+// TrimDeployment drops managedFields, annotations and the pod template from a
+// deployment. The selector, replica count and status are kept.
+func TrimDeployment(obj interface{}) (interface{}, error) {
+	deployment, ok := obj.(*appv1.Deployment)
+	if !ok {
+		return obj, nil
+	}
+
+	return &appv1.Deployment{
+		TypeMeta:   deployment.TypeMeta,
+		ObjectMeta: trimObjectMeta(deployment.ObjectMeta),
+		Spec: appv1.DeploymentSpec{
+			Replicas: deployment.Spec.Replicas,
+			Selector: deployment.Spec.Selector,
+		},
+		Status: deployment.Status,
+	}, nil
+}
+
+// TrimObjectMetadata drops managedFields and annotations from a metadata-only
+// object.
+func TrimObjectMetadata(obj interface{}) (interface{}, error) {
+	partial, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return obj, nil
+	}
+
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   partial.TypeMeta,
+		ObjectMeta: trimObjectMeta(partial.ObjectMeta),
+	}, nil
+}
+
+func trimObjectMeta(m metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              m.Name,
+		Namespace:         m.Namespace,
+		UID:               m.UID,
+		ResourceVersion:   m.ResourceVersion,
+		Generation:        m.Generation,
+		CreationTimestamp: m.CreationTimestamp,
+		DeletionTimestamp: m.DeletionTimestamp,
+		Labels:            m.Labels,
+		OwnerReferences:   m.OwnerReferences,
+	}
+}
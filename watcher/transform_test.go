@@ -0,0 +1,159 @@
+package watcher
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+const benchmarkObjects = 2000
+
+func benchmarkMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		ResourceVersion: "1",
+		Labels:          map[string]string{"app": "bench", "pod-template-hash": "5d8f9c7b6"},
+		Annotations: map[string]string{
+			"kubectl.kubernetes.io/last-applied-configuration": string(make([]byte, 1024)),
+		},
+		ManagedFields: []metav1.ManagedFieldsEntry{{
+			Manager:    "kube-controller-manager",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: make([]byte, 2048)},
+		}},
+	}
+}
+
+func benchmarkPod(i int) *v1.Pod {
+	container := v1.Container{
+		Name:    "app",
+		Image:   "registry.example.com/bench/app:1.0.0",
+		Command: []string{"/app", "--serve"},
+	}
+	for j := 0; j < 20; j++ {
+		container.Env = append(container.Env, v1.EnvVar{Name: fmt.Sprintf("ENV_%d", j), Value: "some-configuration-value"})
+	}
+
+	return &v1.Pod{
+		ObjectMeta: benchmarkMeta(fmt.Sprintf("pod-%d", i)),
+		Spec: v1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []v1.Container{container},
+		},
+		Status: v1.PodStatus{
+			Phase:  v1.PodRunning,
+			PodIP:  "10.0.0.1",
+			HostIP: "192.168.0.1",
+		},
+	}
+}
+
+func benchmarkDeployment(i int) *appv1.Deployment {
+	replicas := int32(3)
+	return &appv1.Deployment{
+		ObjectMeta: benchmarkMeta(fmt.Sprintf("deployment-%d", i)),
+		Spec: appv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{Spec: benchmarkPod(i).Spec},
+		},
+	}
+}
+
+// heapInUse reports the live heap after a full collection. It is signed so
+// that a heap shrinking between two readings gives a negative difference.
+func heapInUse() int64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
+
+// benchmarkCache measures the heap retained by the informer caches that
+// register registers with a fresh manager.
+func benchmarkCache(b *testing.B, newManager func() *Manager, register func(*Manager)) {
+	for i := 0; i < b.N; i++ {
+		manager := newManager()
+		before := heapInUse()
+
+		register(manager)
+		stopCh := make(chan struct{})
+		manager.Start(stopCh)
+		if err := manager.WaitForCacheSync(stopCh); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportMetric(float64(heapInUse()-before)/benchmarkObjects, "cached-B/object")
+		close(stopCh)
+	}
+}
+
+func BenchmarkPodCache(b *testing.B) {
+	objects := make([]k8sruntime.Object, 0, benchmarkObjects)
+	for i := 0; i < benchmarkObjects; i++ {
+		objects = append(objects, benchmarkPod(i))
+	}
+
+	for _, trim := range []bool{false, true} {
+		b.Run(fmt.Sprintf("trim=%v", trim), func(b *testing.B) {
+			benchmarkCache(b, func() *Manager {
+				opts := DefaultOptions()
+				opts.TrimObjects = trim
+				return NewManager(fake.NewSimpleClientset(objects...), opts)
+			}, func(m *Manager) {
+				m.PodInformer("default")
+			})
+		})
+	}
+}
+
+func BenchmarkDeploymentCache(b *testing.B) {
+	objects := make([]k8sruntime.Object, 0, benchmarkObjects)
+	partials := make([]k8sruntime.Object, 0, benchmarkObjects)
+	for i := 0; i < benchmarkObjects; i++ {
+		deployment := benchmarkDeployment(i)
+		objects = append(objects, deployment)
+		partials = append(partials, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: deployment.ObjectMeta,
+		})
+	}
+
+	scheme := k8sruntime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+
+	for _, tc := range []struct {
+		name         string
+		trim         bool
+		metadataOnly bool
+	}{
+		{name: "full"},
+		{name: "trim", trim: true},
+		{name: "metadata-only", trim: true, metadataOnly: true},
+	} {
+		tc := tc
+		b.Run(tc.name, func(b *testing.B) {
+			benchmarkCache(b, func() *Manager {
+				opts := DefaultOptions()
+				opts.TrimObjects = tc.trim
+				if tc.metadataOnly {
+					opts.MetadataOnlyDeployments = true
+					opts.MetadataClient = metadatafake.NewSimpleMetadataClient(scheme, partials...)
+					return NewManager(fake.NewSimpleClientset(), opts)
+				}
+				return NewManager(fake.NewSimpleClientset(objects...), opts)
+			}, func(m *Manager) {
+				NewDeploymentWatcher(m, "default")
+			})
+		})
+	}
+}
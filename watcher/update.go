@@ -0,0 +1,103 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/bobbybho/k8s-deployment-watcher/common"
+	"github.com/bobbybho/k8s-deployment-watcher/diff"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/klog/v2"
+)
+
+// defaultDiffer ignores diff.DefaultIgnorePaths.
+var defaultDiffer, _ = diff.New(diff.DefaultOptions())
+
+// Update describes the changes of an updated pod or deployment.
+type Update struct {
+	Resource        string        `json:"resource"`
+	Key             string        `json:"key"`
+	ResourceVersion string        `json:"resourceVersion"`
+	Changes         []diff.Change `json:"changes"`
+}
+
+// reportUpdate diffs the modified event e, logs the changes at level 5
+// and passes them to the OnUpdate handler. Nothing is diffed unless the
+// changes are logged or handled.
+func (m *Manager) reportUpdate(e common.Event) {
+	klogV := klog.V(5)
+	if !klogV.Enabled() && m.opts.OnUpdate == nil {
+		return
+	}
+
+	changes, err := m.opts.Differ.Diff(e.Old, e.Object)
+	if err != nil {
+		klog.ErrorS(err, "Failed to diff update", e.LogValues()...)
+		return
+	}
+
+	klogV.InfoS("Changes", append(e.LogValues(), "changes", diff.Text(changes))...)
+
+	if m.opts.OnUpdate != nil {
+		update := Update{Resource: e.ResourceType, Key: e.Key, Changes: changes}
+		if obj, err := meta.Accessor(e.Object); err == nil {
+			update.ResourceVersion = obj.GetResourceVersion()
+		}
+		m.opts.OnUpdate(update)
+	}
+}
+
+// PrintUpdates returns an OnUpdate handler writing the updates with changes
+// to w, in one of the diff formats. Updates that only change ignored fields
+// are skipped.
+func PrintUpdates(w io.Writer, format string) (func(Update), error) {
+	if _, err := diff.Format(nil, format); err != nil {
+		return nil, err
+	}
+
+	// the informers of every namespace report concurrently
+	var lock sync.Mutex
+
+	return func(update Update) {
+		if len(update.Changes) == 0 {
+			return
+		}
+
+		var out []byte
+		var err error
+		switch format {
+		case diff.FormatText:
+			var b strings.Builder
+			fmt.Fprintf(&b, "%s %s updated (resourceVersion %s)\n", update.Resource, update.Key, update.ResourceVersion)
+			for _, line := range strings.SplitAfter(diff.Text(update.Changes), "\n") {
+				if line != "" {
+					b.WriteString("  " + line)
+				}
+			}
+			out = []byte(b.String())
+		case diff.FormatJSON:
+			out, err = json.Marshal(update)
+		case diff.FormatJSONPatch:
+			out, err = json.Marshal(struct {
+				Resource        string           `json:"resource"`
+				Key             string           `json:"key"`
+				ResourceVersion string           `json:"resourceVersion"`
+				Patch           []diff.Operation `json:"patch"`
+			}{update.Resource, update.Key, update.ResourceVersion, diff.Patch(update.Changes)})
+		}
+		if err != nil {
+			klog.ErrorS(err, "Failed to print update", "resource", update.Resource, "key", update.Key)
+			return
+		}
+		if format != diff.FormatText {
+			out = append(out, '\n')
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		w.Write(out)
+	}, nil
+}